@@ -0,0 +1,103 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FromBinding converts an error returned while binding a request.
+// Validation errors become a validation_failed error detailing every invalid field,
+// the other ones a malformed_request error.
+func FromBinding(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:  fieldErr.Field(),
+				Rule:   fieldErr.Tag(),
+				Detail: ruleDetail(fieldErr),
+			})
+		}
+
+		detail := fmt.Sprintf("%s %s", fields[0].Field, fields[0].Detail)
+		if len(fields) > 1 {
+			detail = fmt.Sprintf("%d fields are invalid", len(fields))
+		}
+
+		return &Error{Code: CodeValidationFailed, Detail: detail, Fields: fields, cause: err}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &Error{
+			Code:   CodeValidationFailed,
+			Detail: fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type.Kind()),
+			Fields: []FieldError{{Field: typeErr.Field, Rule: "type", Detail: fmt.Sprintf("must be a %s", typeErr.Type.Kind())}},
+			cause:  err,
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &Error{Code: CodeMalformedRequest, Detail: "the request body is not valid JSON", cause: err}
+	case errors.Is(err, io.EOF):
+		return &Error{Code: CodeMalformedRequest, Detail: "the request body is empty", cause: err}
+	}
+
+	return &Error{Code: CodeMalformedRequest, Detail: "the request cannot be parsed", cause: err}
+}
+
+// FieldName is a validator.TagNameFunc naming the fields after their JSON, URI or query parameter
+func FieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "uri", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func ruleDetail(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "email":
+		return "must be a valid email address"
+	case "uuid":
+		return "must be a valid UUID"
+	case "currency":
+		return "must be a supported currency"
+	case "role":
+		return "must be a supported role"
+	case "username":
+		return "is reserved"
+	case "nefield":
+		return fmt.Sprintf("must differ from %s", fieldErr.Param())
+	}
+	return fmt.Sprintf("doesn't satisfy the %s rule", fieldErr.Tag())
+}
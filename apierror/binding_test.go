@@ -0,0 +1,77 @@
+package apierror
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+)
+
+type bindingRequest struct {
+	Username string `json:"username" validate:"required,alphanum"`
+	Amount   int64  `json:"amount" validate:"gt=0"`
+	Currency string `json:"currency" validate:"oneof=USD EUR"`
+	Note     string `json:"note" validate:"max=5"`
+}
+
+func TestFromBindingValidationErrors(t *testing.T) {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(FieldName)
+
+	err := validate.Struct(bindingRequest{Username: "bad-name", Amount: 0, Currency: "GBP", Note: "too long"})
+	require.Error(t, err)
+
+	apiErr := FromBinding(err)
+	require.Equal(t, CodeValidationFailed, apiErr.Code)
+	require.Equal(t, "4 fields are invalid", apiErr.Detail)
+	require.Equal(t, []FieldError{
+		{Field: "username", Rule: "alphanum", Detail: "must contain only letters and digits"},
+		{Field: "amount", Rule: "gt", Detail: "must be greater than 0"},
+		{Field: "currency", Rule: "oneof", Detail: "must be one of USD, EUR"},
+		{Field: "note", Rule: "max", Detail: "must be at most 5 characters long"},
+	}, apiErr.Fields)
+
+	err = validate.Struct(bindingRequest{Amount: 1, Currency: "USD"})
+	require.Error(t, err)
+
+	apiErr = FromBinding(err)
+	require.Equal(t, "username is required", apiErr.Detail)
+	require.Len(t, apiErr.Fields, 1)
+}
+
+func TestFromBindingDecodeErrors(t *testing.T) {
+	decode := func(body string) error {
+		var req bindingRequest
+		return json.NewDecoder(bytes.NewBufferString(body)).Decode(&req)
+	}
+
+	testCases := []struct {
+		name   string
+		err    error
+		code   Code
+		detail string
+	}{
+		{"Syntax", decode(`{"username":`), CodeMalformedRequest, "the request body is not valid JSON"},
+		{"InvalidCharacter", decode(`{username}`), CodeMalformedRequest, "the request body is not valid JSON"},
+		{"Empty", decode(``), CodeMalformedRequest, "the request body is empty"},
+		{"Type", decode(`{"amount":"ten"}`), CodeValidationFailed, "amount must be a int64"},
+		{"Other", errors.New("unexpected"), CodeMalformedRequest, "the request cannot be parsed"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := FromBinding(tc.err)
+			require.Equal(t, tc.code, apiErr.Code)
+			require.Equal(t, tc.detail, apiErr.Detail)
+			require.ErrorIs(t, apiErr, tc.err)
+		})
+	}
+
+	require.ErrorIs(t, decode(``), io.EOF)
+}
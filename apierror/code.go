@@ -0,0 +1,202 @@
+package apierror
+
+import "net/http"
+
+// Code is the stable, machine-readable identifier of an API error.
+// Codes are part of the API contract: they can be added but never renamed.
+type Code string
+
+// Request errors
+const (
+	CodeValidationFailed  Code = "validation_failed"
+	CodeMalformedRequest  Code = "malformed_request"
+	CodeCurrencyMismatch  Code = "currency_mismatch"
+	CodeRateQuoteMismatch Code = "rate_quote_mismatch"
+)
+
+// Authentication and authorization errors
+const (
+	CodeUnauthenticated       Code = "unauthenticated"
+	CodeTokenInvalid          Code = "token_invalid"
+	CodeTokenExpired          Code = "token_expired"
+	CodeTokenRevoked          Code = "token_revoked"
+	CodeInvalidCredentials    Code = "invalid_credentials"
+	CodeUserLocked            Code = "user_locked"
+	CodeSessionBlocked        Code = "session_blocked"
+	CodeSessionExpired        Code = "session_expired"
+	CodeSessionNotOwned       Code = "session_not_owned"
+	CodeAccountNotOwned       Code = "account_not_owned"
+	CodeTransferNotOwned      Code = "transfer_not_owned"
+	CodeRateQuoteNotOwned     Code = "rate_quote_not_owned"
+	CodeStandingOrderNotOwned Code = "standing_order_not_owned"
+	CodeNotTransferRecipient  Code = "not_transfer_recipient"
+	CodeEmailNotVerified      Code = "email_not_verified"
+	CodeForbidden             Code = "forbidden"
+)
+
+// Resource errors
+const (
+	CodeNotFound              Code = "not_found"
+	CodeUserNotFound          Code = "user_not_found"
+	CodeAccountNotFound       Code = "account_not_found"
+	CodeTransferNotFound      Code = "transfer_not_found"
+	CodeSessionNotFound       Code = "session_not_found"
+	CodeRateQuoteNotFound     Code = "rate_quote_not_found"
+	CodeExchangeRateNotFound  Code = "exchange_rate_not_found"
+	CodeStandingOrderNotFound Code = "standing_order_not_found"
+	CodePasswordResetNotFound Code = "password_reset_not_found"
+	CodeVerifyEmailNotFound   Code = "verify_email_not_found"
+	CodeReferenceNotFound     Code = "reference_not_found"
+	CodeAlreadyExists         Code = "already_exists"
+	CodeUserAlreadyExists     Code = "user_already_exists"
+	CodeAccountAlreadyExists  Code = "account_already_exists"
+	CodeEmailAlreadyExists    Code = "email_already_exists"
+	CodeConstraintViolation   Code = "constraint_violation"
+)
+
+// Ledger errors
+const (
+	CodeInsufficientFunds       Code = "insufficient_funds"
+	CodeAccountFrozen           Code = "account_frozen"
+	CodeAccountClosed           Code = "account_closed"
+	CodeAccountBalanceNotZero   Code = "account_balance_not_zero"
+	CodeInvalidStatusTransition Code = "invalid_status_transition"
+	CodeRateQuoteExpired        Code = "rate_quote_expired"
+	CodeRateQuoteUsed           Code = "rate_quote_used"
+	CodeConvertedAmountTooSmall Code = "converted_amount_too_small"
+	CodeTransferIsReversal      Code = "transfer_is_reversal"
+	CodeTransferIsCash          Code = "transfer_is_cash"
+	CodeTransferAlreadyReversed Code = "transfer_already_reversed"
+	CodeReversalExceedsTransfer Code = "reversal_exceeds_transfer"
+	CodeStandingOrderFinished   Code = "standing_order_finished"
+	CodeTransactionConflict     Code = "transaction_conflict"
+)
+
+// Idempotency errors
+const (
+	CodeIdempotencyKeyReused     Code = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress Code = "idempotency_key_in_progress"
+)
+
+// Password reset errors
+const (
+	CodePasswordResetExpired Code = "password_reset_expired"
+	CodePasswordResetUsed    Code = "password_reset_used"
+)
+
+// Email verification errors
+const (
+	CodeVerifyEmailInvalid Code = "verify_email_invalid"
+	CodeVerifyEmailExpired Code = "verify_email_expired"
+	CodeVerifyEmailUsed    Code = "verify_email_used"
+)
+
+// Two-factor authentication errors
+const (
+	CodeMFARequired       Code = "mfa_required"
+	CodeMFACodeInvalid    Code = "mfa_code_invalid"
+	CodeMFANotEnabled     Code = "mfa_not_enabled"
+	CodeMFAAlreadyEnabled Code = "mfa_already_enabled"
+)
+
+// CodeRateLimited is returned once a client used up its requests, the response tells when to retry
+const CodeRateLimited Code = "rate_limited"
+
+// CodeInternal hides the cause of an unexpected error from the client
+const CodeInternal Code = "internal"
+
+type definition struct {
+	status int
+	title  string
+}
+
+var definitions = map[Code]definition{
+	CodeValidationFailed:  {http.StatusBadRequest, "The request is invalid"},
+	CodeMalformedRequest:  {http.StatusBadRequest, "The request cannot be parsed"},
+	CodeCurrencyMismatch:  {http.StatusBadRequest, "The currency doesn't match the account"},
+	CodeRateQuoteMismatch: {http.StatusBadRequest, "The rate quote doesn't match the accounts"},
+
+	CodeUnauthenticated:       {http.StatusUnauthorized, "Authentication is required"},
+	CodeTokenInvalid:          {http.StatusUnauthorized, "The token is invalid"},
+	CodeTokenExpired:          {http.StatusUnauthorized, "The token has expired"},
+	CodeTokenRevoked:          {http.StatusUnauthorized, "The token has been revoked"},
+	CodeInvalidCredentials:    {http.StatusUnauthorized, "The credentials are invalid"},
+	CodeUserLocked:            {http.StatusLocked, "The user is locked after too many failed logins"},
+	CodeSessionBlocked:        {http.StatusUnauthorized, "The session is blocked"},
+	CodeSessionExpired:        {http.StatusUnauthorized, "The session has expired"},
+	CodeSessionNotOwned:       {http.StatusUnauthorized, "The session doesn't belong to the user"},
+	CodeAccountNotOwned:       {http.StatusUnauthorized, "The account doesn't belong to the user"},
+	CodeTransferNotOwned:      {http.StatusUnauthorized, "The transfer doesn't belong to the user"},
+	CodeRateQuoteNotOwned:     {http.StatusUnauthorized, "The rate quote doesn't belong to the user"},
+	CodeStandingOrderNotOwned: {http.StatusUnauthorized, "The standing order doesn't belong to the user"},
+	CodeNotTransferRecipient:  {http.StatusUnauthorized, "The user is not the recipient of the transfer"},
+	CodeEmailNotVerified:      {http.StatusForbidden, "The email address of the user is not verified"},
+	CodeForbidden:             {http.StatusForbidden, "The user is not allowed to perform this action"},
+
+	CodeNotFound:              {http.StatusNotFound, "The resource was not found"},
+	CodeUserNotFound:          {http.StatusNotFound, "The user was not found"},
+	CodeAccountNotFound:       {http.StatusNotFound, "The account was not found"},
+	CodeTransferNotFound:      {http.StatusNotFound, "The transfer was not found"},
+	CodeSessionNotFound:       {http.StatusNotFound, "The session was not found"},
+	CodeRateQuoteNotFound:     {http.StatusNotFound, "The rate quote was not found"},
+	CodeExchangeRateNotFound:  {http.StatusNotFound, "No exchange rate is available"},
+	CodeStandingOrderNotFound: {http.StatusNotFound, "The standing order was not found"},
+	CodePasswordResetNotFound: {http.StatusNotFound, "The password reset token was not found"},
+	CodeVerifyEmailNotFound:   {http.StatusNotFound, "The email verification was not found"},
+	CodeReferenceNotFound:     {http.StatusUnprocessableEntity, "A referenced resource doesn't exist"},
+	CodeAlreadyExists:         {http.StatusConflict, "The resource already exists"},
+	CodeUserAlreadyExists:     {http.StatusForbidden, "The username or email is already taken"},
+	CodeAccountAlreadyExists:  {http.StatusForbidden, "The user already has an account in this currency"},
+	CodeEmailAlreadyExists:    {http.StatusConflict, "The email is already used by another user"},
+	CodeConstraintViolation:   {http.StatusUnprocessableEntity, "The request violates a constraint"},
+
+	CodeInsufficientFunds:       {http.StatusUnprocessableEntity, "The account has insufficient funds"},
+	CodeAccountFrozen:           {http.StatusUnprocessableEntity, "The account is frozen"},
+	CodeAccountClosed:           {http.StatusUnprocessableEntity, "The account is closed"},
+	CodeAccountBalanceNotZero:   {http.StatusUnprocessableEntity, "The account balance is not zero"},
+	CodeInvalidStatusTransition: {http.StatusConflict, "The account cannot move to this status"},
+	CodeRateQuoteExpired:        {http.StatusUnprocessableEntity, "The rate quote has expired"},
+	CodeRateQuoteUsed:           {http.StatusUnprocessableEntity, "The rate quote was already used"},
+	CodeConvertedAmountTooSmall: {http.StatusUnprocessableEntity, "The converted amount is too small"},
+	CodeTransferIsReversal:      {http.StatusUnprocessableEntity, "A reversal cannot be reversed"},
+	CodeTransferIsCash:          {http.StatusUnprocessableEntity, "A deposit or withdrawal cannot be reversed"},
+	CodeTransferAlreadyReversed: {http.StatusConflict, "The transfer is already fully reversed"},
+	CodeReversalExceedsTransfer: {http.StatusUnprocessableEntity, "The reversal exceeds the amount left on the transfer"},
+	CodeStandingOrderFinished:   {http.StatusConflict, "The standing order is finished"},
+	CodeTransactionConflict:     {http.StatusConflict, "The request conflicted with a concurrent one, it can be retried"},
+
+	CodeIdempotencyKeyReused:     {http.StatusConflict, "The idempotency key was used with a different request"},
+	CodeIdempotencyKeyInProgress: {http.StatusConflict, "A request with the same idempotency key is in progress"},
+
+	CodePasswordResetExpired: {http.StatusUnprocessableEntity, "The password reset token has expired"},
+	CodePasswordResetUsed:    {http.StatusConflict, "The password reset token was already used"},
+
+	CodeVerifyEmailInvalid: {http.StatusUnprocessableEntity, "The email verification code is invalid"},
+	CodeVerifyEmailExpired: {http.StatusUnprocessableEntity, "The email verification has expired"},
+	CodeVerifyEmailUsed:    {http.StatusConflict, "The email address is already verified"},
+
+	CodeMFARequired:       {http.StatusForbidden, "A one-time code is required for this action"},
+	CodeMFACodeInvalid:    {http.StatusUnauthorized, "The one-time code is invalid"},
+	CodeMFANotEnabled:     {http.StatusForbidden, "Two-factor authentication is not enabled"},
+	CodeMFAAlreadyEnabled: {http.StatusConflict, "Two-factor authentication is already enabled"},
+
+	CodeRateLimited: {http.StatusTooManyRequests, "Too many requests, retry later"},
+
+	CodeInternal: {http.StatusInternalServerError, "An internal error occurred"},
+}
+
+// Status returns the HTTP status of the code
+func (c Code) Status() int {
+	if def, ok := definitions[c]; ok {
+		return def.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Title returns the short human-readable summary of the code
+func (c Code) Title() string {
+	if def, ok := definitions[c]; ok {
+		return def.title
+	}
+	return definitions[CodeInternal].title
+}
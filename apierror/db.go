@@ -0,0 +1,100 @@
+package apierror
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+)
+
+// Resource holds the codes of the errors about a resource a query looked up or created
+type Resource struct {
+	NotFound      Code
+	AlreadyExists Code
+}
+
+// Resources of the API
+var (
+	User          = Resource{NotFound: CodeUserNotFound, AlreadyExists: CodeUserAlreadyExists}
+	UserProfile   = Resource{NotFound: CodeUserNotFound, AlreadyExists: CodeEmailAlreadyExists}
+	Account       = Resource{NotFound: CodeAccountNotFound, AlreadyExists: CodeAccountAlreadyExists}
+	Transfer      = Resource{NotFound: CodeTransferNotFound, AlreadyExists: CodeAlreadyExists}
+	Session       = Resource{NotFound: CodeSessionNotFound, AlreadyExists: CodeAlreadyExists}
+	RateQuote     = Resource{NotFound: CodeRateQuoteNotFound, AlreadyExists: CodeAlreadyExists}
+	ExchangeRate  = Resource{NotFound: CodeExchangeRateNotFound, AlreadyExists: CodeAlreadyExists}
+	StandingOrder = Resource{NotFound: CodeStandingOrderNotFound, AlreadyExists: CodeAlreadyExists}
+	PasswordReset = Resource{NotFound: CodePasswordResetNotFound, AlreadyExists: CodeAlreadyExists}
+	VerifyEmail   = Resource{NotFound: CodeVerifyEmailNotFound, AlreadyExists: CodeAlreadyExists}
+)
+
+// ledgerCodes maps the errors of the Store to their codes
+var ledgerCodes = []struct {
+	err  error
+	code Code
+}{
+	{db.ErrAccountNotFound, CodeAccountNotFound},
+	{db.ErrCurrencyMismatch, CodeCurrencyMismatch},
+	{db.ErrInsufficientFunds, CodeInsufficientFunds},
+	{db.ErrAccountFrozen, CodeAccountFrozen},
+	{db.ErrAccountClosed, CodeAccountClosed},
+	{db.ErrSessionNotOwned, CodeSessionNotOwned},
+	{db.ErrRateQuoteNotFound, CodeRateQuoteNotFound},
+	{db.ErrRateQuoteExpired, CodeRateQuoteExpired},
+	{db.ErrRateQuoteUsed, CodeRateQuoteUsed},
+	{db.ErrRateQuoteMismatch, CodeRateQuoteMismatch},
+	{db.ErrConvertedAmountTooSmall, CodeConvertedAmountTooSmall},
+	{db.ErrTransferNotFound, CodeTransferNotFound},
+	{db.ErrTransferIsReversal, CodeTransferIsReversal},
+	{db.ErrTransferIsCash, CodeTransferIsCash},
+	{db.ErrTransferAlreadyReversed, CodeTransferAlreadyReversed},
+	{db.ErrReversalExceedsTransfer, CodeReversalExceedsTransfer},
+	{db.ErrInvalidStatusTransition, CodeInvalidStatusTransition},
+	{db.ErrAccountBalanceNotZero, CodeAccountBalanceNotZero},
+	{db.ErrPasswordResetExpired, CodePasswordResetExpired},
+	{db.ErrPasswordResetUsed, CodePasswordResetUsed},
+	{db.ErrVerifyEmailInvalid, CodeVerifyEmailInvalid},
+	{db.ErrVerifyEmailExpired, CodeVerifyEmailExpired},
+	{db.ErrVerifyEmailUsed, CodeVerifyEmailUsed},
+}
+
+// FromDB converts an error returned by the Store about resource.
+// It is the only place where database errors are mapped to API errors,
+// the errors it doesn't know are hidden behind an internal error.
+func FromDB(err error, resource Resource) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Code: resource.NotFound, Detail: resource.NotFound.Title(), cause: err}
+	}
+
+	for _, ledger := range ledgerCodes {
+		if errors.Is(err, ledger.err) {
+			return &Error{Code: ledger.code, Detail: ledger.err.Error(), cause: err}
+		}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return &Error{Code: resource.AlreadyExists, Detail: resource.AlreadyExists.Title(), cause: err}
+		case "foreign_key_violation":
+			return &Error{Code: CodeReferenceNotFound, Detail: CodeReferenceNotFound.Title(), cause: err}
+		case "check_violation", "not_null_violation":
+			return &Error{Code: CodeConstraintViolation, Detail: CodeConstraintViolation.Title(), cause: err}
+		case "deadlock_detected", "serialization_failure":
+			return &Error{Code: CodeTransactionConflict, Detail: CodeTransactionConflict.Title(), cause: err}
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return &Error{Code: CodeInternal, Detail: "the request was canceled", cause: err}
+	}
+
+	return Internal(err)
+}
@@ -0,0 +1,67 @@
+package apierror
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromDB(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		resource Resource
+		code     Code
+	}{
+		{"NoRows", sql.ErrNoRows, Account, CodeAccountNotFound},
+		{"WrappedNoRows", fmt.Errorf("get user: %w", sql.ErrNoRows), User, CodeUserNotFound},
+		{"InsufficientFunds", db.ErrInsufficientFunds, Transfer, CodeInsufficientFunds},
+		{"WrappedCurrencyMismatch", fmt.Errorf("transfer tx: %w", db.ErrCurrencyMismatch), Transfer, CodeCurrencyMismatch},
+		{"AccountFrozen", db.ErrAccountFrozen, Account, CodeAccountFrozen},
+		{"AlreadyReversed", db.ErrTransferAlreadyReversed, Transfer, CodeTransferAlreadyReversed},
+		{"TransferIsCash", db.ErrTransferIsCash, Transfer, CodeTransferIsCash},
+		{"SessionNotOwned", db.ErrSessionNotOwned, Session, CodeSessionNotOwned},
+		{"PasswordResetNotFound", sql.ErrNoRows, PasswordReset, CodePasswordResetNotFound},
+		{"WrappedPasswordResetUsed", fmt.Errorf("reset password tx: %w", db.ErrPasswordResetUsed), PasswordReset, CodePasswordResetUsed},
+		{"VerifyEmailInvalid", db.ErrVerifyEmailInvalid, VerifyEmail, CodeVerifyEmailInvalid},
+		{"UniqueViolation", &pq.Error{Code: "23505"}, User, CodeUserAlreadyExists},
+		{"UniqueEmail", &pq.Error{Code: "23505"}, UserProfile, CodeEmailAlreadyExists},
+		{"UniqueViolationWithoutResourceCode", &pq.Error{Code: "23505"}, Transfer, CodeAlreadyExists},
+		{"ForeignKeyViolation", &pq.Error{Code: "23503"}, Account, CodeReferenceNotFound},
+		{"CheckViolation", &pq.Error{Code: "23514"}, Account, CodeConstraintViolation},
+		{"Deadlock", fmt.Errorf("transfer tx: %w", &pq.Error{Code: "40P01"}), Transfer, CodeTransactionConflict},
+		{"SerializationFailure", &pq.Error{Code: "40001"}, Transfer, CodeTransactionConflict},
+		{"Canceled", context.Canceled, Account, CodeInternal},
+		{"Unknown", sql.ErrConnDone, Account, CodeInternal},
+		{"AlreadyConverted", New(CodeForbidden, "forbidden"), Account, CodeForbidden},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := FromDB(tc.err, tc.resource)
+			require.Equal(t, tc.code, apiErr.Code)
+			require.NotEmpty(t, apiErr.Detail)
+			require.ErrorIs(t, apiErr, tc.err)
+		})
+	}
+}
+
+func TestFromDBHidesDriverErrors(t *testing.T) {
+	err := &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "users_pkey"`}
+
+	apiErr := FromDB(err, User)
+	require.NotContains(t, apiErr.Detail, "users_pkey")
+	require.ErrorIs(t, apiErr, err)
+
+	apiErr = FromDB(sql.ErrConnDone, User)
+	require.Equal(t, CodeInternal, apiErr.Code)
+	require.NotContains(t, apiErr.Detail, sql.ErrConnDone.Error())
+	require.ErrorIs(t, apiErr, sql.ErrConnDone)
+}
@@ -0,0 +1,95 @@
+// Package apierror defines the errors returned by the API.
+// Every error has a stable Code and is rendered as an RFC 7807 problem details object.
+package apierror
+
+import (
+	"fmt"
+)
+
+// ContentType is the media type of the problem details responses
+const ContentType = "application/problem+json"
+
+// typePrefix makes the problem type a URI identifying the code
+const typePrefix = "urn:simplebank:problem:"
+
+// FieldError describes why a field of the request is invalid
+type FieldError struct {
+	Field  string `json:"field"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+// Error is an API error.
+// Its detail is sent to the client, while its cause is only meant for the logs.
+type Error struct {
+	Code   Code
+	Detail string
+	Fields []FieldError
+	cause  error
+}
+
+// New creates an error with the given detail
+func New(code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail}
+}
+
+// Wrap creates an error whose detail is the message of err.
+// It must only be used for errors whose message is safe to send to the client.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Detail: err.Error(), cause: err}
+}
+
+// Internal creates an error that hides err from the client
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Detail: "the server encountered an unexpected error", cause: err}
+}
+
+// Validation creates a validation_failed error for a single field
+func Validation(field string, rule string, detail string) *Error {
+	return &Error{
+		Code:   CodeValidationFailed,
+		Detail: fmt.Sprintf("%s %s", field, detail),
+		Fields: []FieldError{{Field: field, Rule: rule, Detail: detail}},
+	}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Status returns the HTTP status of the error
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// Problem is the RFC 7807 representation of an Error, extended with its code and the invalid fields
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem returns the problem details of the error for the given request path
+func (e *Error) Problem(instance string) Problem {
+	return Problem{
+		Type:     typePrefix + string(e.Code),
+		Title:    e.Code.Title(),
+		Status:   e.Status(),
+		Detail:   e.Detail,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
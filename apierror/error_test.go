@@ -0,0 +1,65 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mrohadi/simplebank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodesAreDefined(t *testing.T) {
+	for code, def := range definitions {
+		require.NotEmpty(t, def.title, code)
+		require.GreaterOrEqual(t, def.status, http.StatusBadRequest, code)
+		require.Equal(t, def.status, code.Status())
+		require.Equal(t, def.title, code.Title())
+	}
+
+	unknown := Code("unknown")
+	require.Equal(t, http.StatusInternalServerError, unknown.Status())
+	require.Equal(t, CodeInternal.Title(), unknown.Title())
+}
+
+func TestProblem(t *testing.T) {
+	err := Validation("amount", "gt", "must be greater than 0")
+
+	problem := err.Problem("/transfers")
+	problem.RequestID = "request-id"
+
+	data, marshalErr := json.Marshal(problem)
+	require.NoError(t, marshalErr)
+	require.JSONEq(t, `{
+		"type": "urn:simplebank:problem:validation_failed",
+		"title": "The request is invalid",
+		"status": 400,
+		"detail": "amount must be greater than 0",
+		"instance": "/transfers",
+		"code": "validation_failed",
+		"request_id": "request-id",
+		"errors": [{"field": "amount", "rule": "gt", "detail": "must be greater than 0"}]
+	}`, string(data))
+}
+
+func TestInternal(t *testing.T) {
+	cause := fmt.Errorf("query accounts: %w", errors.New("connection refused"))
+
+	err := Internal(cause)
+	require.Equal(t, CodeInternal, err.Code)
+	require.Equal(t, http.StatusInternalServerError, err.Status())
+	require.NotContains(t, err.Detail, "connection refused")
+	require.ErrorIs(t, err, cause)
+	require.Contains(t, err.Error(), "connection refused")
+}
+
+func TestFromToken(t *testing.T) {
+	require.Equal(t, CodeTokenExpired, FromToken(token.ErrExpiredToken).Code)
+	require.Equal(t, CodeTokenInvalid, FromToken(token.ErrInvalidToken).Code)
+
+	err := FromToken(errors.New("paseto: invalid footer"))
+	require.Equal(t, CodeTokenInvalid, err.Code)
+	require.Equal(t, token.ErrInvalidToken.Error(), err.Detail)
+}
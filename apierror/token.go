@@ -0,0 +1,15 @@
+package apierror
+
+import (
+	"errors"
+
+	"github.com/mrohadi/simplebank/token"
+)
+
+// FromToken converts an error returned while verifying a token
+func FromToken(err error) *Error {
+	if errors.Is(err, token.ErrExpiredToken) {
+		return Wrap(CodeTokenExpired, token.ErrExpiredToken)
+	}
+	return &Error{Code: CodeTokenInvalid, Detail: token.ErrInvalidToken.Error(), cause: err}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+type accountStatusChangeResponse struct {
+	ID              int64     `json:"id"`
+	AccountID       int64     `json:"account_id"`
+	FromStatus      string    `json:"from_status"`
+	ToStatus        string    `json:"to_status"`
+	ChangedBy       string    `json:"changed_by"`
+	Reason          string    `json:"reason,omitempty"`
+	SweepTransferID *int64    `json:"sweep_transfer_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func newAccountStatusChangeResponse(change db.AccountStatusChange) accountStatusChangeResponse {
+	rsp := accountStatusChangeResponse{
+		ID:         change.ID,
+		AccountID:  change.AccountID,
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		ChangedBy:  change.ChangedBy,
+		Reason:     change.Reason,
+		CreatedAt:  change.CreatedAt,
+	}
+	if change.SweepTransferID.Valid {
+		rsp.SweepTransferID = &change.SweepTransferID.Int64
+	}
+
+	return rsp
+}
+
+type accountStatusResponse struct {
+	Account db.Account                  `json:"account"`
+	Change  accountStatusChangeResponse `json:"change"`
+	Sweep   *transferResponse           `json:"sweep,omitempty"`
+}
+
+type changeAccountStatusRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=255"`
+}
+
+// freezeAccount handle blocking every debit of an account
+func (s *Server) freezeAccount(ctx *gin.Context) {
+	s.changeAccountStatus(ctx, db.AccountStatusFrozen)
+}
+
+// unfreezeAccount handle making a frozen account active again
+func (s *Server) unfreezeAccount(ctx *gin.Context) {
+	s.changeAccountStatus(ctx, db.AccountStatusActive)
+}
+
+func (s *Server) changeAccountStatus(ctx *gin.Context, status string) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	// the body is optional, it only carries the reason of the transition
+	var req changeAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := s.store.ChangeAccountStatusTx(ctx, db.ChangeAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    status,
+		ChangedBy: authPayload.Username,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountStatusResponse{
+		Account: result.Account,
+		Change:  newAccountStatusChangeResponse(result.Change),
+	})
+}
+
+type closeAccountRequest struct {
+	// SweepAccountID receives the remaining balance, it must belong to the owner of the closed account
+	SweepAccountID int64  `json:"sweep_account_id" binding:"omitempty,min=1"`
+	Reason         string `json:"reason" binding:"omitempty,max=255"`
+}
+
+// closeAccount handle closing an account by its owner or by an admin
+func (s *Server) closeAccount(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	// the body is optional, an empty account can be closed without one
+	var req closeAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if req.SweepAccountID == uri.ID {
+		writeError(ctx, apierror.Validation("sweep_account_id", "nefield", "must differ from the closed account"))
+		return
+	}
+
+	account, err := s.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username && !hasRole(authPayload, utils.AdminRole) {
+		writeError(ctx, apierror.New(apierror.CodeAccountNotOwned, "account doesn't belong to the authorized user"))
+		return
+	}
+
+	if req.SweepAccountID != 0 {
+		sweepAccount, valid := s.validAccount(ctx, req.SweepAccountID, account.Currency)
+		if !valid {
+			return
+		}
+
+		if sweepAccount.Owner != account.Owner {
+			writeError(ctx, apierror.Validation("sweep_account_id", "owner", "must belong to the owner of the closed account"))
+			return
+		}
+	}
+
+	result, err := s.store.CloseAccountTx(ctx, db.CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: req.SweepAccountID,
+		ChangedBy:      authPayload.Username,
+		Reason:         req.Reason,
+		CloseFrozen:    hasRole(authPayload, utils.AdminRole),
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
+		return
+	}
+
+	rsp := accountStatusResponse{
+		Account: result.Account,
+		Change:  newAccountStatusChangeResponse(result.Change),
+	}
+	if result.Sweep != nil {
+		sweep := newTransferResponse(result.Sweep.Transfer)
+		rsp.Sweep = &sweep
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,425 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFreezeAccountAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	account := randomAccount(utils.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		path          string
+		accountID     int64
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "Freeze",
+			path:      "freeze",
+			accountID: account.ID,
+			body:      gin.H{"reason": "suspicious activity"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ChangeAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusFrozen,
+					ChangedBy: admin.Username,
+					Reason:    "suspicious activity",
+				}
+				frozen := account
+				frozen.Status = db.AccountStatusFrozen
+
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ChangeAccountStatusTxResult{
+						Account: frozen,
+						Change: db.AccountStatusChange{
+							AccountID:  account.ID,
+							FromStatus: db.AccountStatusActive,
+							ToStatus:   db.AccountStatusFrozen,
+							ChangedBy:  admin.Username,
+							Reason:     arg.Reason,
+						},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountStatusResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, db.AccountStatusFrozen, rsp.Account.Status)
+				require.Equal(t, db.AccountStatusActive, rsp.Change.FromStatus)
+				require.Equal(t, db.AccountStatusFrozen, rsp.Change.ToStatus)
+				require.Equal(t, admin.Username, rsp.Change.ChangedBy)
+				require.Nil(t, rsp.Sweep)
+			},
+		},
+		{
+			name:      "UnfreezeWithoutBody",
+			path:      "unfreeze",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ChangeAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusActive,
+					ChangedBy: admin.Username,
+				}
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ChangeAccountStatusTxResult{Account: account}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidTransition",
+			path:      "freeze",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ChangeAccountStatusTxResult{}, db.ErrInvalidStatusTransition)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInvalidStatusTransition)
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			path:      "freeze",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ChangeAccountStatusTxResult{}, db.ErrAccountNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotFound)
+			},
+		},
+		{
+			name:      "InvalidID",
+			path:      "freeze",
+			accountID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ChangeAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:      "InternalError",
+			path:      "unfreeze",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ChangeAccountStatusTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			url := fmt.Sprintf("/accounts/%d/%s", tc.accountID, tc.path)
+			request, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+
+			addRoleAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, utils.AdminRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCloseAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	sweepAccount := randomAccount(user.Username)
+	sweepAccount.ID = account.ID + 1
+	sweepAccount.Currency = account.Currency
+
+	otherAccount := randomAccount(utils.RandomOwner())
+	otherAccount.ID = account.ID + 2
+	otherAccount.Currency = account.Currency
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.CloseAccountTxParams{AccountID: account.ID, ChangedBy: user.Username}
+				closed := account
+				closed.Status = db.AccountStatusClosed
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{Account: closed}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountStatusResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, db.AccountStatusClosed, rsp.Account.Status)
+				require.Nil(t, rsp.Sweep)
+			},
+		},
+		{
+			name:      "SweepBalance",
+			accountID: account.ID,
+			body:      gin.H{"sweep_account_id": sweepAccount.ID, "reason": "moving out"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(sweepAccount.ID)).Times(1).Return(sweepAccount, nil)
+
+				arg := db.CloseAccountTxParams{
+					AccountID:      account.ID,
+					SweepAccountID: sweepAccount.ID,
+					ChangedBy:      user.Username,
+					Reason:         "moving out",
+				}
+				sweep := db.Transfer{
+					ID:            utils.RandomInt(1, 1000),
+					FromAccountID: account.ID,
+					ToAccountID:   sweepAccount.ID,
+					Amount:        account.Balance,
+				}
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{
+						Account: db.Account{ID: account.ID, Status: db.AccountStatusClosed},
+						Change: db.AccountStatusChange{
+							AccountID:       account.ID,
+							FromStatus:      db.AccountStatusActive,
+							ToStatus:        db.AccountStatusClosed,
+							SweepTransferID: sql.NullInt64{Int64: sweep.ID, Valid: true},
+						},
+						Sweep: &db.TransferTxResult{Transfer: sweep},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountStatusResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.Sweep)
+				require.Equal(t, sweepAccount.ID, rsp.Sweep.ToAccountID)
+				require.Equal(t, account.Balance, rsp.Sweep.Amount)
+				require.NotNil(t, rsp.Change.SweepTransferID)
+				require.Equal(t, rsp.Sweep.ID, *rsp.Change.SweepTransferID)
+			},
+		},
+		{
+			name:      "BalanceNotZero",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, db.ErrAccountBalanceNotZero)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountBalanceNotZero)
+			},
+		},
+		{
+			name:      "AlreadyClosed",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, db.ErrInvalidStatusTransition)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInvalidStatusTransition)
+			},
+		},
+		{
+			name:      "FrozenAccount",
+			accountID: account.ID,
+			body:      gin.H{"sweep_account_id": sweepAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(sweepAccount.ID)).Times(1).Return(sweepAccount, nil)
+
+				// only an admin can close a frozen account
+				arg := db.CloseAccountTxParams{
+					AccountID:      account.ID,
+					SweepAccountID: sweepAccount.ID,
+					ChangedBy:      user.Username,
+				}
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountFrozen)
+			},
+		},
+		{
+			name:      "SweepIntoItself",
+			accountID: account.ID,
+			body:      gin.H{"sweep_account_id": account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:      "SweepAccountOfAnotherOwner",
+			accountID: account.ID,
+			body:      gin.H{"sweep_account_id": otherAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherAccount.ID)).Times(1).Return(otherAccount, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:      "SweepAccountNotFound",
+			accountID: account.ID,
+			body:      gin.H{"sweep_account_id": sweepAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(sweepAccount.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotFound)
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotFound)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: otherAccount.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherAccount.ID)).Times(1).Return(otherAccount, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotOwned)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			url := fmt.Sprintf("/accounts/%d/close", tc.accountID)
+			request, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,12 +1,10 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/mrohadi/simplebank/apierror"
 	db "github.com/mrohadi/simplebank/db/sqlc"
 	"github.com/mrohadi/simplebank/token"
 )
@@ -19,7 +17,7 @@ type createAccountParams struct {
 func (s *Server) createAccount(ctx *gin.Context) {
 	var req createAccountParams
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
@@ -32,15 +30,7 @@ func (s *Server) createAccount(ctx *gin.Context) {
 
 	account, err := s.store.CreateAccount(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
 		return
 	}
 
@@ -55,25 +45,19 @@ type getAccountRequest struct {
 func (s *Server) getAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
 	account, err := s.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
 		return
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
-		err := errors.New("account doesn't belong to the authorized user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if !canReadAccount(authPayload, account.Owner) {
+		writeError(ctx, apierror.New(apierror.CodeAccountNotOwned, "account doesn't belong to the authorized user"))
 		return
 	}
 
@@ -81,28 +65,39 @@ func (s *Server) getAccount(ctx *gin.Context) {
 }
 
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	// Owner lists the accounts of another customer, it is restricted to bankers and admins
+	Owner    string `form:"owner" binding:"omitempty,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
 }
 
 // listAccount handle get list of accounts
 func (s *Server) listAccount(ctx *gin.Context) {
 	var req listAccountRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	owner := authPayload.Username
+	if len(req.Owner) > 0 {
+		if !canReadAccount(authPayload, req.Owner) {
+			writeError(ctx, apierror.New(apierror.CodeForbidden, "the authorized user cannot list accounts of another user"))
+			return
+		}
+		owner = req.Owner
+	}
+
 	arg := db.ListAccountsParams{
-		Owner:  authPayload.Username,
+		Owner:  owner,
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
 
 	account, err := s.store.ListAccounts(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, apierror.Internal(err))
 		return
 	}
 
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mrohadi/simplebank/apierror"
 	mockdb "github.com/mrohadi/simplebank/db/mock"
 	db "github.com/mrohadi/simplebank/db/sqlc"
 	"github.com/mrohadi/simplebank/token"
@@ -60,7 +61,7 @@ func TestGetAccount(t *testing.T) {
 					Return(db.Account{}, sql.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeAccountNotFound)
 			},
 		},
 		{
@@ -76,7 +77,7 @@ func TestGetAccount(t *testing.T) {
 					Return(db.Account{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeInternal)
 			},
 		},
 		{
@@ -92,7 +93,7 @@ func TestGetAccount(t *testing.T) {
 					Return(db.Account{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeInternal)
 			},
 		},
 		{
@@ -107,7 +108,7 @@ func TestGetAccount(t *testing.T) {
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
 			},
 		},
 	}
@@ -194,7 +195,7 @@ func TestCreateAccount(t *testing.T) {
 					Return(db.Account{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeInternal)
 			},
 		},
 		{
@@ -209,7 +210,7 @@ func TestCreateAccount(t *testing.T) {
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
 			},
 		},
 	}
@@ -308,7 +309,7 @@ func TestListAccountsAPI(t *testing.T) {
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeUnauthenticated)
 			},
 		},
 		{
@@ -327,7 +328,7 @@ func TestListAccountsAPI(t *testing.T) {
 					Return([]db.Account{}, sql.ErrConnDone)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeInternal)
 			},
 		},
 		{
@@ -345,7 +346,7 @@ func TestListAccountsAPI(t *testing.T) {
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
 			},
 		},
 		{
@@ -363,7 +364,7 @@ func TestListAccountsAPI(t *testing.T) {
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
 			},
 		},
 	}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+// A depositor only reaches its own accounts. A banker can read the accounts and
+// history of every customer, and an admin can do everything a banker can plus
+// manage users and intervene on any transfer.
+
+// hasRole reports whether the authorized user holds one of the given roles
+func hasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// canReadAnyAccount reports whether the authorized user can read accounts it doesn't own
+func canReadAnyAccount(payload *token.Payload) bool {
+	return hasRole(payload, utils.BankerRole, utils.AdminRole)
+}
+
+// canReadAccount reports whether the authorized user can read the accounts of owner
+func canReadAccount(payload *token.Payload, owner string) bool {
+	return payload.Username == owner || canReadAnyAccount(payload)
+}
+
+// requireRoles aborts the request unless the authorized user holds one of the given roles.
+// It must run after authMiddleware.
+func requireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !hasRole(authPayload, roles...) {
+			writeError(ctx, apierror.New(apierror.CodeForbidden, "the authorized user doesn't have the required role"))
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRoleAuthorization(t *testing.T) {
+	customer, _ := randomUser(t)
+	other, _ := randomUser(t)
+	caller, _ := randomUser(t)
+
+	account := randomAccount(customer.Username)
+	otherAccount := randomAccount(other.Username)
+	otherAccount.ID = account.ID + 1
+	transfer := randomTransfer(otherAccount, account)
+
+	// times returns the number of expected calls of a store method guarded by the authorization
+	times := func(allowed bool) int {
+		if allowed {
+			return 1
+		}
+		return 0
+	}
+
+	routes := []struct {
+		name       string
+		method     string
+		url        string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore, allowed bool)
+		expected   map[string]int
+	}{
+		{
+			name:   "GetAccount",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusOK,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListAccountsOfAnotherOwner",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts?owner=%s&page_id=1&page_size=5", customer.Username),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				arg := db.ListAccountsParams{Owner: customer.Username, Limit: 5, Offset: 0}
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(times(allowed)).
+					Return([]db.Account{account}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusForbidden,
+				utils.BankerRole:    http.StatusOK,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListAccountEntries",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/entries?page_size=5", account.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(times(allowed)).
+					Return([]db.Entry{}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusOK,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListAccountTransfers",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/transfers?page_size=5", account.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Any()).
+					Times(times(allowed)).
+					Return([]db.Transfer{}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusOK,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "GetTransfer",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/transfers/%d", transfer.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherAccount.ID)).Times(times(!allowed)).Return(otherAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(times(!allowed)).Return(account, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusOK,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "CreateReversal",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/transfers/%d/reversal", transfer.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				allowVerifiedEmail(store)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(times(!allowed)).Return(account, nil)
+				store.EXPECT().
+					ReversalTx(gomock.Any(), gomock.Eq(db.ReversalTxParams{TransferID: transfer.ID})).
+					Times(times(allowed)).
+					Return(db.ReversalTxResult{Original: transfer}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusUnauthorized,
+				utils.AdminRole:     http.StatusCreated,
+			},
+		},
+		{
+			name:   "CreateTransferFromAnotherOwner",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"to_account_id":   otherAccount.ID,
+				"amount":          10,
+				"currency":        account.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				allowVerifiedEmail(store)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusUnauthorized,
+				utils.AdminRole:     http.StatusUnauthorized,
+			},
+		},
+		{
+			name:   "FreezeAccount",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/accounts/%d/freeze", account.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(times(allowed)).
+					Return(db.ChangeAccountStatusTxResult{Account: account}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusForbidden,
+				utils.BankerRole:    http.StatusForbidden,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "UnfreezeAccount",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/accounts/%d/unfreeze", account.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(times(allowed)).
+					Return(db.ChangeAccountStatusTxResult{Account: account}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusForbidden,
+				utils.BankerRole:    http.StatusForbidden,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "CloseAccountOfAnotherOwner",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/accounts/%d/close", account.ID),
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CloseAccountTx(gomock.Any(), gomock.Any()).
+					Times(times(allowed)).
+					DoAndReturn(func(_ any, arg db.CloseAccountTxParams) (db.CloseAccountTxResult, error) {
+						require.True(t, arg.CloseFrozen)
+						return db.CloseAccountTxResult{Account: account}, nil
+					})
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusUnauthorized,
+				utils.BankerRole:    http.StatusUnauthorized,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "UpdateUserRole",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/users/%s/role", customer.Username),
+			body:   gin.H{"role": utils.BankerRole},
+			buildStubs: func(store *mockdb.MockStore, allowed bool) {
+				store.EXPECT().
+					ChangeUserRoleTx(gomock.Any(), gomock.Any()).
+					Times(times(allowed)).
+					Return(db.ChangeUserRoleTxResult{User: customer}, nil)
+			},
+			expected: map[string]int{
+				utils.DepositorRole: http.StatusForbidden,
+				utils.BankerRole:    http.StatusForbidden,
+				utils.AdminRole:     http.StatusOK,
+			},
+		},
+	}
+
+	for _, route := range routes {
+		for _, role := range []string{utils.DepositorRole, utils.BankerRole, utils.AdminRole} {
+			route, role := route, role
+			expected := route.expected[role]
+
+			t.Run(fmt.Sprintf("%s/%s", route.name, role), func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				store := mockdb.NewMockStore(ctrl)
+				route.buildStubs(store, expected < http.StatusBadRequest)
+
+				server := newTestServer(t, store)
+				recorder := httptest.NewRecorder()
+
+				var body bytes.Buffer
+				if route.body != nil {
+					err := json.NewEncoder(&body).Encode(route.body)
+					require.NoError(t, err)
+				}
+
+				request, err := http.NewRequest(route.method, route.url, &body)
+				require.NoError(t, err)
+
+				addRoleAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, caller.Username, role, time.Minute)
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, expected, recorder.Code)
+			})
+		}
+	}
+}
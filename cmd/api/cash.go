@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+)
+
+type cashRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,currency"`
+	// TOTPCode is the one-time code of the banker required for the withdrawals above the step-up threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+type cashResponse struct {
+	Transfer db.Transfer `json:"transfer"`
+	Account  db.Account  `json:"account"`
+	Entry    db.Entry    `json:"entry"`
+}
+
+// createDeposit handle depositing cash into an account
+func (s *Server) createDeposit(ctx *gin.Context) {
+	result, ok := s.createCashTx(ctx, s.store.DepositTx, false)
+	if !ok {
+		return
+	}
+
+	rsp := cashResponse{
+		Transfer: result.Transfer,
+		Account:  result.ToAccount,
+		Entry:    result.ToEntry,
+	}
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// createWithdrawal handle withdrawing cash from an account
+func (s *Server) createWithdrawal(ctx *gin.Context) {
+	result, ok := s.createCashTx(ctx, s.store.WithdrawalTx, true)
+	if !ok {
+		return
+	}
+
+	rsp := cashResponse{
+		Transfer: result.Transfer,
+		Account:  result.FromAccount,
+		Entry:    result.FromEntry,
+	}
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// createCashTx validates a deposit or withdrawal request and runs it with the given transaction.
+// Cash only moves through a banker or an admin at the counter, so the account can belong to any customer.
+// With stepUp the amounts above the step-up threshold require a one-time code of the banker.
+// It writes the error response itself and reports whether the transaction succeeded.
+func (s *Server) createCashTx(
+	ctx *gin.Context,
+	cashTx func(context.Context, db.CashTxParams) (db.TransferTxResult, error),
+	stepUp bool,
+) (db.TransferTxResult, bool) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return db.TransferTxResult{}, false
+	}
+
+	var req cashRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return db.TransferTxResult{}, false
+	}
+
+	account, valid := s.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return db.TransferTxResult{}, false
+	}
+
+	if stepUp {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if apiErr := s.checkStepUp(ctx, authPayload.Username, req.Amount, req.TOTPCode); apiErr != nil {
+			writeError(ctx, apiErr)
+			return db.TransferTxResult{}, false
+		}
+	}
+
+	result, err := cashTx(ctx, db.CashTxParams{
+		AccountID: account.ID,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
+		return db.TransferTxResult{}, false
+	}
+
+	return result, true
+}
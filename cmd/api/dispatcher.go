@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/mail"
+)
+
+const (
+	defaultMailDispatchInterval = time.Minute
+
+	// verifyEmailBatchSize is the number of verification emails claimed in a single transaction
+	verifyEmailBatchSize = 10
+
+	// verifyEmailLease postpones claimed emails, so an email whose delivery was interrupted is only retried after the lease
+	verifyEmailLease = 5 * time.Minute
+
+	// verifyEmailRetryBackoff is the delay before retrying a failed delivery, doubled after each failure
+	verifyEmailRetryBackoff = time.Minute
+
+	// maxVerifyEmailSendAttempts is the number of failed deliveries after which an email is given up
+	maxVerifyEmailSendAttempts = 5
+
+	// mailQueueSize is the number of queued messages waiting for the dispatcher, further messages are dropped
+	mailQueueSize = 100
+)
+
+// mailDispatcher delivers the emails in the background so that no request waits on the mail server.
+// The verification emails are stored with the user, so an email survives a restart. Every instance of the server
+// runs one, claimed emails are locked so an email is never sent twice at once.
+// The other messages carry secrets that are not stored, they are queued in memory and sent once.
+type mailDispatcher struct {
+	store    db.Store
+	mailer   mail.Sender
+	appURL   string
+	interval time.Duration
+	wake     chan struct{}
+	messages chan mail.Message
+}
+
+func newMailDispatcher(store db.Store, mailer mail.Sender, appURL string, interval time.Duration) *mailDispatcher {
+	if interval <= 0 {
+		interval = defaultMailDispatchInterval
+	}
+
+	return &mailDispatcher{
+		store:    store,
+		mailer:   mailer,
+		appURL:   strings.TrimSuffix(appURL, "/"),
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+		messages: make(chan mail.Message, mailQueueSize),
+	}
+}
+
+// Enqueue hands a message to the dispatcher, it never blocks and reports false when the queue is full
+func (d *mailDispatcher) Enqueue(msg mail.Message) bool {
+	select {
+	case d.messages <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver sends a queued message, a failed delivery is not retried
+func (d *mailDispatcher) deliver(ctx context.Context, msg mail.Message) {
+	if err := d.mailer.Send(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "cannot send email", slog.String("subject", msg.Subject), slog.Any("error", err))
+	}
+}
+
+// Notify wakes up the dispatcher without waiting for the next interval, it never blocks
+func (d *mailDispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DispatchPending sends all the verification emails that are due and returns how many were delivered
+func (d *mailDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		claimed, err := d.store.ClaimVerifyEmailsTx(ctx, db.ClaimVerifyEmailsTxParams{
+			Now:       time.Now(),
+			BatchSize: verifyEmailBatchSize,
+			Lease:     verifyEmailLease,
+		})
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, email := range claimed {
+			ok, err := d.send(ctx, email)
+			if err != nil {
+				return delivered, err
+			}
+			if ok {
+				delivered++
+			}
+		}
+
+		if len(claimed) < verifyEmailBatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// send delivers a claimed email and records the outcome, a failed delivery is retried later.
+// Only the hash of the secret code is stored, so every attempt mails a new code replacing the previous one.
+func (d *mailDispatcher) send(ctx context.Context, email db.VerifyEmail) (bool, error) {
+	secretCode, err := randomSecret(verifyEmailSecretCodeSize)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = d.store.UpdateVerifyEmailSecretCode(ctx, db.UpdateVerifyEmailSecretCodeParams{
+		ID:             email.ID,
+		SecretCodeHash: hashSecret(secretCode),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	sendErr := d.mailer.Send(ctx, d.message(email, secretCode))
+	if sendErr == nil {
+		_, err = d.store.MarkVerifyEmailSent(ctx, email.ID)
+		return true, err
+	}
+
+	slog.ErrorContext(ctx, "cannot send verification email",
+		slog.String("username", email.Username),
+		slog.Int("attempts", int(email.SendAttempts)),
+		slog.Any("error", sendErr))
+
+	arg := db.ScheduleVerifyEmailSendParams{
+		ID:           email.ID,
+		SendAttempts: email.SendAttempts,
+	}
+	if email.SendAttempts < maxVerifyEmailSendAttempts {
+		backoff := verifyEmailRetryBackoff << (email.SendAttempts - 1)
+		arg.NextSendAt = sql.NullTime{Time: time.Now().Add(backoff), Valid: true}
+	}
+
+	_, err = d.store.ScheduleVerifyEmailSend(ctx, arg)
+	return false, err
+}
+
+func (d *mailDispatcher) message(email db.VerifyEmail, secretCode string) mail.Message {
+	query := url.Values{}
+	query.Set("id", strconv.FormatInt(email.ID, 10))
+	query.Set("code", secretCode)
+	link := fmt.Sprintf("%s/users/verify_email?%s", d.appURL, query.Encode())
+
+	return mail.Message{
+		To:      email.Email,
+		Subject: "Verify your Simple Bank email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Open the following link to verify your email address:\n\n%s\n\n"+
+			"It expires at %s. Transfers are enabled once the address is verified.\n",
+			email.Username, link, email.ExpiresAt.UTC().Format(time.RFC1123)),
+	}
+}
+
+// Run sends the due verification emails every interval, or as soon as it is notified, and the queued messages
+// as they come until the context is canceled
+func (d *mailDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil {
+			slog.ErrorContext(ctx, "cannot dispatch verification emails", slog.Any("error", err))
+		}
+
+		if !d.wait(ctx, ticker.C) {
+			return
+		}
+	}
+}
+
+// wait delivers the queued messages until the verification emails are due again,
+// it returns false once the context is canceled
+func (d *mailDispatcher) wait(ctx context.Context, tick <-chan time.Time) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-tick:
+			return true
+		case <-d.wake:
+			return true
+		case msg := <-d.messages:
+			d.deliver(ctx, msg)
+		}
+	}
+}
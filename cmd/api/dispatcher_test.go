@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/mail"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMailDispatcherDispatchPending(t *testing.T) {
+	email := db.VerifyEmail{
+		ID:           1,
+		Username:     "alice",
+		Email:        "alice@example.com",
+		SendAttempts: 1,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	lastAttempt := email
+	lastAttempt.SendAttempts = maxVerifyEmailSendAttempts
+
+	testCases := []struct {
+		name       string
+		email      db.VerifyEmail
+		sendErr    error
+		delivered  int
+		buildStubs func(t *testing.T, store *mockdb.MockStore, email db.VerifyEmail)
+	}{
+		{
+			name:      "Sent",
+			email:     email,
+			delivered: 1,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, email db.VerifyEmail) {
+				store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Eq(email.ID)).Times(1).Return(email, nil)
+				store.EXPECT().ScheduleVerifyEmailSend(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:    "FailureBacksOff",
+			email:   email,
+			sendErr: errors.New("mail server unavailable"),
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, email db.VerifyEmail) {
+				store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					ScheduleVerifyEmailSend(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ScheduleVerifyEmailSendParams) (db.VerifyEmail, error) {
+						require.Equal(t, email.ID, arg.ID)
+						require.Equal(t, email.SendAttempts, arg.SendAttempts)
+						require.True(t, arg.NextSendAt.Valid)
+						require.WithinDuration(t, time.Now().Add(verifyEmailRetryBackoff), arg.NextSendAt.Time, time.Second)
+						return email, nil
+					})
+			},
+		},
+		{
+			name:    "LastFailureGivesUp",
+			email:   lastAttempt,
+			sendErr: errors.New("mailbox unavailable"),
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, email db.VerifyEmail) {
+				store.EXPECT().
+					ScheduleVerifyEmailSend(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ScheduleVerifyEmailSendParams) (db.VerifyEmail, error) {
+						require.False(t, arg.NextSendAt.Valid)
+						return email, nil
+					})
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ClaimVerifyEmailsTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.ClaimVerifyEmailsTxParams) ([]db.VerifyEmail, error) {
+					require.Equal(t, int32(verifyEmailBatchSize), arg.BatchSize)
+					require.Equal(t, verifyEmailLease, arg.Lease)
+					return []db.VerifyEmail{tc.email}, nil
+				})
+			var storedHash string
+			store.EXPECT().
+				UpdateVerifyEmailSecretCode(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.UpdateVerifyEmailSecretCodeParams) (db.VerifyEmail, error) {
+					require.Equal(t, tc.email.ID, arg.ID)
+					storedHash = arg.SecretCodeHash
+					return tc.email, nil
+				})
+			tc.buildStubs(t, store, tc.email)
+
+			sender := &recordingSender{err: tc.sendErr}
+			dispatcher := newMailDispatcher(store, sender, "http://localhost:8080", time.Minute)
+
+			delivered, err := dispatcher.DispatchPending(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tc.delivered, delivered)
+
+			messages := sender.Messages()
+			require.Len(t, messages, 1)
+			require.Equal(t, tc.email.Email, messages[0].To)
+
+			// the mailed code is the one whose hash was stored
+			var code string
+			for _, field := range strings.Fields(messages[0].Body) {
+				if link, err := url.Parse(field); err == nil && link.Query().Has("code") {
+					code = link.Query().Get("code")
+				}
+			}
+			require.NotEmpty(t, code)
+			require.Equal(t, storedHash, hashSecret(code))
+		})
+	}
+}
+
+func TestMailDispatcherRunWakesUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claims := make(chan struct{}, 2)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimVerifyEmailsTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ any, _ db.ClaimVerifyEmailsTxParams) ([]db.VerifyEmail, error) {
+			claims <- struct{}{}
+			return nil, nil
+		})
+
+	// the interval is too long to tick during the test, only Notify can trigger the second dispatch
+	dispatcher := newMailDispatcher(store, &recordingSender{}, "", time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dispatcher.Run(ctx)
+	}()
+
+	<-claims
+	dispatcher.Notify()
+	<-claims
+
+	cancel()
+	<-done
+}
+
+// deliverQueued sends the messages queued so far, as Run would
+func deliverQueued(d *mailDispatcher) {
+	for {
+		select {
+		case msg := <-d.messages:
+			d.deliver(context.Background(), msg)
+		default:
+			return
+		}
+	}
+}
+
+func TestMailDispatcherRunDeliversQueuedMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimVerifyEmailsTx(gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+
+	// the queued message is delivered without waiting for the interval or a notification
+	sender := &recordingSender{}
+	dispatcher := newMailDispatcher(store, sender, "", time.Hour)
+	msg := mail.Message{To: "alice@example.com", Subject: "Hello", Body: "Hello Alice"}
+	require.True(t, dispatcher.Enqueue(msg))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dispatcher.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(sender.Messages()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, msg, sender.Messages()[0])
+
+	cancel()
+	<-done
+}
+
+func TestMailDispatcherEnqueueFull(t *testing.T) {
+	dispatcher := newMailDispatcher(nil, &recordingSender{}, "", 0)
+	for i := 0; i < mailQueueSize; i++ {
+		require.True(t, dispatcher.Enqueue(mail.Message{}))
+	}
+
+	require.False(t, dispatcher.Enqueue(mail.Message{}))
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+// writeError aborts the request with the problem details of err.
+// Only the detail of err is sent to the client, its cause is attached to the context for the request log.
+func writeError(ctx *gin.Context, err *apierror.Error) {
+	if cause := err.Unwrap(); cause != nil {
+		_ = ctx.Error(cause)
+	}
+
+	problem := err.Problem(ctx.Request.URL.Path)
+	problem.RequestID = utils.RequestIDFromContext(ctx.Request.Context())
+
+	ctx.Header("Content-Type", apierror.ContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	"github.com/stretchr/testify/require"
+)
+
+// requireProblem checks the response is the problem details of an error with the given code
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, code apierror.Code) apierror.Problem {
+	require.Equal(t, code.Status(), recorder.Code)
+	require.Equal(t, apierror.ContentType, recorder.Header().Get("Content-Type"))
+
+	var problem apierror.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, code, problem.Code)
+	require.Equal(t, code.Status(), problem.Status)
+	require.Equal(t, "urn:simplebank:problem:"+string(code), problem.Type)
+	require.NotEmpty(t, problem.Title)
+
+	return problem
+}
+
+func TestWriteError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           *apierror.Error
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Detail",
+			err:  apierror.New(apierror.CodeInsufficientFunds, "insufficient funds"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeInsufficientFunds)
+				require.Equal(t, "insufficient funds", problem.Detail)
+				require.Equal(t, "/error", problem.Instance)
+				require.Equal(t, recorder.Header().Get(requestIDHeaderKey), problem.RequestID)
+				require.Empty(t, problem.Errors)
+			},
+		},
+		{
+			name: "Fields",
+			err:  apierror.Validation("amount", "gt", "must be greater than 0"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeValidationFailed)
+				require.Equal(t, []apierror.FieldError{{Field: "amount", Rule: "gt", Detail: "must be greater than 0"}}, problem.Errors)
+			},
+		},
+		{
+			name: "InternalHidesCause",
+			err:  apierror.FromDB(fmt.Errorf("pq: relation \"accounts\" does not exist: %w", sql.ErrConnDone), apierror.Account),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeInternal)
+				require.NotContains(t, recorder.Body.String(), "accounts")
+				require.NotContains(t, recorder.Body.String(), sql.ErrConnDone.Error())
+				require.NotEmpty(t, problem.Detail)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			var loggedErrors []string
+
+			router := gin.New()
+			router.Use(requestIDMiddleware())
+			router.GET("/error", func(ctx *gin.Context) {
+				writeError(ctx, tc.err)
+				loggedErrors = ctx.Errors.Errors()
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/error", nil)
+			require.NoError(t, err)
+
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+
+			// the cause is only kept for the request log
+			if cause := tc.err.Unwrap(); cause != nil {
+				require.Equal(t, []string{cause.Error()}, loggedErrors)
+			}
+		})
+	}
+}
+
+func TestValidationFieldNames(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	data, err := json.Marshal(gin.H{
+		"username":  "invalid-username",
+		"password":  "123",
+		"full_name": "",
+		"email":     "someone@example.com",
+	})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+
+	problem := requireProblem(t, recorder, apierror.CodeValidationFailed)
+	require.Equal(t, []apierror.FieldError{
+		{Field: "username", Rule: "alphanum", Detail: "must contain only letters and digits"},
+		{Field: "password", Rule: "min", Detail: "must be at least 6 characters long"},
+		{Field: "full_name", Rule: "required", Detail: "is required"},
+	}, problem.Errors)
+}
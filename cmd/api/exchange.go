@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+)
+
+type createRateQuoteRequest struct {
+	FromCurrency string `json:"from_currency" binding:"required,currency"`
+	ToCurrency   string `json:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+}
+
+type rateQuoteResponse struct {
+	QuoteID      uuid.UUID `json:"quote_id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	SpreadBps    int32     `json:"spread_bps"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func newRateQuoteResponse(quote db.RateQuote) rateQuoteResponse {
+	return rateQuoteResponse{
+		QuoteID:      quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		Rate:         quote.Rate,
+		SpreadBps:    quote.SpreadBps,
+		ExpiresAt:    quote.ExpiresAt,
+	}
+}
+
+// createRateQuote locks in the current exchange rate for the authenticated user.
+// The returned quote ID can be used for a single cross-currency transfer until it expires.
+func (s *Server) createRateQuote(ctx *gin.Context) {
+	var req createRateQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	now := time.Now()
+	rate, err := s.store.GetExchangeRate(ctx, db.GetExchangeRateParams{
+		BaseCurrency:  req.FromCurrency,
+		QuoteCurrency: req.ToCurrency,
+		EffectiveAt:   now,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.ExchangeRate))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	quote, err := s.store.CreateRateQuote(ctx, db.CreateRateQuoteParams{
+		ID:           uuid.New(),
+		Username:     authPayload.Username,
+		FromCurrency: rate.BaseCurrency,
+		ToCurrency:   rate.QuoteCurrency,
+		Rate:         rate.Rate,
+		SpreadBps:    rate.SpreadBps,
+		ExpiresAt:    now.Add(s.config.RateQuoteDuration),
+	})
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newRateQuoteResponse(quote))
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateRateQuoteAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	rate := db.ExchangeRate{
+		ID:            utils.RandomInt(1, 1000),
+		BaseCurrency:  utils.USD,
+		QuoteCurrency: utils.EUR,
+		Rate:          "0.9200000000",
+		SpreadBps:     50,
+		EffectiveAt:   time.Now().Add(-time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_currency": utils.USD,
+				"to_currency":   utils.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetExchangeRate(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.GetExchangeRateParams) (db.ExchangeRate, error) {
+						require.Equal(t, utils.USD, arg.BaseCurrency)
+						require.Equal(t, utils.EUR, arg.QuoteCurrency)
+						require.WithinDuration(t, time.Now(), arg.EffectiveAt, time.Second)
+						return rate, nil
+					})
+				store.EXPECT().
+					CreateRateQuote(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateRateQuoteParams) (db.RateQuote, error) {
+						require.NotEqual(t, uuid.Nil, arg.ID)
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, rate.Rate, arg.Rate)
+						require.Equal(t, rate.SpreadBps, arg.SpreadBps)
+						require.WithinDuration(t, time.Now().Add(30*time.Second), arg.ExpiresAt, time.Second)
+						return db.RateQuote{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							FromCurrency: arg.FromCurrency,
+							ToCurrency:   arg.ToCurrency,
+							Rate:         arg.Rate,
+							SpreadBps:    arg.SpreadBps,
+							ExpiresAt:    arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp rateQuoteResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEqual(t, uuid.Nil, rsp.QuoteID)
+				require.Equal(t, utils.USD, rsp.FromCurrency)
+				require.Equal(t, utils.EUR, rsp.ToCurrency)
+				require.Equal(t, rate.Rate, rsp.Rate)
+				require.Equal(t, rate.SpreadBps, rsp.SpreadBps)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"from_currency": utils.USD,
+				"to_currency":   utils.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetExchangeRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUnauthenticated)
+			},
+		},
+		{
+			name: "SameCurrency",
+			body: gin.H{
+				"from_currency": utils.USD,
+				"to_currency":   utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetExchangeRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "InvalidCurrency",
+			body: gin.H{
+				"from_currency": utils.USD,
+				"to_currency":   "XYZ",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetExchangeRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "RateNotFound",
+			body: gin.H{
+				"from_currency": utils.USD,
+				"to_currency":   utils.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetExchangeRate(gomock.Any(), gomock.Any()).Times(1).Return(db.ExchangeRate{}, sql.ErrNoRows)
+				store.EXPECT().CreateRateQuote(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeExchangeRateNotFound)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"from_currency": utils.USD,
+				"to_currency":   utils.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetExchangeRate(gomock.Any(), gomock.Any()).Times(1).Return(rate, nil)
+				store.EXPECT().CreateRateQuote(gomock.Any(), gomock.Any()).Times(1).Return(db.RateQuote{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/rate_quotes", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomRateQuote(username, fromCurrency, toCurrency string) db.RateQuote {
+	return db.RateQuote{
+		ID:           uuid.New(),
+		Username:     username,
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Rate:         "0.9200000000",
+		SpreadBps:    50,
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}
+}
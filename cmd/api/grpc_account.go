@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+)
+
+// CreateAccount is the gRPC counterpart of createAccount
+func (s *grpcServer) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	params := createAccountParams{Currency: req.GetCurrency()}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	authPayload := grpcAuthPayload(ctx)
+	account, err := s.store.CreateAccount(ctx, db.CreateAccountParams{
+		Owner:    authPayload.Username,
+		Currency: params.Currency,
+		Balance:  0,
+	})
+	if err != nil {
+		return nil, grpcError(apierror.FromDB(err, apierror.Account))
+	}
+
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+// GetAccount is the gRPC counterpart of getAccount
+func (s *grpcServer) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	params := getAccountRequest{ID: req.GetId()}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	account, err := s.store.GetAccount(ctx, params.ID)
+	if err != nil {
+		return nil, grpcError(apierror.FromDB(err, apierror.Account))
+	}
+
+	if !canReadAccount(grpcAuthPayload(ctx), account.Owner) {
+		return nil, grpcError(apierror.New(apierror.CodeAccountNotOwned, "account doesn't belong to the authorized user"))
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+// ListAccounts is the gRPC counterpart of listAccount
+func (s *grpcServer) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	params := listAccountRequest{
+		Owner:    req.GetOwner(),
+		PageID:   req.GetPageId(),
+		PageSize: req.GetPageSize(),
+	}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	authPayload := grpcAuthPayload(ctx)
+	owner := authPayload.Username
+	if len(params.Owner) > 0 {
+		if !canReadAccount(authPayload, params.Owner) {
+			return nil, grpcError(apierror.New(apierror.CodeForbidden, "the authorized user cannot list accounts of another user"))
+		}
+		owner = params.Owner
+	}
+
+	accounts, err := s.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  owner,
+		Limit:  params.PageSize,
+		Offset: (params.PageID - 1) * params.PageSize,
+	})
+	if err != nil {
+		return nil, grpcError(apierror.Internal(err))
+	}
+
+	rsp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, len(accounts))}
+	for i, account := range accounts {
+		rsp.Accounts[i] = convertAccount(account)
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateAccountGRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		currency   string
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name:     "OK",
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{Owner: user.Username, Currency: account.Currency}
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:     "DuplicateCurrency",
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23505"})
+			},
+			code: codes.AlreadyExists,
+		},
+		{
+			name:     "UnknownOwner",
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23503"})
+			},
+			code: codes.FailedPrecondition,
+		},
+		{
+			name:     "InvalidCurrency",
+			currency: "invalid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			rsp, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Currency: tc.currency})
+			requireGRPCCode(t, err, tc.code)
+			if tc.code == codes.OK {
+				require.Equal(t, account.ID, rsp.GetAccount().GetId())
+				require.Equal(t, account.Balance, rsp.GetAccount().GetBalance())
+			}
+		})
+	}
+}
+
+func TestGetAccountGRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		accountID  int64
+		setupAuth  func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:      "BankerReadsAnotherOwner",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, "banker", utils.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, "unauthorized_user", utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			code: codes.PermissionDenied,
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			code: codes.NotFound,
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrConnDone)
+			},
+			code: codes.Internal,
+		},
+		{
+			name:      "InvalidID",
+			accountID: 0,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			rsp, err := client.GetAccount(tc.setupAuth(t, server.tokenMaker), &pb.GetAccountRequest{Id: tc.accountID})
+			requireGRPCCode(t, err, tc.code)
+			if tc.code == codes.OK {
+				require.Equal(t, account.Owner, rsp.GetAccount().GetOwner())
+			}
+		})
+	}
+}
+
+func TestListAccountsGRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	accounts := []db.Account{randomAccount(user.Username), randomAccount(user.Username)}
+
+	testCases := []struct {
+		name       string
+		req        *pb.ListAccountsRequest
+		role       string
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name: "OK",
+			req:  &pb.ListAccountsRequest{PageId: 1, PageSize: 5},
+			role: utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{Owner: user.Username, Limit: 5, Offset: 0}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name: "AnotherOwnerAsDepositor",
+			req:  &pb.ListAccountsRequest{Owner: "another", PageId: 1, PageSize: 5},
+			role: utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "AnotherOwnerAsBanker",
+			req:  &pb.ListAccountsRequest{Owner: "another", PageId: 1, PageSize: 5},
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{Owner: "another", Limit: 5, Offset: 0}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name: "InvalidPageSize",
+			req:  &pb.ListAccountsRequest{PageId: 1, PageSize: 100},
+			role: utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, user.Username, tc.role, time.Minute)
+			rsp, err := client.ListAccounts(ctx, tc.req)
+			requireGRPCCode(t, err, tc.code)
+			if tc.code == codes.OK {
+				require.Len(t, rsp.GetAccounts(), len(accounts))
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+		IsEmailVerified:   user.IsEmailVerified,
+		IsTotpEnabled:     user.IsTotpEnabled,
+	}
+}
+
+func convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		Id:        account.ID,
+		Owner:     account.Owner,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		Status:    account.Status,
+		CreatedAt: timestamppb.New(account.CreatedAt),
+	}
+}
+
+func convertEntry(entry db.Entry) *pb.Entry {
+	return &pb.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID,
+		Amount:    entry.Amount,
+		Kind:      entry.Kind,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+}
+
+// convertTransfer builds on transferResponse so both APIs report the same reversal status
+func convertTransfer(transfer db.Transfer) *pb.Transfer {
+	rsp := newTransferResponse(transfer)
+
+	result := &pb.Transfer{
+		Id:                 rsp.ID,
+		FromAccountId:      rsp.FromAccountID,
+		ToAccountId:        rsp.ToAccountID,
+		Amount:             rsp.Amount,
+		ConvertedAmount:    rsp.ConvertedAmount,
+		ExchangeRate:       rsp.ExchangeRate,
+		SpreadBps:          rsp.SpreadBps,
+		ReversesTransferId: rsp.ReversesTransferID,
+		ReversedAmount:     rsp.ReversedAmount,
+		ReversalStatus:     rsp.ReversalStatus,
+		CreatedAt:          timestamppb.New(rsp.CreatedAt),
+	}
+	if rsp.QuoteID != nil {
+		result.QuoteId = rsp.QuoteID.String()
+	}
+
+	return result
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mrohadi/simplebank/apierror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo attached to the gRPC errors
+const grpcErrorDomain = "simplebank"
+
+// grpcCodes overrides the gRPC code of the API errors whose HTTP status maps to a less precise one
+var grpcCodes = map[apierror.Code]codes.Code{
+	apierror.CodeAlreadyExists:         codes.AlreadyExists,
+	apierror.CodeUserAlreadyExists:     codes.AlreadyExists,
+	apierror.CodeAccountAlreadyExists:  codes.AlreadyExists,
+	apierror.CodeSessionNotOwned:       codes.PermissionDenied,
+	apierror.CodeAccountNotOwned:       codes.PermissionDenied,
+	apierror.CodeTransferNotOwned:      codes.PermissionDenied,
+	apierror.CodeRateQuoteNotOwned:     codes.PermissionDenied,
+	apierror.CodeStandingOrderNotOwned: codes.PermissionDenied,
+	apierror.CodeNotTransferRecipient:  codes.PermissionDenied,
+	apierror.CodeTransactionConflict:   codes.Aborted,
+	apierror.CodeUserLocked:            codes.PermissionDenied,
+}
+
+// validateGRPCRequest checks a gRPC request against the binding rules of the matching HTTP request
+func validateGRPCRequest(req any) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return grpcError(apierror.FromBinding(err))
+	}
+	return nil
+}
+
+// grpcError converts an API error into a gRPC status.
+// The status carries the code of the error in an ErrorInfo, and the invalid fields in a BadRequest.
+func grpcError(err *apierror.Error) error {
+	if err.Code == apierror.CodeInternal {
+		slog.Error("gRPC request failed", slog.Any("error", err))
+	}
+
+	code, ok := grpcCodes[err.Code]
+	if !ok {
+		code = grpcCode(err.Status())
+	}
+
+	st := status.New(code, err.Detail)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(err.Code), Domain: grpcErrorDomain}}
+	if len(err.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(err.Fields))
+		for i, field := range err.Fields {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Detail}
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	if withDetails, detailsErr := st.WithDetails(details...); detailsErr == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
+
+// grpcCode maps a HTTP status code returned by the Gin handlers to the matching gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	}
+
+	return codes.Internal
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    *apierror.Error
+		code   codes.Code
+		fields []string
+	}{
+		{
+			name: "InsufficientFunds",
+			err:  apierror.FromDB(db.ErrInsufficientFunds, apierror.Transfer),
+			code: codes.FailedPrecondition,
+		},
+		{
+			name: "CurrencyMismatch",
+			err:  apierror.FromDB(db.ErrCurrencyMismatch, apierror.Transfer),
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "AccountNotOwned",
+			err:  apierror.New(apierror.CodeAccountNotOwned, "account doesn't belong to the authorized user"),
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "TokenExpired",
+			err:  apierror.New(apierror.CodeTokenExpired, "token has expired"),
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "UserAlreadyExists",
+			err:  apierror.FromDB(&pq.Error{Code: "23505"}, apierror.User),
+			code: codes.AlreadyExists,
+		},
+		{
+			name: "Deadlock",
+			err:  apierror.FromDB(&pq.Error{Code: "40P01"}, apierror.Transfer),
+			code: codes.Aborted,
+		},
+		{
+			name:   "ValidationFailed",
+			err:    apierror.Validation("quote_id", "uuid", "must be a valid UUID"),
+			code:   codes.InvalidArgument,
+			fields: []string{"quote_id"},
+		},
+		{
+			name: "Internal",
+			err:  apierror.FromDB(sql.ErrConnDone, apierror.Account),
+			code: codes.Internal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			st, ok := status.FromError(grpcError(tc.err))
+			require.True(t, ok)
+			require.Equal(t, tc.code, st.Code())
+			require.Equal(t, tc.err.Detail, st.Message())
+			require.NotContains(t, st.Message(), sql.ErrConnDone.Error())
+
+			var fields []string
+			var info *errdetails.ErrorInfo
+			for _, detail := range st.Details() {
+				switch detail := detail.(type) {
+				case *errdetails.ErrorInfo:
+					info = detail
+				case *errdetails.BadRequest:
+					for _, violation := range detail.GetFieldViolations() {
+						fields = append(fields, violation.GetField())
+					}
+				}
+			}
+
+			require.NotNil(t, info)
+			require.Equal(t, string(tc.err.Code), info.GetReason())
+			require.Equal(t, grpcErrorDomain, info.GetDomain())
+			require.Equal(t, tc.fields, fields)
+		})
+	}
+}
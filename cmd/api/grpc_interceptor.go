@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	grpcUserAgentHeader    = "user-agent"
+	grpcForwardedForHeader = "x-forwarded-for"
+)
+
+// grpcPublicMethods can be called without an access token
+var grpcPublicMethods = map[string]bool{
+	pb.SimpleBank_CreateUser_FullMethodName:   true,
+	pb.SimpleBank_LoginUser_FullMethodName:    true,
+	pb.SimpleBank_LoginUserMFA_FullMethodName: true,
+}
+
+type grpcPayloadKey struct{}
+
+// authInterceptor is the gRPC counterpart of authMiddleware.
+// It reads the bearer token from the authorization metadata and stores its payload in the context.
+func authInterceptor(tokenMaker token.Maker, revocations *revocationList) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if grpcPublicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		var authorizationHeader string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(authorizationHeaderKey); len(values) > 0 {
+				authorizationHeader = values[0]
+			}
+		}
+
+		payload, err := verifyAuthorization(authorizationHeader, tokenMaker, revocations)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+
+		return handler(context.WithValue(ctx, grpcPayloadKey{}, payload), req)
+	}
+}
+
+// grpcAuthPayload returns the payload stored by authInterceptor
+func grpcAuthPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(grpcPayloadKey{}).(*token.Payload)
+}
+
+// grpcClientInfo returns the user agent and IP address of the caller of a gRPC request.
+// The x-forwarded-for metadata is only honored when the peer is a trusted proxy.
+func (s *Server) grpcClientInfo(ctx context.Context) (userAgent string, clientIP string) {
+	var forwardedFor []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(grpcUserAgentHeader); len(values) > 0 {
+			userAgent = values[0]
+		}
+		forwardedFor = md.Get(grpcForwardedForHeader)
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	clientIP = p.Addr.String()
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+
+	remoteIP, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return
+	}
+
+	clientIP = s.forwardedClientIP(remoteIP, strings.Join(forwardedFor, ",")).String()
+	return
+}
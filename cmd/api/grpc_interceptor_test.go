@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newGRPCAuthContext(
+	t *testing.T,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) context.Context {
+	accessToken, payload, err := tokenMaker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, accessToken)
+	return metadata.AppendToOutgoingContext(context.Background(), authorizationHeaderKey, authorizationHeader)
+}
+
+func requireGRPCCode(t *testing.T, err error, code codes.Code) {
+	if code == codes.OK {
+		require.NoError(t, err)
+		return
+	}
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code(), st.Message())
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	username := utils.RandomOwner()
+
+	testCases := []struct {
+		name       string
+		setupAuth  func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Owner: username, Limit: 5, Offset: 0})).
+					Times(1).
+					Return([]db.Account{}, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "UnsupportedAuthorization",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, "unsupported", username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "InvalidAuthorizationFormat",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, "", username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "ExpiredToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newGRPCAuthContext(t, tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			ctx := tc.setupAuth(t, server.tokenMaker)
+			_, err := client.ListAccounts(ctx, &pb.ListAccountsRequest{PageId: 1, PageSize: 5})
+			requireGRPCCode(t, err, tc.code)
+		})
+	}
+}
+
+func TestAuthInterceptorRevokedToken(t *testing.T) {
+	server := newTestServer(t, nil)
+	client := newTestGRPCClient(t, server)
+
+	accessToken, payload, err := server.tokenMaker.CreateToken("user", utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	server.revocations.Add(db.RevokedToken{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+
+	ctx := metadata.AppendToOutgoingContext(
+		context.Background(),
+		authorizationHeaderKey,
+		fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken),
+	)
+	_, err = client.GetAccount(ctx, &pb.GetAccountRequest{Id: 1})
+	requireGRPCCode(t, err, codes.Unauthenticated)
+}
+
+func TestGRPCClientInfo(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.trustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	newContext := func(peerIP string, forwardedFor string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(peerIP), Port: 1234},
+		})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(
+			grpcUserAgentHeader, "grpc-go",
+			grpcForwardedForHeader, forwardedFor,
+		))
+	}
+
+	userAgent, clientIP := server.grpcClientInfo(newContext("203.0.113.7", "198.51.100.1"))
+	require.Equal(t, "grpc-go", userAgent)
+	require.Equal(t, "203.0.113.7", clientIP)
+
+	_, clientIP = server.grpcClientInfo(newContext("10.0.0.1", "198.51.100.1"))
+	require.Equal(t, "198.51.100.1", clientIP)
+}
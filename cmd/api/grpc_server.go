@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/mrohadi/simplebank/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// grpcServer serves the SimpleBank gRPC API.
+// It embeds the HTTP server so both APIs share the same store, token maker and revocation list.
+type grpcServer struct {
+	pb.UnimplementedSimpleBankServer
+	*Server
+}
+
+// newGRPCServer creates a gRPC server with the auth and rate limit interceptors and registers the SimpleBank service on it
+func (s *Server) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		authInterceptor(s.tokenMaker, s.revocations),
+		s.rateLimitInterceptor(),
+	))
+	pb.RegisterSimpleBankServer(server, &grpcServer{Server: s})
+	reflection.Register(server)
+
+	return server
+}
+
+// StartGRPC runs the gRPC server on a specific address until ctx is canceled.
+// The background workers are run by Start, so both listeners are expected to be started together.
+func (s *Server) StartGRPC(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot create gRPC listener: %w", err)
+	}
+
+	return s.serveGRPC(ctx, listener)
+}
+
+func (s *Server) serveGRPC(ctx context.Context, listener net.Listener) error {
+	server := s.newGRPCServer()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("cannot serve gRPC: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.shuttingDown.Store(true)
+
+	// GracefulStop waits for the pending RPCs without a deadline, so fall back to Stop past the shutdown timeout
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(s.shutdownTimeout())
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+		server.Stop()
+		return fmt.Errorf("cannot drain gRPC connections: %w", context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+)
+
+// CreateTransfer is the gRPC counterpart of createTransfer
+func (s *grpcServer) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	params := transferRequest{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+		QuoteID:       req.GetQuoteId(),
+		TOTPCode:      req.GetTotpCode(),
+	}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	authPayload := grpcAuthPayload(ctx)
+	if apiErr := s.checkEmailVerified(ctx, authPayload.Username); apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+
+	fromAccount, err := s.grpcValidAccount(ctx, params.FromAccountID, params.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		return nil, grpcError(apierror.New(apierror.CodeAccountNotOwned, "from account doesn't belong to authenticated user"))
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: params.FromAccountID,
+		ToAccountID:   params.ToAccountID,
+		Amount:        params.Amount,
+	}
+
+	toCurrency := params.Currency
+	if params.QuoteID != "" {
+		quote, err := s.grpcValidRateQuote(ctx, params.QuoteID, authPayload.Username, params.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		toCurrency = quote.ToCurrency
+		arg.QuoteID = uuid.NullUUID{UUID: quote.ID, Valid: true}
+	}
+
+	if _, err := s.grpcValidAccount(ctx, params.ToAccountID, toCurrency); err != nil {
+		return nil, err
+	}
+
+	if apiErr := s.checkStepUp(ctx, authPayload.Username, params.Amount, params.TOTPCode); apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+
+	start := time.Now()
+	result, err := s.store.TransferTx(ctx, arg)
+	s.metrics.observeTransferTx(params.Currency, time.Since(start), result, err)
+	if err != nil {
+		return nil, grpcError(apierror.FromDB(err, apierror.Transfer))
+	}
+
+	return &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}, nil
+}
+
+// GetTransfer is the gRPC counterpart of getTransfer
+func (s *grpcServer) GetTransfer(ctx context.Context, req *pb.GetTransferRequest) (*pb.GetTransferResponse, error) {
+	params := getTransferRequest{ID: req.GetId()}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, params.ID)
+	if err != nil {
+		return nil, grpcError(apierror.FromDB(err, apierror.Transfer))
+	}
+
+	authPayload := grpcAuthPayload(ctx)
+	if !canReadAnyAccount(authPayload) {
+		owned, err := s.ownsAnyAccount(ctx, authPayload.Username, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			return nil, grpcError(apierror.Internal(err))
+		}
+		if !owned {
+			return nil, grpcError(apierror.New(apierror.CodeTransferNotOwned, "transfer doesn't belong to the authorized user"))
+		}
+	}
+
+	return &pb.GetTransferResponse{Transfer: convertTransfer(transfer)}, nil
+}
+
+// grpcValidAccount is the gRPC counterpart of validAccount
+func (s *grpcServer) grpcValidAccount(ctx context.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, grpcError(apierror.FromDB(err, apierror.Account))
+	}
+
+	if account.Currency != currency {
+		detail := fmt.Sprintf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		return account, grpcError(apierror.New(apierror.CodeCurrencyMismatch, detail))
+	}
+
+	return account, nil
+}
+
+// grpcValidRateQuote is the gRPC counterpart of validRateQuote
+func (s *grpcServer) grpcValidRateQuote(ctx context.Context, quoteID string, username string, fromCurrency string) (db.RateQuote, error) {
+	id, err := uuid.Parse(quoteID)
+	if err != nil {
+		return db.RateQuote{}, grpcError(apierror.Validation("quote_id", "uuid", "must be a valid UUID"))
+	}
+
+	quote, err := s.store.GetRateQuote(ctx, id)
+	if err != nil {
+		return quote, grpcError(apierror.FromDB(err, apierror.RateQuote))
+	}
+
+	if quote.Username != username {
+		return quote, grpcError(apierror.New(apierror.CodeRateQuoteNotOwned, "rate quote doesn't belong to authenticated user"))
+	}
+
+	if quote.FromCurrency != fromCurrency {
+		detail := fmt.Sprintf("rate quote currency mismatch: %s vs %s", quote.FromCurrency, fromCurrency)
+		return quote, grpcError(apierror.New(apierror.CodeRateQuoteMismatch, detail))
+	}
+
+	return quote, nil
+}
@@ -0,0 +1,369 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateTransferGRPC(t *testing.T) {
+	amount := int64(10)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	account1.Currency = utils.USD
+	account2.Currency = utils.USD
+
+	account3 := randomAccount(user2.Username)
+	account3.ID = account1.ID + 2
+	account3.Currency = utils.EUR
+
+	quote := db.RateQuote{
+		ID:           uuid.New(),
+		Username:     user1.Username,
+		FromCurrency: utils.USD,
+		ToCurrency:   utils.EUR,
+		Rate:         "0.9",
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}
+
+	testCases := []struct {
+		name       string
+		username   string
+		req        *pb.CreateTransferRequest
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        amount,
+				}
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferTxResult{
+						Transfer:    randomTransfer(account1, account2),
+						FromAccount: account1,
+					}, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:     "OKWithQuote",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account3.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+				QuoteId:       quote.ID.String(),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetRateQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(quote, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account3.ID,
+					Amount:        amount,
+					QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+				}
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: randomTransfer(account1, account3)}, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: user2.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.PermissionDenied,
+		},
+		{
+			name:     "FromAccountNotFound",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.NotFound,
+		},
+		{
+			name:     "ToAccountCurrencyMismatch",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account3.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name:     "NegativeAmount",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        -amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name:     "QuoteOfAnotherUser",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account3.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+				QuoteId:       quote.ID.String(),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				otherQuote := quote
+				otherQuote.Username = user2.Username
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetRateQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(otherQuote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.PermissionDenied,
+		},
+	}
+
+	// the ledger errors share their mapping with the HTTP handler
+	for _, ledgerCase := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{db.ErrInsufficientFunds, codes.FailedPrecondition},
+		{db.ErrAccountFrozen, codes.FailedPrecondition},
+		{db.ErrAccountClosed, codes.FailedPrecondition},
+		{db.ErrAccountNotFound, codes.NotFound},
+		{db.ErrCurrencyMismatch, codes.InvalidArgument},
+		{sql.ErrConnDone, codes.Internal},
+	} {
+		ledgerCase := ledgerCase
+		testCases = append(testCases, struct {
+			name       string
+			username   string
+			req        *pb.CreateTransferRequest
+			buildStubs func(store *mockdb.MockStore)
+			code       codes.Code
+		}{
+			name:     fmt.Sprintf("TransferTx/%s", ledgerCase.err),
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, ledgerCase.err)
+			},
+			code: ledgerCase.code,
+		})
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			allowVerifiedEmail(store)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, tc.username, utils.DepositorRole, time.Minute)
+			_, err := client.CreateTransfer(ctx, tc.req)
+			requireGRPCCode(t, err, tc.code)
+		})
+	}
+}
+
+func TestGetTransferGRPC(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	transfer := randomTransfer(account1, account2)
+
+	testCases := []struct {
+		name       string
+		username   string
+		role       string
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:     "Banker",
+			username: "banker",
+			role:     utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: codes.OK,
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: "unauthorized_user",
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			},
+			code: codes.PermissionDenied,
+		},
+		{
+			name:     "NotFound",
+			username: user1.Username,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, tc.username, tc.role, time.Minute)
+			rsp, err := client.GetTransfer(ctx, &pb.GetTransferRequest{Id: transfer.ID})
+			requireGRPCCode(t, err, tc.code)
+			if tc.code == codes.OK {
+				require.Equal(t, transfer.ID, rsp.GetTransfer().GetId())
+				require.Equal(t, transfer.Amount, rsp.GetTransfer().GetAmount())
+			}
+		})
+	}
+}
+
+func TestGetTransferGRPCReversal(t *testing.T) {
+	account1 := randomAccount(utils.RandomOwner())
+	account2 := randomAccount(utils.RandomOwner())
+	account2.ID = account1.ID + 1
+
+	original := randomTransfer(account1, account2)
+	original.ReversedAmount = original.Amount - 1
+
+	reversal := randomTransfer(account2, account1)
+	reversal.ID = original.ID + 1
+	reversal.Amount = original.ReversedAmount
+	reversal.ReversesTransferID = sql.NullInt64{Int64: original.ID, Valid: true}
+
+	for _, transfer := range []db.Transfer{original, reversal} {
+		t.Run(fmt.Sprint(transfer.ID), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+
+			server := newTestServer(t, store)
+			client := newTestGRPCClient(t, server)
+
+			ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, "banker", utils.BankerRole, time.Minute)
+			rsp, err := client.GetTransfer(ctx, &pb.GetTransferRequest{Id: transfer.ID})
+			require.NoError(t, err)
+
+			// the gRPC transfer reports the reversal the same way as the HTTP response
+			want := newTransferResponse(transfer)
+			got := rsp.GetTransfer()
+			require.Equal(t, want.ReversedAmount, got.GetReversedAmount())
+			require.Equal(t, want.ReversalStatus, got.GetReversalStatus())
+			require.Equal(t, want.ReversesTransferID, got.ReversesTransferId)
+		})
+	}
+}
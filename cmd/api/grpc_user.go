@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateUser is the gRPC counterpart of createUser
+func (s *grpcServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	params := createUserParams{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		FullName: req.GetFullName(),
+		Email:    req.GetEmail(),
+	}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(params.Password)
+	if err != nil {
+		return nil, grpcError(apierror.Internal(err))
+	}
+
+	result, err := s.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       params.Username,
+			HashedPassword: hashedPassword,
+			FullName:       params.FullName,
+			Email:          params.Email,
+		},
+		ExpiresAt: time.Now().Add(s.verifyEmailDuration()),
+	})
+	if err != nil {
+		return nil, grpcError(apierror.FromDB(err, apierror.User))
+	}
+
+	s.dispatcher.Notify()
+
+	return &pb.CreateUserResponse{User: convertUser(result.User)}, nil
+}
+
+// LoginUser is the gRPC counterpart of loginUser
+func (s *grpcServer) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	params := loginUserParams{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+	}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	user, apiErr := s.authenticateUser(ctx, params.Username, params.Password)
+	if apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+
+	if user.IsTotpEnabled {
+		mfaToken, challenge, err := s.newMFAChallenge(ctx, user.Username)
+		if err != nil {
+			return nil, grpcError(apierror.Internal(err))
+		}
+
+		return &pb.LoginUserResponse{
+			MfaToken:          mfaToken,
+			MfaTokenExpiresAt: timestamppb.New(challenge.ExpiresAt),
+		}, nil
+	}
+
+	return s.grpcLoginSession(ctx, user)
+}
+
+// LoginUserMFA is the gRPC counterpart of loginUserMFA
+func (s *grpcServer) LoginUserMFA(ctx context.Context, req *pb.LoginUserMFARequest) (*pb.LoginUserResponse, error) {
+	params := loginUserMFARequest{
+		MFAToken:     req.GetMfaToken(),
+		Code:         req.GetCode(),
+		RecoveryCode: req.GetRecoveryCode(),
+	}
+	if err := validateGRPCRequest(&params); err != nil {
+		return nil, err
+	}
+
+	user, apiErr := s.authenticateMFA(ctx, params)
+	if apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+
+	return s.grpcLoginSession(ctx, user)
+}
+
+// grpcLoginSession creates the session of a fully authenticated user
+func (s *grpcServer) grpcLoginSession(ctx context.Context, user db.User) (*pb.LoginUserResponse, error) {
+	userAgent, clientIP := s.grpcClientInfo(ctx)
+	login, err := s.createLoginSession(ctx, user, userAgent, clientIP)
+	if err != nil {
+		return nil, grpcError(apierror.Internal(err))
+	}
+
+	return &pb.LoginUserResponse{
+		SessionId:             login.session.ID.String(),
+		AccessToken:           login.accessToken,
+		AccessTokenExpiresAt:  timestamppb.New(login.accessPayload.ExpiredAt),
+		RefreshToken:          login.refreshToken,
+		RefreshTokenExpiresAt: timestamppb.New(login.refreshPayload.ExpiredAt),
+		User:                  convertUser(user),
+	}, nil
+}
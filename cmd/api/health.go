@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+const (
+	checkOK      = "ok"
+	checkFailing = "failing"
+)
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// healthz handle the liveness probe, it only reports the process is able to serve requests
+func (s *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": checkOK})
+}
+
+// readyz handle the readiness probe, it fails while a dependency is unavailable or the server is shutting down
+func (s *Server) readyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	rsp := readinessResponse{
+		Status: "ready",
+		Checks: map[string]string{
+			"shutdown":    checkOK,
+			"database":    checkOK,
+			"migrations":  checkOK,
+			"token_maker": checkOK,
+		},
+	}
+
+	if s.shuttingDown.Load() {
+		rsp.Checks["shutdown"] = "shutting down"
+	}
+
+	if err := s.store.Ping(checkCtx); err != nil {
+		rsp.Checks["database"] = err.Error()
+		rsp.Checks["migrations"] = checkFailing
+	} else if err := checkMigrationVersion(checkCtx, s.store); err != nil {
+		rsp.Checks["migrations"] = err.Error()
+	}
+
+	if s.tokenMaker == nil {
+		rsp.Checks["token_maker"] = "not configured"
+	}
+
+	for _, check := range rsp.Checks {
+		if check != checkOK {
+			rsp.Status = "not ready"
+			ctx.JSON(http.StatusServiceUnavailable, rsp)
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// checkMigrationVersion verifies the database schema is the one the queries are written against
+func checkMigrationVersion(ctx context.Context, store db.Store) error {
+	version, dirty, err := store.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+
+	if version != db.SchemaVersion {
+		return fmt.Errorf("migration version %d, expected %d", version, db.SchemaVersion)
+	}
+
+	return nil
+}
+
+// version handle returning the build information of the running binary
+func (s *Server) version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, utils.GetBuildInfo())
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthzAPI(t *testing.T) {
+	server := newTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestReadyzAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		shuttingDown  bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Ready",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireReadinessCheck(t, recorder, "database", checkOK)
+			},
+		},
+		{
+			name: "DatabaseUnavailable",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(errors.New("connection refused"))
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireReadinessCheck(t, recorder, "database", "connection refused")
+			},
+		},
+		{
+			name: "MigrationBehind",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion-1), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireReadinessCheck(t, recorder, "migrations",
+					fmt.Sprintf("migration version %d, expected %d", db.SchemaVersion-1, db.SchemaVersion))
+			},
+		},
+		{
+			name: "MigrationDirty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), true, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireReadinessCheck(t, recorder, "migrations", fmt.Sprintf("migration %d is dirty", db.SchemaVersion))
+			},
+		},
+		{
+			name:         "ShuttingDown",
+			shuttingDown: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireReadinessCheck(t, recorder, "shutdown", "shutting down")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.shuttingDown.Store(tc.shuttingDown)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReadyzFailsDuringShutdown(t *testing.T) {
+	server := newLifecycleTestServer(t, 5*time.Second)
+	server.config.ShutdownDelay = time.Second
+
+	store := server.store.(*mockdb.MockStore)
+	store.EXPECT().Ping(gomock.Any()).AnyTimes().Return(nil)
+	store.EXPECT().MigrationVersion(gomock.Any()).AnyTimes().Return(int64(db.SchemaVersion), false, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := fmt.Sprintf("http://%s/readyz", listener.Addr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(ctx, listener)
+	}()
+
+	rsp, err := http.Get(url)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	cancel()
+
+	// the server keeps serving during the shutdown delay, but reports it is not ready anymore
+	require.Eventually(t, func() bool {
+		rsp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		rsp.Body.Close()
+		return rsp.StatusCode == http.StatusServiceUnavailable
+	}, 500*time.Millisecond, 10*time.Millisecond)
+
+	require.NoError(t, <-served)
+}
+
+func TestVersionAPI(t *testing.T) {
+	gitCommit, buildTime := utils.GitCommit, utils.BuildTime
+	defer func() {
+		utils.GitCommit, utils.BuildTime = gitCommit, buildTime
+	}()
+	utils.GitCommit = "0b11ff7"
+	utils.BuildTime = "2024-01-02T03:04:05Z"
+
+	server := newTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/version", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp utils.BuildInfo
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, "0b11ff7", rsp.GitCommit)
+	require.Equal(t, "2024-01-02T03:04:05Z", rsp.BuildTime)
+	require.NotEmpty(t, rsp.GoVersion)
+}
+
+func requireReadinessCheck(t *testing.T, recorder *httptest.ResponseRecorder, check string, expected string) {
+	var rsp readinessResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, expected, rsp.Checks[check])
+}
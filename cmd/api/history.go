@@ -0,0 +1,211 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+)
+
+type listHistoryRequest struct {
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=in out"`
+	MinAmount *int64    `form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount *int64    `form:"max_amount" binding:"omitempty,min=0"`
+	Cursor    string    `form:"cursor"`
+	PageSize  int32     `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// historyFilter is the validated form of listHistoryRequest shared by the entries and transfers queries
+type historyFilter struct {
+	Direction       sql.NullString
+	CreatedFrom     sql.NullTime
+	CreatedTo       sql.NullTime
+	MinAmount       sql.NullInt64
+	MaxAmount       sql.NullInt64
+	CursorCreatedAt sql.NullTime
+	CursorID        sql.NullInt64
+	PageLimit       int32
+}
+
+type listEntriesResponse struct {
+	Entries    []db.Entry `json:"entries"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type listTransfersResponse struct {
+	Transfers  []db.Transfer `json:"transfers"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// listAccountEntries handle get the entries of an account, newest first
+func (s *Server) listAccountEntries(ctx *gin.Context) {
+	account, filter, ok := s.bindHistoryRequest(ctx)
+	if !ok {
+		return
+	}
+
+	entries, err := s.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+		AccountID:       account.ID,
+		Direction:       filter.Direction,
+		CreatedFrom:     filter.CreatedFrom,
+		CreatedTo:       filter.CreatedTo,
+		MinAmount:       filter.MinAmount,
+		MaxAmount:       filter.MaxAmount,
+		CursorCreatedAt: filter.CursorCreatedAt,
+		CursorID:        filter.CursorID,
+		PageLimit:       filter.PageLimit,
+	})
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	rsp := listEntriesResponse{Entries: entries}
+	if len(entries) == int(filter.PageLimit) {
+		rsp.Entries = entries[:len(entries)-1]
+		last := rsp.Entries[len(rsp.Entries)-1]
+		rsp.NextCursor = encodeHistoryCursor(last.CreatedAt, last.ID)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listAccountTransfers handle get the transfers sent or received by an account, newest first
+func (s *Server) listAccountTransfers(ctx *gin.Context) {
+	account, filter, ok := s.bindHistoryRequest(ctx)
+	if !ok {
+		return
+	}
+
+	transfers, err := s.store.ListAccountTransfers(ctx, db.ListAccountTransfersParams{
+		AccountID:       account.ID,
+		Direction:       filter.Direction,
+		CreatedFrom:     filter.CreatedFrom,
+		CreatedTo:       filter.CreatedTo,
+		MinAmount:       filter.MinAmount,
+		MaxAmount:       filter.MaxAmount,
+		CursorCreatedAt: filter.CursorCreatedAt,
+		CursorID:        filter.CursorID,
+		PageLimit:       filter.PageLimit,
+	})
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	rsp := listTransfersResponse{Transfers: transfers}
+	if len(transfers) == int(filter.PageLimit) {
+		rsp.Transfers = transfers[:len(transfers)-1]
+		last := rsp.Transfers[len(rsp.Transfers)-1]
+		rsp.NextCursor = encodeHistoryCursor(last.CreatedAt, last.ID)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// bindHistoryRequest validates the request and checks the account belongs to the authorized user.
+// It writes the error response itself and reports whether the request can be served.
+func (s *Server) bindHistoryRequest(ctx *gin.Context) (db.Account, historyFilter, bool) {
+	var filter historyFilter
+
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return db.Account{}, filter, false
+	}
+
+	var req listHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return db.Account{}, filter, false
+	}
+
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MaxAmount < *req.MinAmount {
+		writeError(ctx, apierror.Validation("max_amount", "gtefield", "must not be less than min_amount"))
+		return db.Account{}, filter, false
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && !req.To.After(req.From) {
+		writeError(ctx, apierror.Validation("to", "gtfield", "must be after from"))
+		return db.Account{}, filter, false
+	}
+
+	// fetch one extra row to know whether there is a next page
+	filter.PageLimit = req.PageSize + 1
+	filter.Direction = sql.NullString{String: req.Direction, Valid: req.Direction != ""}
+	filter.CreatedFrom = sql.NullTime{Time: req.From.UTC(), Valid: !req.From.IsZero()}
+	filter.CreatedTo = sql.NullTime{Time: req.To.UTC(), Valid: !req.To.IsZero()}
+	if req.MinAmount != nil {
+		filter.MinAmount = sql.NullInt64{Int64: *req.MinAmount, Valid: true}
+	}
+	if req.MaxAmount != nil {
+		filter.MaxAmount = sql.NullInt64{Int64: *req.MaxAmount, Valid: true}
+	}
+
+	if len(req.Cursor) > 0 {
+		createdAt, id, err := decodeHistoryCursor(req.Cursor)
+		if err != nil {
+			writeError(ctx, apierror.Validation("cursor", "cursor", "must be a cursor returned by a previous page"))
+			return db.Account{}, filter, false
+		}
+		filter.CursorCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+		filter.CursorID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	account, err := s.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
+		return account, filter, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canReadAccount(authPayload, account.Owner) {
+		writeError(ctx, apierror.New(apierror.CodeAccountNotOwned, "account doesn't belong to the authorized user"))
+		return account, filter, false
+	}
+
+	return account, filter, true
+}
+
+// encodeHistoryCursor builds an opaque cursor pointing at the last row of a page
+func encodeHistoryCursor(createdAt time.Time, id int64) string {
+	raw := fmt.Sprintf("%s|%d", createdAt.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeHistoryCursor(cursor string) (time.Time, int64, error) {
+	errInvalidCursor := errors.New("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, errInvalidCursor
+	}
+
+	createdAtText, idText, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, 0, errInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtText)
+	if err != nil {
+		return time.Time{}, 0, errInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errInvalidCursor
+	}
+
+	return createdAt, id, nil
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	pageSize := 5
+	entries := make([]db.Entry, pageSize+1)
+	createdAt := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	for i := range entries {
+		entries[i] = db.Entry{
+			ID:        int64(100 - i),
+			AccountID: account.ID,
+			Amount:    utils.RandomMoney(),
+			CreatedAt: createdAt.Add(-time.Duration(i) * time.Minute),
+			Kind:      db.EntryKindTransfer,
+		}
+	}
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	cursorCreatedAt := time.Date(2026, 1, 31, 13, 0, 0, 0, time.UTC)
+	cursor := encodeHistoryCursor(cursorCreatedAt, 200)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: url.Values{
+				"page_size":  {fmt.Sprint(pageSize)},
+				"direction":  {"in"},
+				"from":       {from.Format(time.RFC3339)},
+				"to":         {to.Format(time.RFC3339)},
+				"min_amount": {"1"},
+				"max_amount": {"1000"},
+				"cursor":     {cursor},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.ListAccountEntriesParams{
+					AccountID:       account.ID,
+					Direction:       sql.NullString{String: "in", Valid: true},
+					CreatedFrom:     sql.NullTime{Time: from, Valid: true},
+					CreatedTo:       sql.NullTime{Time: to, Valid: true},
+					MinAmount:       sql.NullInt64{Int64: 1, Valid: true},
+					MaxAmount:       sql.NullInt64{Int64: 1000, Valid: true},
+					CursorCreatedAt: sql.NullTime{Time: cursorCreatedAt, Valid: true},
+					CursorID:        sql.NullInt64{Int64: 200, Valid: true},
+					PageLimit:       int32(pageSize + 1),
+				}
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp listEntriesResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Entries, pageSize)
+				require.NotEmpty(t, rsp.NextCursor)
+
+				last := entries[pageSize-1]
+				nextCreatedAt, nextID, err := decodeHistoryCursor(rsp.NextCursor)
+				require.NoError(t, err)
+				require.True(t, last.CreatedAt.Equal(nextCreatedAt))
+				require.Equal(t, last.ID, nextID)
+			},
+		},
+		{
+			name:  "LastPage",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(entries[:2], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp listEntriesResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Entries, 2)
+				require.Empty(t, rsp.NextCursor)
+			},
+		},
+		{
+			name:  "UnauthorizedUser",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotOwned)
+			},
+		},
+		{
+			name:  "AccountNotFound",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotFound)
+			},
+		},
+		{
+			name:  "InvalidDirection",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}, "direction": {"sideways"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:  "InvalidAmountRange",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}, "min_amount": {"100"}, "max_amount": {"10"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:  "InvalidCursor",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}, "cursor": {"not-a-cursor"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: url.Values{"page_size": {"1000"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Entry{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	transfers := []db.Transfer{
+		{ID: 2, FromAccountID: account.ID, ToAccountID: account.ID + 1, Amount: 10, CreatedAt: time.Now()},
+		{ID: 1, FromAccountID: account.ID + 1, ToAccountID: account.ID, Amount: 20, CreatedAt: time.Now()},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+	arg := db.ListAccountTransfersParams{
+		AccountID: account.ID,
+		Direction: sql.NullString{String: "out", Valid: true},
+		PageLimit: 6,
+	}
+	store.EXPECT().
+		ListAccountTransfers(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(transfers, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/accounts/%d/transfers?page_size=5&direction=out", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp listTransfersResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp.Transfers, len(transfers))
+	require.Empty(t, rsp.NextCursor)
+}
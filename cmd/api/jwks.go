@@ -0,0 +1,23 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/token"
+)
+
+// jwksMaxAge lets the verifiers cache the public keys, a new key must be published this long before it signs tokens
+const jwksMaxAge = 300
+
+// jwks handle publishing the public keys verifying the tokens, it is empty when the tokens use a symmetric key
+func (s *Server) jwks(ctx *gin.Context) {
+	set := token.JWKS{Keys: []token.JWK{}}
+	if publisher, ok := s.tokenMaker.(token.KeyPublisher); ok {
+		set = publisher.JWKS()
+	}
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	ctx.JSON(http.StatusOK, set)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestJWKSAPI(t *testing.T) {
+	newKey, err := token.GenerateEd25519Key("new")
+	require.NoError(t, err)
+	oldKey, err := token.GenerateEd25519Key("old")
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	get := func() token.JWKS {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Contains(t, recorder.Header().Get("Cache-Control"), "max-age")
+
+		var set token.JWKS
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &set))
+		return set
+	}
+
+	// the symmetric key is never published
+	require.Empty(t, get().Keys)
+
+	server.tokenMaker, err = newTokenMaker(utils.Config{
+		TokenType:        token.TypePasetoPublic,
+		TokenSigningKeys: newKey.String() + "," + oldKey.String(),
+	})
+	require.NoError(t, err)
+
+	set := get()
+	require.Len(t, set.Keys, 2)
+	require.Equal(t, newKey.ID, set.Keys[0].KeyID)
+	require.Equal(t, oldKey.ID, set.Keys[1].KeyID)
+
+	// only the public half of the keys is published
+	for i, key := range []token.Ed25519Key{newKey, oldKey} {
+		publicKey, err := base64.RawURLEncoding.DecodeString(set.Keys[i].X)
+		require.NoError(t, err)
+		require.Equal(t, []byte(key.PrivateKey.Public().(ed25519.PublicKey)), publicKey)
+	}
+}
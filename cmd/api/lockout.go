@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+// authenticateUser checks the password of a user for the HTTP and gRPC logins.
+// The user is locked after too many failed logins, and the failures are forgotten on success.
+func (s *Server) authenticateUser(ctx context.Context, username string, password string) (db.User, *apierror.Error) {
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		return user, apierror.FromDB(err, apierror.User)
+	}
+
+	return s.checkPassword(ctx, user, password, apierror.New(apierror.CodeInvalidCredentials, "incorrect password"))
+}
+
+// checkPassword verifies the password of an authenticated or authenticating user.
+// A wrong password counts toward the lockout of the user and returns failure, the failures are forgotten on success
+// unless the user has two-factor authentication, whose failures are only forgotten once the code is verified.
+func (s *Server) checkPassword(ctx context.Context, user db.User, password string, failure *apierror.Error) (db.User, *apierror.Error) {
+	// a locked user is rejected before the password is checked, so it cannot be guessed during the lockout
+	now := time.Now()
+	if now.Before(user.LockedUntil) {
+		return user, userLockedError(user)
+	}
+
+	if err := utils.CheckPassword(password, user.HashedPassword); err != nil {
+		return s.recordFailedLogin(ctx, user, now, failure)
+	}
+
+	// the wrong codes share the counter, resetting it here would let the password holder guess codes forever
+	if user.IsTotpEnabled {
+		return user, nil
+	}
+
+	return s.resetFailedLogins(ctx, user)
+}
+
+// recordFailedLogin counts a wrong password or one-time code toward the lockout of the user.
+// It returns failure, or the lock error when the user is locked by this attempt.
+func (s *Server) recordFailedLogin(ctx context.Context, user db.User, now time.Time, failure *apierror.Error) (db.User, *apierror.Error) {
+	if s.config.LoginMaxAttempts <= 0 {
+		return user, failure
+	}
+
+	result, err := s.store.RecordFailedLoginTx(ctx, db.RecordFailedLoginTxParams{
+		Username:           user.Username,
+		MaxAttempts:        int32(s.config.LoginMaxAttempts),
+		AttemptWindow:      s.config.LoginAttemptWindow,
+		LockoutDuration:    s.config.LoginLockoutDuration,
+		MaxLockoutDuration: s.config.LoginMaxLockoutDuration,
+		Now:                now,
+	})
+	if err != nil {
+		return user, apierror.FromDB(err, apierror.User)
+	}
+
+	if result.Locked {
+		return result.User, userLockedError(result.User)
+	}
+
+	return result.User, failure
+}
+
+// resetFailedLogins forgets the failed logins of a user once it is authenticated.
+// The user was read before the password was checked, so the lock is checked again on the locked row:
+// a guess that succeeds after concurrent failures locked the user is rejected and the lock is kept.
+func (s *Server) resetFailedLogins(ctx context.Context, user db.User) (db.User, *apierror.Error) {
+	if s.config.LoginMaxAttempts <= 0 {
+		return user, nil
+	}
+
+	updated, err := s.store.ResetUnlockedUserLoginAttempts(ctx, db.ResetUnlockedUserLoginAttemptsParams{
+		Username: user.Username,
+		Now:      time.Now(),
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return user, apierror.FromDB(err, apierror.User)
+		}
+
+		locked, err := s.store.GetUser(ctx, user.Username)
+		if err != nil {
+			return user, apierror.FromDB(err, apierror.User)
+		}
+		return locked, userLockedError(locked)
+	}
+
+	return updated, nil
+}
+
+func userLockedError(user db.User) *apierror.Error {
+	return apierror.New(apierror.CodeUserLocked, fmt.Sprintf("user is locked until %s", user.LockedUntil.UTC().Format(time.RFC3339)))
+}
+
+type userLockoutUri struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type userLockoutResponse struct {
+	Username            string    `json:"username"`
+	Locked              bool      `json:"locked"`
+	LockedUntil         time.Time `json:"locked_until"`
+	FailedLoginAttempts int32     `json:"failed_login_attempts"`
+	LastFailedLoginAt   time.Time `json:"last_failed_login_at"`
+	Lockouts            int32     `json:"lockouts"`
+}
+
+func newUserLockoutResponse(user db.User) userLockoutResponse {
+	return userLockoutResponse{
+		Username:            user.Username,
+		Locked:              time.Now().Before(user.LockedUntil),
+		LockedUntil:         user.LockedUntil,
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		LastFailedLoginAt:   user.LastFailedLoginAt,
+		Lockouts:            user.Lockouts,
+	}
+}
+
+// getUserLockout handle reading the lock state of a user by an admin
+func (s *Server) getUserLockout(ctx *gin.Context) {
+	var uri userLockoutUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	user, err := s.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserLockoutResponse(user))
+}
+
+// unlockUser handle lifting the lock of a user by an admin, the failed logins and lockouts are forgotten
+func (s *Server) unlockUser(ctx *gin.Context) {
+	var uri userLockoutUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	user, err := s.store.ResetUserLoginAttempts(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserLockoutResponse(user))
+}
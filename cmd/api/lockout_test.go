@@ -0,0 +1,387 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+)
+
+func newTestLockoutServer(t *testing.T, store db.Store) *Server {
+	server := newTestServer(t, store)
+	server.config.LoginMaxAttempts = 3
+	server.config.LoginAttemptWindow = 15 * time.Minute
+	server.config.LoginLockoutDuration = 5 * time.Minute
+	server.config.LoginMaxLockoutDuration = time.Hour
+
+	return server
+}
+
+// expectResetLoginAttempts expects a successful check to forget the failed logins of the user
+func expectResetLoginAttempts(store *mockdb.MockStore, user db.User) {
+	store.EXPECT().
+		ResetUnlockedUserLoginAttempts(gomock.Any(), gomock.Cond(func(arg db.ResetUnlockedUserLoginAttemptsParams) bool {
+			return arg.Username == user.Username
+		})).
+		Times(1).
+		Return(user, nil)
+}
+
+func TestLoginUserLockout(t *testing.T) {
+	user, password := randomUser(t)
+
+	locked := user
+	locked.Lockouts = 1
+	locked.LockedUntil = time.Now().Add(5 * time.Minute)
+
+	expectSession := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CreateSession(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.CreateSessionParams) (db.Session, error) {
+				return db.Session{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+			})
+		expectSessionAccessToken(store)
+	}
+
+	expectFailedLogin := func(store *mockdb.MockStore, result db.RecordFailedLoginTxResult, err error) {
+		store.EXPECT().
+			RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, int32(3), arg.MaxAttempts)
+				require.Equal(t, 15*time.Minute, arg.AttemptWindow)
+				require.Equal(t, 5*time.Minute, arg.LockoutDuration)
+				require.Equal(t, time.Hour, arg.MaxLockoutDuration)
+				require.WithinDuration(t, time.Now(), arg.Now, time.Second)
+				return result, err
+			})
+	}
+
+	testCases := []struct {
+		name          string
+		password      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "WrongPassword",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				failed := user
+				failed.FailedLoginAttempts = 1
+				expectFailedLogin(store, db.RecordFailedLoginTxResult{User: failed}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInvalidCredentials)
+			},
+		},
+		{
+			name:     "WrongPasswordLocksUser",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectFailedLogin(store, db.RecordFailedLoginTxResult{User: locked, Locked: true}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeUserLocked)
+				require.Contains(t, problem.Detail, locked.LockedUntil.UTC().Format(time.RFC3339))
+			},
+		},
+		{
+			name:     "RecordFailedLoginError",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectFailedLogin(store, db.RecordFailedLoginTxResult{}, sql.ErrConnDone)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+		{
+			name:     "Locked",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(locked, nil)
+				store.EXPECT().RecordFailedLoginTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ResetUnlockedUserLoginAttempts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserLocked)
+			},
+		},
+		{
+			name:     "LockExpired",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := locked
+				expired.LockedUntil = time.Now().Add(-time.Second)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(expired, nil)
+				expectResetLoginAttempts(store, user)
+				expectSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "SuccessResetsFailedAttempts",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				failed := user
+				failed.FailedLoginAttempts = 2
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(failed, nil)
+				expectResetLoginAttempts(store, user)
+				expectSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "LockedConcurrently",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				// the user is locked by concurrent failures while the password is checked
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ResetUnlockedUserLoginAttempts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(locked, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserLocked)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestLockoutServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"username": user.Username, "password": tc.password})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserLockoutDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().RecordFailedLoginTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	body, err := json.Marshal(gin.H{"username": user.Username, "password": "wrong-password"})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	requireProblem(t, recorder, apierror.CodeInvalidCredentials)
+}
+
+func TestLoginUserGRPCLocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, password := randomUser(t)
+	user.LockedUntil = time.Now().Add(time.Minute)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestLockoutServer(t, store)
+	client := newTestGRPCClient(t, server)
+
+	_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{Username: user.Username, Password: password})
+	requireGRPCCode(t, err, codes.PermissionDenied)
+}
+
+func TestUserLockoutAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	user, _ := randomUser(t)
+	user.FailedLoginAttempts = 1
+	user.Lockouts = 2
+	user.LockedUntil = time.Now().Add(time.Minute).Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		method        string
+		action        string
+		username      string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Get",
+			method:   http.MethodGet,
+			action:   "lockout",
+			username: user.Username,
+			role:     utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userLockoutResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.Username)
+				require.True(t, rsp.Locked)
+				require.True(t, user.LockedUntil.Equal(rsp.LockedUntil))
+				require.Equal(t, int32(1), rsp.FailedLoginAttempts)
+				require.Equal(t, int32(2), rsp.Lockouts)
+			},
+		},
+		{
+			name:     "GetUserNotFound",
+			method:   http.MethodGet,
+			action:   "lockout",
+			username: user.Username,
+			role:     utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserNotFound)
+			},
+		},
+		{
+			name:     "Unlock",
+			method:   http.MethodPost,
+			action:   "unlock",
+			username: user.Username,
+			role:     utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				unlocked := user
+				unlocked.FailedLoginAttempts = 0
+				unlocked.Lockouts = 0
+				unlocked.LockedUntil = time.Time{}
+
+				store.EXPECT().ResetUserLoginAttempts(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(unlocked, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userLockoutResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.False(t, rsp.Locked)
+				require.Zero(t, rsp.FailedLoginAttempts)
+				require.Zero(t, rsp.Lockouts)
+			},
+		},
+		{
+			name:     "UnlockUserNotFound",
+			method:   http.MethodPost,
+			action:   "unlock",
+			username: user.Username,
+			role:     utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetUserLoginAttempts(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserNotFound)
+			},
+		},
+		{
+			name:     "UnlockForbidden",
+			method:   http.MethodPost,
+			action:   "unlock",
+			username: user.Username,
+			role:     utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetUserLoginAttempts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeForbidden)
+			},
+		},
+		{
+			name:     "InvalidUsername",
+			method:   http.MethodGet,
+			action:   "lockout",
+			username: "not-valid",
+			role:     utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/%s/%s", tc.username, tc.action)
+			request, err := http.NewRequest(tc.method, url, nil)
+			require.NoError(t, err)
+
+			addRoleAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
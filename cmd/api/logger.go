@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+const (
+	requestIDHeaderKey = "X-Request-ID"
+
+	// maxLoggedBodySize is the size above which a request body is not logged
+	maxLoggedBodySize = 4 << 10
+)
+
+// validRequestID restricts the request IDs propagated from the clients, so they can't inject anything in the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIDMiddleware propagates the X-Request-ID of the request, or assigns a new one.
+// The ID is sent back in the response and carried by the request context, down to the Store calls.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Request = ctx.Request.WithContext(utils.WithRequestID(ctx.Request.Context(), requestID))
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Next()
+	}
+}
+
+// loggerMiddleware logs every request once it has been served.
+// It must run after requestIDMiddleware so the record carries the request ID.
+func loggerMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		body := readLoggedBody(ctx)
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			attrs = append(attrs, slog.String("username", payload.(*token.Payload).Username))
+		}
+
+		if body != nil {
+			attrs = append(attrs, slog.Any("body", body))
+		}
+
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", ctx.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(ctx.Request.Context(), level, "request served", attrs...)
+	}
+}
+
+// readLoggedBody returns the redacted JSON body of the request, and restores the body for the handlers.
+// It returns nil when the body must not be logged.
+func readLoggedBody(ctx *gin.Context) json.RawMessage {
+	if ctx.Request.Body == nil || ctx.Request.ContentLength > maxLoggedBodySize {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxLoggedBodySize+1))
+	ctx.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), ctx.Request.Body))
+	if err != nil || len(body) == 0 || len(body) > maxLoggedBodySize {
+		return nil
+	}
+
+	redacted, ok := utils.RedactJSON(body)
+	if !ok {
+		return nil
+	}
+
+	return redacted
+}
+
+// recoveryMiddleware logs the panics of the handlers instead of printing them unstructured
+func recoveryMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, err any) {
+		logger.ErrorContext(ctx.Request.Context(), "request panicked", slog.Any("panic", err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, requestID string)
+	}{
+		{
+			name:      "Propagated",
+			requestID: "4bf92f3577b34da6-a3ce",
+			checkResponse: func(t *testing.T, requestID string) {
+				require.Equal(t, "4bf92f3577b34da6-a3ce", requestID)
+			},
+		},
+		{
+			name: "Assigned",
+			checkResponse: func(t *testing.T, requestID string) {
+				_, err := uuid.Parse(requestID)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:      "InvalidReplaced",
+			requestID: "id\nwith a forged log line",
+			checkResponse: func(t *testing.T, requestID string) {
+				_, err := uuid.Parse(requestID)
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(requestIDMiddleware())
+
+			var contextRequestID string
+			router.GET("/request_id", func(ctx *gin.Context) {
+				contextRequestID = utils.RequestIDFromContext(ctx.Request.Context())
+				ctx.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/request_id", nil)
+			require.NoError(t, err)
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeaderKey, tc.requestID)
+			}
+
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			requestID := recorder.Header().Get(requestIDHeaderKey)
+			require.Equal(t, requestID, contextRequestID)
+			tc.checkResponse(t, requestID)
+		})
+	}
+}
+
+func TestRequestIDReachesStore(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	requestID := "trace-42"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, _ int64) (db.Account, error) {
+			require.Equal(t, requestID, utils.RequestIDFromContext(ctx))
+			return account, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeaderKey, requestID)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	var logs bytes.Buffer
+	logger := utils.NewLogger(&logs, slog.LevelInfo)
+
+	router := gin.New()
+	router.Use(requestIDMiddleware(), loggerMiddleware(logger))
+
+	body := `{"username":"alice","password":"secret123","amount":10}`
+	router.POST(
+		"/logged/:id",
+		authMiddleware(server.tokenMaker, server.revocations),
+		func(ctx *gin.Context) {
+			// the handler still reads the body as it was sent
+			data, err := io.ReadAll(ctx.Request.Body)
+			require.NoError(t, err)
+			require.Equal(t, body, string(data))
+
+			ctx.Status(http.StatusCreated)
+		},
+	)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/logged/1", strings.NewReader(body))
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeaderKey, "req-7")
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "alice", time.Minute)
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	require.NotContains(t, logs.String(), "secret123")
+	require.NotContains(t, logs.String(), request.Header.Get(authorizationHeaderKey))
+
+	var record map[string]any
+	err = json.Unmarshal(logs.Bytes(), &record)
+	require.NoError(t, err)
+	require.Equal(t, "INFO", record["level"])
+	require.Equal(t, "req-7", record["request_id"])
+	require.Equal(t, http.MethodPost, record["method"])
+	require.Equal(t, "/logged/:id", record["route"])
+	require.Equal(t, float64(http.StatusCreated), record["status"])
+	require.Equal(t, "alice", record["username"])
+	require.Contains(t, record, "latency")
+	require.Equal(t, map[string]any{
+		"username": "alice",
+		"password": utils.RedactedValue,
+		"amount":   float64(10),
+	}, record["body"])
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mrohadi/simplebank/mail"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+// Mail senders
+const (
+	mailSenderLog  = "log"
+	mailSenderFile = "file"
+	mailSenderSMTP = "smtp"
+)
+
+// newMailSender creates the sender of the configured kind, the emails are logged by default
+func newMailSender(config utils.Config) (mail.Sender, error) {
+	switch config.MailSender {
+	case "", mailSenderLog:
+		return mail.NewLogSender(slog.Default()), nil
+	case mailSenderFile:
+		return mail.NewFileSender(config.MailDir, config.MailFrom)
+	case mailSenderSMTP:
+		return mail.NewSMTPSender(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.MailFrom), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail sender %q", config.MailSender)
+}
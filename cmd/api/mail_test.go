@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mrohadi/simplebank/mail"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSender keeps the emails sent by the server
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+	err      error
+}
+
+func (sender *recordingSender) Send(_ context.Context, msg mail.Message) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	sender.messages = append(sender.messages, msg)
+	return sender.err
+}
+
+func (sender *recordingSender) Messages() []mail.Message {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	return append([]mail.Message(nil), sender.messages...)
+}
+
+func TestNewMailSender(t *testing.T) {
+	sender, err := newMailSender(utils.Config{})
+	require.NoError(t, err)
+	require.IsType(t, &mail.LogSender{}, sender)
+
+	sender, err = newMailSender(utils.Config{MailSender: mailSenderFile, MailDir: filepath.Join(t.TempDir(), "mail")})
+	require.NoError(t, err)
+	require.IsType(t, &mail.FileSender{}, sender)
+
+	sender, err = newMailSender(utils.Config{MailSender: mailSenderSMTP, SMTPAddress: "localhost:25"})
+	require.NoError(t, err)
+	require.IsType(t, &mail.SMTPSender{}, sender)
+
+	_, err = newMailSender(utils.Config{MailSender: "pigeon"})
+	require.EqualError(t, err, `unknown mail sender "pigeon"`)
+}
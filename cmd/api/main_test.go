@@ -1,20 +1,28 @@
 package api
 
 import (
+	"context"
+	"net"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
 	"github.com/mrohadi/simplebank/utils"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := utils.Config{
-		TokenSymmectricKey:  utils.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmectricKey:   utils.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		RateQuoteDuration:    30 * time.Second,
 	}
 
 	server, err := NewServer(config, store)
@@ -23,6 +31,26 @@ func newTestServer(t *testing.T, store db.Store) *Server {
 	return server
 }
 
+// newTestGRPCClient serves the gRPC API of server on an in-memory listener and returns a client connected to it
+func newTestGRPCClient(t *testing.T, server *Server) pb.SimpleBankClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.newGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewSimpleBankClient(conn)
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
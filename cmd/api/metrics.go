@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "simplebank"
+
+// metrics holds the collectors exposed on /metrics.
+// Every server has its own registry so several servers can live in the same process.
+type metrics struct {
+	registry            *prometheus.Registry
+	httpRequestDuration *prometheus.HistogramVec
+	transfersCreated    *prometheus.CounterVec
+	transfersFailed     *prometheus.CounterVec
+	transferTxDuration  *prometheus.HistogramVec
+	transferTxLockWait  prometheus.Histogram
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the HTTP requests by route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		transfersCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "transfers_created_total",
+			Help:      "Number of transfers created by currency of the source account.",
+		}, []string{"currency"}),
+		transfersFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "transfers_failed_total",
+			Help:      "Number of transfers that failed by reason and currency of the source account.",
+		}, []string{"currency", "reason"}),
+		transferTxDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "transfer_tx_duration_seconds",
+			Help:      "Duration of the transfer transactions, including the wait for the account locks.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		transferTxLockWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "transfer_tx_lock_wait_seconds",
+			Help:      "Time spent by the successful transfer transactions waiting for the account locks.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequestDuration,
+		m.transfersCreated,
+		m.transfersFailed,
+		m.transferTxDuration,
+		m.transferTxLockWait,
+	)
+
+	return m
+}
+
+// handler serves the collected metrics in the Prometheus exposition format
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// middleware observes the duration of every request, labelled by route template to keep the cardinality bounded
+func (m *metrics) middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.httpRequestDuration.
+			WithLabelValues(ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// observeTransferTx records the outcome of a TransferTx call that took duration
+func (m *metrics) observeTransferTx(currency string, duration time.Duration, result db.TransferTxResult, err error) {
+	if err != nil {
+		m.transfersFailed.WithLabelValues(currency, transferFailureReason(err)).Inc()
+		m.transferTxDuration.WithLabelValues("failed").Observe(duration.Seconds())
+		return
+	}
+
+	m.transfersCreated.WithLabelValues(currency).Inc()
+	m.transferTxDuration.WithLabelValues("created").Observe(duration.Seconds())
+	m.transferTxLockWait.Observe(result.LockWait.Seconds())
+}
+
+// transferFailureReason maps the errors returned by TransferTx to a label value
+func transferFailureReason(err error) string {
+	switch {
+	case errors.Is(err, db.ErrInsufficientFunds):
+		return "insufficient_funds"
+	case errors.Is(err, db.ErrAccountNotFound):
+		return "account_not_found"
+	case errors.Is(err, db.ErrAccountFrozen):
+		return "account_frozen"
+	case errors.Is(err, db.ErrAccountClosed):
+		return "account_closed"
+	case errors.Is(err, db.ErrCurrencyMismatch):
+		return "currency_mismatch"
+	case errors.Is(err, db.ErrRateQuoteNotFound),
+		errors.Is(err, db.ErrRateQuoteExpired),
+		errors.Is(err, db.ErrRateQuoteUsed),
+		errors.Is(err, db.ErrRateQuoteMismatch),
+		errors.Is(err, db.ErrConvertedAmountTooSmall):
+		return "rate_quote"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "deadlock_detected":
+			return "deadlock"
+		case "serialization_failure":
+			return "serialization_failure"
+		}
+	}
+
+	return "internal"
+}
+
+// RegisterDBStats exposes the connection pool statistics of conn on /metrics
+func (s *Server) RegisterDBStats(conn *sql.DB) error {
+	return s.metrics.registry.Register(collectors.NewDBStatsCollector(conn, "simple_bank"))
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMetricsAPI(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	for _, url := range []string{"/healthz", "/healthz", "/unknown"} {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		server.router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	require.Contains(t, body, `simplebank_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"} 2`)
+	require.Contains(t, body, `simplebank_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	require.Contains(t, body, "go_goroutines")
+}
+
+func TestTransferMetrics(t *testing.T) {
+	user, _ := randomUser(t)
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(user.Username)
+	account2.ID = account1.ID + 1
+	account1.Currency = utils.USD
+	account2.Currency = utils.USD
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	allowVerifiedEmail(store)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(3).Return(account1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(3).Return(account2, nil)
+	gomock.InOrder(
+		store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{LockWait: time.Millisecond}, nil),
+		store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, db.ErrInsufficientFunds),
+		store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, &pq.Error{Code: "40P01"}),
+	)
+
+	server := newTestServer(t, store)
+	for i := 0; i < 3; i++ {
+		data, err := json.Marshal(gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          10,
+			"currency":        utils.USD,
+		})
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		server.router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	m := server.metrics
+	require.Equal(t, 1.0, testutil.ToFloat64(m.transfersCreated.WithLabelValues(utils.USD)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.transfersFailed.WithLabelValues(utils.USD, "insufficient_funds")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.transfersFailed.WithLabelValues(utils.USD, "deadlock")))
+	require.Equal(t, 2, testutil.CollectAndCount(m.transferTxDuration))
+	require.Equal(t, 1, testutil.CollectAndCount(m.transferTxLockWait))
+}
+
+func TestTransferFailureReason(t *testing.T) {
+	testCases := []struct {
+		err    error
+		reason string
+	}{
+		{fmt.Errorf("%w: account [1]", db.ErrInsufficientFunds), "insufficient_funds"},
+		{db.ErrAccountNotFound, "account_not_found"},
+		{db.ErrAccountFrozen, "account_frozen"},
+		{db.ErrAccountClosed, "account_closed"},
+		{db.ErrCurrencyMismatch, "currency_mismatch"},
+		{db.ErrRateQuoteExpired, "rate_quote"},
+		{&pq.Error{Code: "40P01"}, "deadlock"},
+		{&pq.Error{Code: "40001"}, "serialization_failure"},
+		{fmt.Errorf("tx err: %w", &pq.Error{Code: "40P01"}), "deadlock"},
+		{http.ErrHandlerTimeout, "internal"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.reason, transferFailureReason(tc.err), tc.err.Error())
+	}
+}
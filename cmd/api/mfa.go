@@ -0,0 +1,361 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/totp"
+)
+
+const (
+	defaultMFAChallengeDuration = 5 * time.Minute
+	mfaTokenSize                = 32
+	totpIssuer                  = "Simple Bank"
+	recoveryCodeCount           = 10
+	recoveryCodeSize            = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP handle generating the TOTP secret of the authorized user.
+// Two-factor authentication is only enabled once a first code is confirmed by confirmTOTP.
+func (s *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	if user.IsTotpEnabled {
+		writeError(ctx, apierror.New(apierror.CodeMFAAlreadyEnabled, "two-factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	_, err = s.store.UpdateUserTOTPSecret(ctx, db.UpdateUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	rsp := enrollTOTPResponse{
+		Secret:     secret,
+		OtpauthURI: totp.URI(totpIssuer, user.Username, secret),
+	}
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	User userResponse `json:"user"`
+	// RecoveryCodes are shown only once, each of them can replace a one-time code for a single login
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP handle enabling the two-factor authentication of the authorized user with a first code
+func (s *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	if user.IsTotpEnabled {
+		writeError(ctx, apierror.New(apierror.CodeMFAAlreadyEnabled, "two-factor authentication is already enabled"))
+		return
+	}
+
+	if len(user.TotpSecret) == 0 {
+		writeError(ctx, apierror.New(apierror.CodeMFANotEnabled, "two-factor authentication must be enrolled first"))
+		return
+	}
+
+	step, err := totp.Validate(user.TotpSecret, req.Code, time.Now(), user.TotpLastUsedStep)
+	if err != nil {
+		writeError(ctx, totpError(err))
+		return
+	}
+
+	recoveryCodes, codeHashes, err := newRecoveryCodes()
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	result, err := s.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               step,
+		RecoveryCodeHashes: codeHashes,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	rsp := confirmTOTPResponse{
+		User:          newUserResponse(result.User),
+		RecoveryCodes: recoveryCodes,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type mfaChallengeResponse struct {
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+type loginUserMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// loginUserMFA handle the second step of the login of a user with two-factor authentication.
+// The challenge token of loginUser is exchanged along with a one-time code or a recovery code for the session.
+func (s *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginUserMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	user, apiErr := s.authenticateMFA(ctx, req)
+	if apiErr != nil {
+		writeError(ctx, apiErr)
+		return
+	}
+
+	login, err := s.createLoginSession(ctx, user, ctx.Request.UserAgent(), ctx.ClientIP())
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newLoginUserResponse(login, user))
+}
+
+// authenticateMFA checks the second step of a login for the HTTP and gRPC APIs.
+// The wrong codes count toward the lockout like the wrong passwords, the challenge is used up on success.
+func (s *Server) authenticateMFA(ctx context.Context, req loginUserMFARequest) (db.User, *apierror.Error) {
+	challenge, err := s.store.GetMFAChallenge(ctx, hashSecret(req.MFAToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.User{}, apierror.New(apierror.CodeTokenInvalid, "mfa token is invalid")
+		}
+		return db.User{}, apierror.Internal(err)
+	}
+
+	now := time.Now()
+	if challenge.UsedAt.Valid {
+		return db.User{}, apierror.New(apierror.CodeTokenInvalid, "mfa token was already used")
+	}
+	if now.After(challenge.ExpiresAt) {
+		return db.User{}, apierror.New(apierror.CodeTokenExpired, "mfa token has expired")
+	}
+
+	user, err := s.store.GetUser(ctx, challenge.Username)
+	if err != nil {
+		return user, apierror.FromDB(err, apierror.User)
+	}
+
+	if now.Before(user.LockedUntil) {
+		return user, userLockedError(user)
+	}
+
+	if !user.IsTotpEnabled {
+		return user, apierror.New(apierror.CodeMFANotEnabled, "two-factor authentication is not enabled")
+	}
+
+	var apiErr *apierror.Error
+	if len(req.Code) > 0 {
+		apiErr = s.verifyTOTP(ctx, user, req.Code)
+	} else {
+		apiErr = s.useRecoveryCode(ctx, user, req.RecoveryCode)
+	}
+	if apiErr != nil {
+		if apiErr.Code != apierror.CodeMFACodeInvalid {
+			return user, apiErr
+		}
+		return s.recordFailedLogin(ctx, user, now, apiErr)
+	}
+
+	// the challenge can only be exchanged once, even by concurrent requests with valid codes
+	_, err = s.store.UseMFAChallenge(ctx, challenge.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, apierror.New(apierror.CodeTokenInvalid, "mfa token was already used")
+		}
+		return user, apierror.Internal(err)
+	}
+
+	return s.resetFailedLogins(ctx, user)
+}
+
+// checkStepUp requires a one-time code for the transfers above the configured amount
+func (s *Server) checkStepUp(ctx context.Context, username string, amount int64, code string) *apierror.Error {
+	if s.config.MFAStepUpAmount <= 0 || amount <= s.config.MFAStepUpAmount {
+		return nil
+	}
+
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		return apierror.FromDB(err, apierror.User)
+	}
+
+	now := time.Now()
+	if now.Before(user.LockedUntil) {
+		return userLockedError(user)
+	}
+
+	if !user.IsTotpEnabled {
+		detail := fmt.Sprintf("transfers above %d require two-factor authentication", s.config.MFAStepUpAmount)
+		return apierror.New(apierror.CodeMFANotEnabled, detail)
+	}
+
+	if len(code) == 0 {
+		detail := fmt.Sprintf("transfers above %d require a one-time code", s.config.MFAStepUpAmount)
+		return apierror.New(apierror.CodeMFARequired, detail)
+	}
+
+	if apiErr := s.verifyTOTP(ctx, user, code); apiErr != nil {
+		if apiErr.Code != apierror.CodeMFACodeInvalid {
+			return apiErr
+		}
+		_, apiErr = s.recordFailedLogin(ctx, user, now, apiErr)
+		return apiErr
+	}
+
+	return nil
+}
+
+// verifyTOTP checks a one-time code of a user, a code cannot be used twice
+func (s *Server) verifyTOTP(ctx context.Context, user db.User, code string) *apierror.Error {
+	step, err := totp.Validate(user.TotpSecret, code, time.Now(), user.TotpLastUsedStep)
+	if err != nil {
+		return totpError(err)
+	}
+
+	_, err = s.store.UseUserTOTPStep(ctx, db.UseUserTOTPStepParams{
+		Username:         user.Username,
+		TotpLastUsedStep: step,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apierror.New(apierror.CodeMFACodeInvalid, "one-time code was already used")
+		}
+		return apierror.Internal(err)
+	}
+
+	return nil
+}
+
+// useRecoveryCode checks a recovery code of a user and uses it up
+func (s *Server) useRecoveryCode(ctx context.Context, user db.User, recoveryCode string) *apierror.Error {
+	_, err := s.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: hashSecret(normalizeRecoveryCode(recoveryCode)),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apierror.New(apierror.CodeMFACodeInvalid, "recovery code is invalid or was already used")
+		}
+		return apierror.Internal(err)
+	}
+
+	return nil
+}
+
+func totpError(err error) *apierror.Error {
+	if errors.Is(err, totp.ErrInvalidCode) {
+		return apierror.New(apierror.CodeMFACodeInvalid, "one-time code is invalid")
+	}
+
+	return apierror.Internal(err)
+}
+
+// newMFAChallenge creates the challenge of the second login step, only the hash of the token is stored
+func (s *Server) newMFAChallenge(ctx context.Context, username string) (string, db.MfaChallenge, error) {
+	mfaToken, err := randomSecret(mfaTokenSize)
+	if err != nil {
+		return "", db.MfaChallenge{}, err
+	}
+
+	challenge, err := s.store.CreateMFAChallenge(ctx, db.CreateMFAChallengeParams{
+		Username:  username,
+		TokenHash: hashSecret(mfaToken),
+		ExpiresAt: time.Now().Add(s.mfaChallengeDuration()),
+	})
+	if err != nil {
+		return "", challenge, err
+	}
+
+	return mfaToken, challenge, nil
+}
+
+func (s *Server) mfaChallengeDuration() time.Duration {
+	if s.config.MFAChallengeDuration <= 0 {
+		return defaultMFAChallengeDuration
+	}
+
+	return s.config.MFAChallengeDuration
+}
+
+// newRecoveryCodes generates the recovery codes given to the user along with their hashes to store
+func newRecoveryCodes() (codes []string, codeHashes []string, err error) {
+	codes = make([]string, recoveryCodeCount)
+	codeHashes = make([]string, recoveryCodeCount)
+	for i := range codes {
+		data := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(data); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(data))
+		codes[i] = code[:4] + "-" + code[4:]
+		codeHashes[i] = hashSecret(code)
+	}
+
+	return codes, codeHashes, nil
+}
+
+// normalizeRecoveryCode lets the user type a recovery code without the dash or in upper case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToLower(code)
+}
@@ -0,0 +1,968 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/totp"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+)
+
+// randomTOTPUser returns a user with two-factor authentication enabled
+func randomTOTPUser(t *testing.T) (user db.User, password string) {
+	user, password = randomUser(t)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	user.TotpSecret = secret
+	user.IsTotpEnabled = true
+	user.IsEmailVerified = true
+	return
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	enabledUser, _ := randomTOTPUser(t)
+	enabledUser.Username = user.Username
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UpdateUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateUserTOTPSecretParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEmpty(t, arg.TotpSecret)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.Secret)
+				require.True(t, strings.HasPrefix(rsp.OtpauthURI, "otpauth://totp/"))
+				require.Contains(t, rsp.OtpauthURI, "secret="+rsp.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabledUser, nil)
+				store.EXPECT().UpdateUserTOTPSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFAAlreadyEnabled)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/totp", nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	enrolledUser, _ := randomTOTPUser(t)
+	enrolledUser.IsTotpEnabled = false
+
+	notEnrolledUser := enrolledUser
+	notEnrolledUser.TotpSecret = ""
+
+	testCases := []struct {
+		name          string
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: func(t *testing.T) string { return currentTOTPCode(t, enrolledUser.TotpSecret) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(enrolledUser.Username)).Times(1).Return(enrolledUser, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.EnableTOTPTxParams) (db.EnableTOTPTxResult, error) {
+						require.Equal(t, enrolledUser.Username, arg.Username)
+						require.Equal(t, totp.Step(time.Now()), arg.Step)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+
+						user := enrolledUser
+						user.IsTotpEnabled = true
+						return db.EnableTOTPTxResult{User: user}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.User.IsTotpEnabled)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: func(t *testing.T) string {
+				code, err := totp.Code(enrolledUser.TotpSecret, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(enrolledUser, nil)
+				store.EXPECT().EnableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFACodeInvalid)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: func(t *testing.T) string { return "123456" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(notEnrolledUser, nil)
+				store.EXPECT().EnableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFANotEnabled)
+			},
+		},
+		{
+			name: "InvalidFormat",
+			code: func(t *testing.T) string { return "12ab" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeValidationFailed)
+				require.Equal(t, "code", problem.Errors[0].Field)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code(t)})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, enrolledUser.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserReturnsMFAChallenge(t *testing.T) {
+	user, password := randomTOTPUser(t)
+	// the failed codes are only forgotten once the second factor is verified
+	user.FailedLoginAttempts = 2
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var tokenHash string
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().ResetUserLoginAttempts(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().
+		CreateMFAChallenge(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateMFAChallengeParams) (db.MfaChallenge, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.WithinDuration(t, time.Now().Add(defaultMFAChallengeDuration), arg.ExpiresAt, time.Second)
+			tokenHash = arg.TokenHash
+			return db.MfaChallenge{ID: 1, Username: arg.Username, TokenHash: arg.TokenHash, ExpiresAt: arg.ExpiresAt}, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+
+	var rsp mfaChallengeResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.NotEmpty(t, rsp.MFAToken)
+	require.Equal(t, tokenHash, hashSecret(rsp.MFAToken))
+	require.NotContains(t, recorder.Body.String(), "access_token")
+}
+
+func TestLoginUserMFAAPI(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+	mfaToken := "challenge-token"
+	challenge := db.MfaChallenge{
+		ID:        1,
+		Username:  user.Username,
+		TokenHash: hashSecret(mfaToken),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	usedChallenge := challenge
+	usedChallenge.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	expiredChallenge := challenge
+	expiredChallenge.ExpiresAt = time.Now().Add(-time.Minute)
+
+	// the challenge is only used up once the code is accepted
+	expectLogin := func(store *mockdb.MockStore) {
+		store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(usedChallenge, nil)
+		store.EXPECT().
+			CreateSession(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.CreateSessionParams) (db.Session, error) {
+				return db.Session{ID: arg.ID, Username: arg.Username}, nil
+			})
+		expectSessionAccessToken(store)
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, user.TotpSecret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.TokenHash)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Eq(db.UseUserTOTPStepParams{
+						Username:         user.Username,
+						TotpLastUsedStep: totp.Step(time.Now()),
+					})).
+					Times(1).
+					Return(user, nil)
+				expectLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "recovery_code": "ABCD-EFGH"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username: user.Username,
+						CodeHash: hashSecret("abcdefgh"),
+					})).
+					Times(1).
+					Return(db.RecoveryCode{}, nil)
+				expectLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "recovery_code": "abcd-efgh"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFACodeInvalid)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: func(t *testing.T) gin.H {
+				code, err := totp.Code(user.TotpSecret, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				return gin.H{"mfa_token": mfaToken, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFACodeInvalid)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, user.TotpSecret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFACodeInvalid)
+			},
+		},
+		{
+			name: "UnknownToken",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": "unknown", "code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(db.MfaChallenge{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeTokenInvalid)
+			},
+		},
+		{
+			name: "UsedToken",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(usedChallenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeTokenInvalid)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(expiredChallenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeTokenExpired)
+			},
+		},
+		{
+			name: "MissingCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserMFAResetsFailedLogins(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+	challenge := db.MfaChallenge{ID: 1, Username: user.Username, ExpiresAt: time.Now().Add(time.Minute)}
+
+	failedUser := user
+	failedUser.FailedLoginAttempts = 2
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectResetLoginAttempts(store, user)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+				expectSessionAccessToken(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "LockedConcurrently",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().
+					ResetUnlockedUserLoginAttempts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(lockedUser, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserLocked)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the failed codes are only forgotten once the code is verified
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(failedUser, nil)
+			store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(failedUser, nil)
+			tc.buildStubs(store)
+
+			server := newTestLockoutServer(t, store)
+
+			data, err := json.Marshal(gin.H{"mfa_token": "challenge-token", "code": currentTOTPCode(t, user.TotpSecret)})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserMFALocksUser(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+	challenge := db.MfaChallenge{ID: 1, Username: user.Username, ExpiresAt: time.Now().Add(time.Minute)}
+
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+	store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+	store.EXPECT().
+		RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.RecordFailedLoginTxResult{User: lockedUser, Locked: true}, nil)
+
+	server := newTestServer(t, store)
+	server.config.LoginMaxAttempts = 1
+
+	data, err := json.Marshal(gin.H{"mfa_token": "challenge-token", "recovery_code": "abcd-efgh"})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	requireProblem(t, recorder, apierror.CodeUserLocked)
+}
+
+func TestTransferStepUp(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+	notEnrolledUser := user
+	notEnrolledUser.IsTotpEnabled = false
+
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(user.Username)
+	account2.Currency = account1.Currency
+
+	const threshold = 1000
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "BelowThreshold",
+			amount: threshold,
+			code:   func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:   "OK",
+			amount: threshold + 1,
+			code:   func(t *testing.T) string { return currentTOTPCode(t, user.TotpSecret) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(user, nil)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingCode",
+			amount: threshold + 1,
+			code:   func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(user, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFARequired)
+			},
+		},
+		{
+			name:   "InvalidCode",
+			amount: threshold + 1,
+			code: func(t *testing.T) string {
+				code, err := totp.Code(user.TotpSecret, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(user, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFACodeInvalid)
+			},
+		},
+		{
+			name:   "NotEnrolled",
+			amount: threshold + 1,
+			code:   func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(notEnrolledUser, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFANotEnabled)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).AnyTimes().Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).AnyTimes().Return(account2, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.MFAStepUpAmount = threshold
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          tc.amount,
+				"currency":        account1.Currency,
+				"totp_code":       tc.code(t),
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStandingOrderStepUp(t *testing.T) {
+	user, _ := randomTOTPUser(t)
+
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(user.Username)
+	account2.Currency = account1.Currency
+
+	order := randomStandingOrder(user.Username)
+	order.Currency = account1.Currency
+	order.Amount = 500
+
+	const threshold = 1000
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "CreateMissingCode",
+			method: http.MethodPost,
+			url:    "/standing_orders",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          threshold + 1,
+				"currency":        account1.Currency,
+				"frequency":       db.StandingOrderOnce,
+				"start_at":        time.Now().Add(time.Minute),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(user, nil)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFARequired)
+			},
+		},
+		{
+			name:   "CreateOK",
+			method: http.MethodPost,
+			url:    "/standing_orders",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          threshold + 1,
+				"currency":        account1.Currency,
+				"frequency":       db.StandingOrderOnce,
+				"start_at":        time.Now().Add(time.Minute),
+				"totp_code":       currentTOTPCode(t, user.TotpSecret),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(user, nil)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(1).Return(order, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:   "RaiseAmountMissingCode",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/standing_orders/%d", order.ID),
+			body:   gin.H{"amount": threshold + 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UpdateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFARequired)
+			},
+		},
+		{
+			name:   "LowerAmount",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/standing_orders/%d", order.ID),
+			body:   gin.H{"amount": order.Amount - 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateStandingOrder(gomock.Any(), gomock.Any()).Times(1).Return(order, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).AnyTimes().Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).AnyTimes().Return(account2, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.MFAStepUpAmount = threshold
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestWithdrawalStepUp(t *testing.T) {
+	banker, _ := randomTOTPUser(t)
+	banker.Role = utils.BankerRole
+
+	account := randomAccount(utils.RandomOwner())
+	account.Currency = utils.USD
+
+	const threshold = 1000
+
+	testCases := []struct {
+		name          string
+		path          string
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "WithdrawalMissingCode",
+			path: "withdrawals",
+			code: func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(banker.Username)).Times(1).Return(banker, nil)
+				store.EXPECT().WithdrawalTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeMFARequired)
+			},
+		},
+		{
+			name: "WithdrawalOK",
+			path: "withdrawals",
+			code: func(t *testing.T) string { return currentTOTPCode(t, banker.TotpSecret) },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(banker.Username)).Times(1).Return(banker, nil)
+				store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(banker, nil)
+				store.EXPECT().WithdrawalTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "DepositWithoutCode",
+			path: "deposits",
+			code: func(t *testing.T) string { return "" },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).AnyTimes().Return(account, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.MFAStepUpAmount = threshold
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"amount":    threshold + 1,
+				"currency":  account.Currency,
+				"totp_code": tc.code(t),
+			})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/%s", account.ID, tc.path)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addRoleAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, banker.Username, utils.BankerRole, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGRPCLoginUserMFA(t *testing.T) {
+	user, password := randomTOTPUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var challenge db.MfaChallenge
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(2).Return(user, nil)
+	store.EXPECT().
+		CreateMFAChallenge(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateMFAChallengeParams) (db.MfaChallenge, error) {
+			challenge = db.MfaChallenge{ID: 1, Username: arg.Username, TokenHash: arg.TokenHash, ExpiresAt: arg.ExpiresAt}
+			return challenge, nil
+		})
+	store.EXPECT().
+		GetMFAChallenge(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, tokenHash string) (db.MfaChallenge, error) {
+			require.Equal(t, challenge.TokenHash, tokenHash)
+			return challenge, nil
+		})
+	store.EXPECT().UseUserTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+	store.EXPECT().UseMFAChallenge(gomock.Any(), gomock.Any()).Times(1).Return(challenge, nil)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateSessionParams) (db.Session, error) {
+			return db.Session{ID: arg.ID, Username: arg.Username}, nil
+		})
+	expectSessionAccessToken(store)
+
+	server := newTestServer(t, store)
+	client := newTestGRPCClient(t, server)
+
+	loginRsp, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{
+		Username: user.Username,
+		Password: password,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, loginRsp.GetMfaToken())
+	require.Empty(t, loginRsp.GetAccessToken())
+
+	_, err = client.LoginUserMFA(context.Background(), &pb.LoginUserMFARequest{MfaToken: loginRsp.GetMfaToken()})
+	requireGRPCCode(t, err, codes.InvalidArgument)
+
+	rsp, err := client.LoginUserMFA(context.Background(), &pb.LoginUserMFARequest{
+		MfaToken: loginRsp.GetMfaToken(),
+		Code:     currentTOTPCode(t, user.TotpSecret),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, rsp.GetAccessToken())
+	require.NotEmpty(t, rsp.GetRefreshToken())
+	_, err = uuid.Parse(rsp.GetSessionId())
+	require.NoError(t, err)
+	require.True(t, rsp.GetUser().GetIsTotpEnabled())
+}
@@ -1,12 +1,22 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
 	"github.com/mrohadi/simplebank/token"
 )
 
@@ -16,38 +26,235 @@ var (
 	authorizationPayloadKey = "authorization_payload"
 )
 
+var (
+	idempotencyKeyHeaderKey = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+const (
+	defaultIdempotencyKeyDuration      = 24 * time.Hour
+	defaultIdempotencyKeyPruneInterval = time.Minute
+)
+
 // authMiddleware
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, revocations *revocationList) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
-		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		payload, err := verifyAuthorization(ctx.GetHeader(authorizationHeaderKey), tokenMaker, revocations)
+		if err != nil {
+			writeError(ctx, err)
 			return
 		}
 
-		fields := strings.Fields(authorizationHeader)
-		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// verifyAuthorization checks a bearer authorization header and returns the payload of its access token.
+// It is shared by the HTTP middleware and the gRPC interceptor.
+func verifyAuthorization(authorizationHeader string, tokenMaker token.Maker, revocations *revocationList) (*token.Payload, *apierror.Error) {
+	if len(authorizationHeader) == 0 {
+		return nil, apierror.New(apierror.CodeUnauthenticated, "authorization header is not provided")
+	}
+
+	fields := strings.Fields(authorizationHeader)
+	if len(fields) < 2 {
+		return nil, apierror.New(apierror.CodeUnauthenticated, "invalid authorization header format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, apierror.New(apierror.CodeUnauthenticated, fmt.Sprintf("unsupported authorization type %s", authorizationType))
+	}
+
+	accessToken := fields[1]
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, apierror.FromToken(err)
+	}
+
+	if revocations.IsRevoked(payload.ID) {
+		return nil, apierror.New(apierror.CodeTokenRevoked, "token has been revoked")
+	}
+
+	return payload, nil
+}
+
+// idempotencyMiddleware replays the stored response when a request is retried with the same Idempotency-Key.
+// It must run after authMiddleware because keys are scoped to the authenticated user.
+// A key can be reused once it expires after duration.
+func idempotencyMiddleware(store db.Store, duration time.Duration) gin.HandlerFunc {
+	if duration <= 0 {
+		duration = defaultIdempotencyKeyDuration
+	}
+
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeaderKey)
+		if len(key) == 0 {
+			ctx.Next()
 			return
 		}
 
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		if len(key) > maxIdempotencyKeyLength {
+			detail := fmt.Sprintf("must be at most %d characters long", maxIdempotencyKeyLength)
+			writeError(ctx, apierror.Validation(idempotencyKeyHeaderKey, "max", detail))
 			return
 		}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		body, err := io.ReadAll(ctx.Request.Body)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, apierror.FromBinding(err))
 			return
 		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
 
-		ctx.Set(authorizationPayloadKey, payload)
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		// the actual path is hashed so a key can't replay the response of another resource of the same route
+		requestHash := hashRequest(ctx.Request.Method, ctx.Request.URL.Path, body)
+
+		_, err = store.CreateIdempotencyKey(ctx, db.CreateIdempotencyKeyParams{
+			Username:       authPayload.Username,
+			IdempotencyKey: key,
+			RequestHash:    requestHash,
+			ExpiresAt:      time.Now().Add(duration),
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				replayIdempotentResponse(ctx, store, authPayload.Username, key, requestHash)
+				return
+			}
+
+			writeError(ctx, apierror.Internal(err))
+			return
+		}
+
+		writer := &bodyCaptureWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+
+		// a panicking handler is answered with a server error by recoveryMiddleware, the key is released the same way
+		defer func() {
+			if err := recover(); err != nil {
+				releaseIdempotencyKey(ctx, store, authPayload.Username, key)
+				panic(err)
+			}
+		}()
 		ctx.Next()
+
+		status := writer.Status()
+		if !storeIdempotentResponse(status) {
+			releaseIdempotencyKey(ctx, store, authPayload.Username, key)
+			return
+		}
+
+		_, err = store.UpdateIdempotencyKeyResponse(ctx, db.UpdateIdempotencyKeyResponseParams{
+			Username:       authPayload.Username,
+			IdempotencyKey: key,
+			ResponseStatus: sql.NullInt32{Int32: int32(status), Valid: true},
+			ResponseBody:   writer.body.Bytes(),
+		})
+		if err != nil {
+			// a key left in progress would reject every retry, the client is rather allowed to retry it
+			slog.ErrorContext(ctx, "cannot store idempotent response", slog.String("idempotency_key", key), slog.Any("error", err))
+			releaseIdempotencyKey(ctx, store, authPayload.Username, key)
+		}
+	}
+}
+
+// storeIdempotentResponse reports whether a response is replayed to the retries of its key.
+// Server errors are not stored so the client can safely retry with the same key, neither are the rejections
+// of a request that did not reach the ledger: a retry with a one-time code or after a lockout or rate limit must run.
+func storeIdempotentResponse(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusLocked, http.StatusTooManyRequests:
+		return false
+	}
+
+	return status < http.StatusInternalServerError
+}
+
+// releaseIdempotencyKey deletes a key without a stored response so the request can be retried with it
+func releaseIdempotencyKey(ctx *gin.Context, store db.Store, username, key string) {
+	err := store.DeleteIdempotencyKey(ctx, db.DeleteIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot release idempotency key", slog.String("idempotency_key", key), slog.Any("error", err))
+	}
+}
+
+// pruneIdempotencyKeys drops the expired idempotency keys until the context is canceled
+func (s *Server) pruneIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(defaultIdempotencyKeyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.store.DeleteExpiredIdempotencyKeys(ctx); err != nil {
+			slog.ErrorContext(ctx, "cannot prune idempotency keys", slog.Any("error", err))
+		}
 	}
 }
+
+// replayIdempotentResponse writes the response stored for a previously used idempotency key
+func replayIdempotentResponse(ctx *gin.Context, store db.Store, username, key, requestHash string) {
+	record, err := store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	if record.RequestHash != requestHash {
+		writeError(ctx, apierror.New(apierror.CodeIdempotencyKeyReused, "idempotency key was already used with a different request"))
+		return
+	}
+
+	if !record.ResponseStatus.Valid {
+		writeError(ctx, apierror.New(apierror.CodeIdempotencyKeyInProgress, "a request with the same idempotency key is still in progress"))
+		return
+	}
+
+	// every error response is a problem details object
+	status := int(record.ResponseStatus.Int32)
+	contentType := gin.MIMEJSON
+	if status >= http.StatusBadRequest {
+		contentType = apierror.ContentType
+	}
+
+	ctx.Data(status, contentType, record.ResponseBody)
+	ctx.Abort()
+}
+
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte(" "))
+	h.Write([]byte(path))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyCaptureWriter keeps a copy of everything written to the response
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"bytes"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +10,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
 	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func addAuthorization(
@@ -20,8 +27,22 @@ func addAuthorization(
 	username string,
 	duration time.Duration,
 ) {
-	token, err := tokenMaker.CreateToken(username, duration)
+	addRoleAuthorization(t, request, tokenMaker, authorizationType, username, utils.DepositorRole, duration)
+}
+
+func addRoleAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) {
+	token, payload, err := tokenMaker.CreateToken(username, role, duration)
 	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	require.Equal(t, role, payload.Role)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
@@ -47,7 +68,7 @@ func TestAuthMiddleware(t *testing.T) {
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeUnauthenticated)
 			},
 		},
 		{
@@ -56,7 +77,7 @@ func TestAuthMiddleware(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, "unsupported", "user", time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeUnauthenticated)
 			},
 		},
 		{
@@ -65,7 +86,7 @@ func TestAuthMiddleware(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, "", "user", time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeUnauthenticated)
 			},
 		},
 		{
@@ -74,7 +95,7 @@ func TestAuthMiddleware(t *testing.T) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, apierror.CodeTokenExpired)
 			},
 		},
 	}
@@ -88,7 +109,7 @@ func TestAuthMiddleware(t *testing.T) {
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.revocations),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -104,3 +125,437 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewareRevokedToken(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	authPath := "/auth"
+	server.router.GET(
+		authPath,
+		authMiddleware(server.tokenMaker, server.revocations),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	accessToken, payload, err := server.tokenMaker.CreateToken("user", utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	server.revocations.Add(db.RevokedToken{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, authPath, nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+	server.router.ServeHTTP(recorder, request)
+	requireProblem(t, recorder, apierror.CodeTokenRevoked)
+}
+
+func TestIdempotencyMiddlewarePath(t *testing.T) {
+	username := "user"
+	key := "6f0c1b0e-retry-key"
+	body := []byte(`{"amount":10}`)
+	storedBody := []byte(`{"id":1}`)
+
+	testCases := []struct {
+		name          string
+		path          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "SameResource",
+			path: "/idempotent/1",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, storedBody, recorder.Body.Bytes())
+			},
+		},
+		{
+			name: "OtherResource",
+			path: "/idempotent/2",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeIdempotencyKeyReused)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.IdempotencyKey{}, sql.ErrNoRows)
+			store.EXPECT().
+				GetIdempotencyKey(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.IdempotencyKey{
+					Username:       username,
+					IdempotencyKey: key,
+					RequestHash:    hashRequest(http.MethodPost, "/idempotent/1", body),
+					ResponseStatus: sql.NullInt32{Int32: http.StatusCreated, Valid: true},
+					ResponseBody:   storedBody,
+				}, nil)
+
+			server := newTestServer(t, store)
+			server.router.POST(
+				"/idempotent/:id",
+				authMiddleware(server.tokenMaker, server.revocations),
+				idempotencyMiddleware(server.store, time.Minute),
+				func(ctx *gin.Context) {
+					require.Fail(t, "the handler must not run")
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, tc.path, bytes.NewReader(body))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeaderKey, key)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	username := "user"
+	key := "6f0c1b0e-retry-key"
+	path := "/idempotent"
+	body := []byte(`{"amount":10}`)
+	requestHash := hashRequest(http.MethodPost, path, body)
+	storedBody := []byte(`{"id":1}`)
+
+	testCases := []struct {
+		name          string
+		key           string
+		handlerStatus int
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int)
+	}{
+		{
+			name:          "NoKey",
+			key:           "",
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "FirstRequest",
+			key:           key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateIdempotencyKeyParams) (db.IdempotencyKey, error) {
+						require.Equal(t, username, arg.Username)
+						require.Equal(t, key, arg.IdempotencyKey)
+						require.Equal(t, requestHash, arg.RequestHash)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return db.IdempotencyKey{}, nil
+					})
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Eq(db.UpdateIdempotencyKeyResponseParams{
+						Username:       username,
+						IdempotencyKey: key,
+						ResponseStatus: sql.NullInt32{Int32: http.StatusCreated, Valid: true},
+						ResponseBody:   storedBody,
+					})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "Replay",
+			key:           key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{
+						Username:       username,
+						IdempotencyKey: key,
+					})).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Username:       username,
+						IdempotencyKey: key,
+						RequestHash:    requestHash,
+						ResponseStatus: sql.NullInt32{Int32: http.StatusCreated, Valid: true},
+						ResponseBody:   storedBody,
+					}, nil)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, storedBody, recorder.Body.Bytes())
+				require.Zero(t, handlerCalls)
+			},
+		},
+		{
+			name:          "DifferentRequest",
+			key:           key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Username:       username,
+						IdempotencyKey: key,
+						RequestHash:    "another hash",
+						ResponseStatus: sql.NullInt32{Int32: http.StatusCreated, Valid: true},
+						ResponseBody:   storedBody,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				requireProblem(t, recorder, apierror.CodeIdempotencyKeyReused)
+				require.Zero(t, handlerCalls)
+			},
+		},
+		{
+			name:          "InProgress",
+			key:           key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Username:       username,
+						IdempotencyKey: key,
+						RequestHash:    requestHash,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				requireProblem(t, recorder, apierror.CodeIdempotencyKeyInProgress)
+				require.Zero(t, handlerCalls)
+			},
+		},
+		{
+			name:          "HandlerServerError",
+			key:           key,
+			handlerStatus: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+						Username:       username,
+						IdempotencyKey: key,
+					})).
+					Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "HandlerClientError",
+			key:           key,
+			handlerStatus: http.StatusUnprocessableEntity,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Eq(db.UpdateIdempotencyKeyResponseParams{
+						Username:       username,
+						IdempotencyKey: key,
+						ResponseStatus: sql.NullInt32{Int32: http.StatusUnprocessableEntity, Valid: true},
+						ResponseBody:   storedBody,
+					})).
+					Times(1)
+				store.EXPECT().DeleteIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			// a transfer rejected for a missing one-time code is retried with the code
+			name:          "HandlerStepUpRejected",
+			key:           key,
+			handlerStatus: http.StatusForbidden,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+						Username:       username,
+						IdempotencyKey: key,
+					})).
+					Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "StoreResponseError",
+			key:           key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, nil)
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+						Username:       username,
+						IdempotencyKey: key,
+					})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "CreateKeyError",
+			key:           key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+				require.Zero(t, handlerCalls)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			handlerCalls := 0
+			server.router.POST(
+				path,
+				authMiddleware(server.tokenMaker, server.revocations),
+				idempotencyMiddleware(server.store, time.Minute),
+				func(ctx *gin.Context) {
+					handlerCalls++
+					ctx.Data(tc.handlerStatus, gin.MIMEJSON, storedBody)
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			require.NoError(t, err)
+			if len(tc.key) > 0 {
+				request.Header.Set(idempotencyKeyHeaderKey, tc.key)
+			}
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, handlerCalls)
+		})
+	}
+}
+
+func TestIdempotencyMiddlewarePanic(t *testing.T) {
+	username := "user"
+	key := "6f0c1b0e-retry-key"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, nil)
+	store.EXPECT().
+		DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{
+			Username:       username,
+			IdempotencyKey: key,
+		})).
+		Times(1)
+	store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	server.router.POST(
+		"/idempotent",
+		authMiddleware(server.tokenMaker, server.revocations),
+		idempotencyMiddleware(server.store, time.Minute),
+		func(ctx *gin.Context) {
+			panic("handler failed")
+		},
+	)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/idempotent", bytes.NewReader([]byte(`{"amount":10}`)))
+	require.NoError(t, err)
+	request.Header.Set(idempotencyKeyHeaderKey, key)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestStoreIdempotentResponse(t *testing.T) {
+	require.True(t, storeIdempotentResponse(http.StatusCreated))
+	require.True(t, storeIdempotentResponse(http.StatusBadRequest))
+	require.True(t, storeIdempotentResponse(http.StatusUnprocessableEntity))
+
+	require.False(t, storeIdempotentResponse(http.StatusUnauthorized))
+	require.False(t, storeIdempotentResponse(http.StatusForbidden))
+	require.False(t, storeIdempotentResponse(http.StatusLocked))
+	require.False(t, storeIdempotentResponse(http.StatusTooManyRequests))
+	require.False(t, storeIdempotentResponse(http.StatusInternalServerError))
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/mail"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+const (
+	defaultPasswordResetDuration = 15 * time.Minute
+	passwordResetTokenSize       = 32
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6,nefield=CurrentPassword"`
+	// RefreshToken is the session of the caller, it stays active while the other sessions are blocked
+	RefreshToken string `json:"refresh_token"`
+}
+
+type changePasswordResponse struct {
+	User            userResponse `json:"user"`
+	RevokedSessions int          `json:"revoked_sessions"`
+}
+
+// changePassword handle changing the password of the authorized user.
+// The other sessions of the user are blocked and their refresh tokens revoked.
+func (s *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var keepSessionID uuid.UUID
+	if len(req.RefreshToken) > 0 {
+		refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			writeError(ctx, apierror.FromToken(err))
+			return
+		}
+
+		if refreshPayload.Username != authPayload.Username {
+			writeError(ctx, apierror.New(apierror.CodeSessionNotOwned, "refresh token doesn't belong to the authorized user"))
+			return
+		}
+		keepSessionID = refreshPayload.ID
+	}
+
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	// a stolen access token cannot be used to guess the password past the lockout
+	user, apiErr := s.checkPassword(ctx, user, req.CurrentPassword, apierror.New(apierror.CodeInvalidCredentials, "incorrect current password"))
+	if apiErr != nil {
+		writeError(ctx, apiErr)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	result, err := s.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		KeepSessionID:  keepSessionID,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	s.revocations.Add(result.RevokedTokens...)
+
+	rsp := changePasswordResponse{
+		User:            newUserResponse(result.User),
+		RevokedSessions: len(result.Sessions),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type requestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// requestPasswordReset handle mailing a password reset token to the owner of an email address.
+// It is accepted whether or not a user has the address, so it cannot be used to find out the registered emails.
+func (s *Server) requestPasswordReset(ctx *gin.Context) {
+	var req requestPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.Status(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	resetToken, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	reset, err := s.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.passwordResetDuration()),
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.PasswordReset))
+		return
+	}
+
+	// the email is sent in the background, so the response takes as long as for an unknown address
+	if !s.dispatcher.Enqueue(passwordResetMessage(user, resetToken, reset.ExpiresAt)) {
+		slog.ErrorContext(ctx, "cannot queue password reset email", slog.String("username", user.Username))
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+type confirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// confirmPasswordReset handle setting a new password with a reset token.
+// The token is used up and every session of the user is blocked.
+func (s *Server) confirmPasswordReset(ctx *gin.Context) {
+	var req confirmPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	result, err := s.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      hashSecret(req.Token),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.PasswordReset))
+		return
+	}
+
+	s.revocations.Add(result.RevokedTokens...)
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+func (s *Server) passwordResetDuration() time.Duration {
+	if s.config.PasswordResetDuration <= 0 {
+		return defaultPasswordResetDuration
+	}
+
+	return s.config.PasswordResetDuration
+}
+
+// newPasswordResetToken generates a random reset token, only its hash is stored
+func newPasswordResetToken() (resetToken string, tokenHash string, err error) {
+	resetToken, err = randomSecret(passwordResetTokenSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	return resetToken, hashSecret(resetToken), nil
+}
+
+// randomSecret returns size bytes read from crypto/rand, encoded to be used in an URL
+func randomSecret(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashSecret returns the hex encoded SHA-256 of a secret sent to the user, only the hash is stored
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func passwordResetMessage(user db.User, resetToken string, expiresAt time.Time) mail.Message {
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Use the following token to choose a new password:\n\n%s\n\n"+
+			"It expires at %s. If you didn't ask for a password reset, you can ignore this email.\n",
+			user.FullName, resetToken, expiresAt.UTC().Format(time.RFC1123)),
+	}
+}
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/mail"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newPassword := utils.RandomString(8)
+
+	revoked := db.RevokedToken{ID: uuid.New(), Username: user.Username, ExpiresAt: time.Now().Add(time.Hour)}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, tokenMaker token.Maker) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"current_password": password, "new_password": newPassword}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ChangePasswordTxParams) (db.PasswordTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, uuid.Nil, arg.KeepSessionID)
+						require.NoError(t, utils.CheckPassword(newPassword, arg.HashedPassword))
+
+						return db.PasswordTxResult{
+							User:          user,
+							Sessions:      []db.Session{{ID: revoked.ID}},
+							RevokedTokens: []db.RevokedToken{revoked},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp changePasswordResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.User.Username)
+				require.Equal(t, 1, rsp.RevokedSessions)
+				require.True(t, server.revocations.IsRevoked(revoked.ID))
+			},
+		},
+		{
+			name: "KeepsCurrentSession",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, time.Hour)
+				require.NoError(t, err)
+				return gin.H{"current_password": password, "new_password": newPassword, "refresh_token": refreshToken}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ChangePasswordTxParams) (db.PasswordTxResult, error) {
+						require.NotEqual(t, uuid.Nil, arg.KeepSessionID)
+						return db.PasswordTxResult{User: user}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshTokenNotOwned",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken("other", user.Role, time.Hour)
+				require.NoError(t, err)
+				return gin.H{"current_password": password, "new_password": newPassword, "refresh_token": refreshToken}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeSessionNotOwned)
+			},
+		},
+		{
+			name: "IncorrectCurrentPassword",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"current_password": "wrong-password", "new_password": newPassword}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInvalidCredentials)
+			},
+		},
+		{
+			name: "SamePassword",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"current_password": password, "new_password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeValidationFailed)
+				require.Equal(t, "new_password", problem.Errors[0].Field)
+				require.Equal(t, "nefield", problem.Errors[0].Rule)
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"current_password": password, "new_password": "abc"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "InternalError",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"current_password": password, "new_password": newPassword}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body(t, server.tokenMaker))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/users/me/password", bytes.NewBuffer(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	user, password := randomUser(t)
+	lockedUser := user
+	lockedUser.LockedUntil = time.Now().Add(time.Minute)
+
+	testCases := []struct {
+		name          string
+		password      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "IncorrectPasswordCounted",
+			password: "wrong-password",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.RecordFailedLoginTxResult{User: lockedUser, Locked: true}, nil
+					})
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserLocked)
+			},
+		},
+		{
+			name:     "LockedUser",
+			password: password,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(lockedUser, nil)
+				store.EXPECT().RecordFailedLoginTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUserLocked)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.LoginMaxAttempts = 1
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"current_password": tc.password, "new_password": utils.RandomString(8)})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/users/me/password", bytes.NewBuffer(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRequestPasswordResetAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	var storedHash string
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		queueFull     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, sender *recordingSender)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.TokenHash, 64)
+						storedHash = arg.TokenHash
+						require.WithinDuration(t, time.Now().Add(defaultPasswordResetDuration), arg.ExpiresAt, time.Second)
+						return db.PasswordReset{ID: 1, Username: arg.Username, TokenHash: arg.TokenHash, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sender *recordingSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, recorder.Body.Bytes())
+
+				messages := sender.Messages()
+				require.Len(t, messages, 1)
+				require.Equal(t, user.Email, messages[0].To)
+
+				// the mailed token is the one whose hash was stored
+				lines := strings.Split(messages[0].Body, "\n")
+				require.Equal(t, storedHash, hashSecret(lines[4]))
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": "unknown@mail.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreatePasswordReset(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sender *recordingSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, sender.Messages())
+			},
+		},
+		{
+			name:      "QueueFull",
+			body:      gin.H{"email": user.Email},
+			queueFull: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreatePasswordReset(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordReset{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sender *recordingSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				for _, msg := range sender.Messages() {
+					require.NotEqual(t, user.Email, msg.To)
+				}
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "not-an-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sender *recordingSender) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sender *recordingSender) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			sender := &recordingSender{}
+			server.dispatcher = newMailDispatcher(store, sender, "", time.Minute)
+			for i := 0; tc.queueFull && i < mailQueueSize; i++ {
+				server.dispatcher.Enqueue(mail.Message{To: "other@mail.com"})
+			}
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password_reset", bytes.NewBuffer(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			deliverQueued(server.dispatcher)
+			tc.checkResponse(t, recorder, sender)
+		})
+	}
+}
+
+func TestConfirmPasswordResetAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	newPassword := utils.RandomString(8)
+
+	resetToken, tokenHash, err := newPasswordResetToken()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ResetPasswordTxParams) (db.PasswordTxResult, error) {
+						require.Equal(t, tokenHash, arg.TokenHash)
+						require.NoError(t, utils.CheckPassword(newPassword, arg.HashedPassword))
+						return db.PasswordTxResult{User: user}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.Username)
+			},
+		},
+		{
+			name: "NotFound",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodePasswordResetNotFound)
+			},
+		},
+		{
+			name: "Expired",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordTxResult{}, db.ErrPasswordResetExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodePasswordResetExpired)
+			},
+		},
+		{
+			name: "Used",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordTxResult{}, db.ErrPasswordResetUsed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodePasswordResetUsed)
+			},
+		},
+		{
+			name: "MissingToken",
+			body: gin.H{"new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password_reset/confirm", bytes.NewBuffer(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestNewPasswordResetToken(t *testing.T) {
+	token1, hash1, err := newPasswordResetToken()
+	require.NoError(t, err)
+	require.Len(t, token1, 43)
+	require.Equal(t, hashSecret(token1), hash1)
+
+	token2, hash2, err := newPasswordResetToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token1, token2)
+	require.NotEqual(t, hash1, hash2)
+}
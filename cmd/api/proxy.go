@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// parseTrustedProxies parses a comma separated list of the IP addresses and CIDR ranges of the reverse proxies in front of the server.
+// The forwarded client address is only read from a trusted proxy, an empty list trusts none.
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return proxies, nil
+}
+
+// isTrustedProxy reports whether the address belongs to one of the trusted proxies
+func (s *Server) isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, proxy := range s.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedClientIP returns the client address of a request received from remoteIP.
+// The X-Forwarded-For entries are walked from the right, each one only trusted when it was appended by a trusted proxy.
+func (s *Server) forwardedClientIP(remoteIP netip.Addr, forwardedFor string) netip.Addr {
+	clientIP := remoteIP
+	if len(forwardedFor) == 0 {
+		return clientIP
+	}
+
+	entries := strings.Split(forwardedFor, ",")
+	for i := len(entries) - 1; i >= 0 && s.isTrustedProxy(clientIP); i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(entries[i]))
+		if err != nil {
+			break
+		}
+		clientIP = addr
+	}
+
+	return clientIP
+}
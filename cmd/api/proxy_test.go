@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies("")
+	require.NoError(t, err)
+	require.Empty(t, proxies)
+
+	proxies, err = parseTrustedProxies("10.0.0.1, 192.168.1.7/16,::1")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("::1/128"),
+	}, proxies)
+
+	_, err = parseTrustedProxies("10.0.0.1,proxy.local")
+	require.Error(t, err)
+
+	_, err = parseTrustedProxies("10.0.0.0/33")
+	require.Error(t, err)
+}
+
+func TestForwardedClientIP(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.trustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	testCases := []struct {
+		name         string
+		remoteIP     string
+		forwardedFor string
+		clientIP     string
+	}{
+		{
+			name:     "NoHeader",
+			remoteIP: "10.0.0.1",
+			clientIP: "10.0.0.1",
+		},
+		{
+			name:         "UntrustedPeer",
+			remoteIP:     "203.0.113.7",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "TrustedPeer",
+			remoteIP:     "10.0.0.1",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "198.51.100.1",
+		},
+		{
+			name:         "SpoofedEntryBeforeProxy",
+			remoteIP:     "10.0.0.1",
+			forwardedFor: "192.0.2.99, 198.51.100.1, 10.0.0.2",
+			clientIP:     "198.51.100.1",
+		},
+		{
+			name:         "InvalidEntry",
+			remoteIP:     "10.0.0.1",
+			forwardedFor: "not-an-ip",
+			clientIP:     "10.0.0.1",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			clientIP := server.forwardedClientIP(netip.MustParseAddr(tc.remoteIP), tc.forwardedFor)
+			require.Equal(t, tc.clientIP, clientIP.String())
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/ratelimit"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	retryAfterHeaderKey           = "Retry-After"
+	defaultRateLimitPruneInterval = time.Minute
+)
+
+// Rate limit backends
+const (
+	rateLimitBackendMemory   = "memory"
+	rateLimitBackendPostgres = "postgres"
+)
+
+// Route groups sharing a rate limit.
+// The public routes are limited per IP address, the authenticated ones per user.
+const (
+	rateLimitPublic   = "public"
+	rateLimitTransfer = "transfer"
+	rateLimitAPI      = "api"
+)
+
+// grpcRateLimitGroups lists the limits of the gRPC methods, the other methods share the API limit
+var grpcRateLimitGroups = map[string][]string{
+	pb.SimpleBank_CreateUser_FullMethodName:     {rateLimitPublic},
+	pb.SimpleBank_LoginUser_FullMethodName:      {rateLimitPublic},
+	pb.SimpleBank_LoginUserMFA_FullMethodName:   {rateLimitPublic},
+	pb.SimpleBank_CreateTransfer_FullMethodName: {rateLimitAPI, rateLimitTransfer},
+}
+
+// newRateLimiter creates the limiter of the configured backend, the buckets are kept in memory by default
+func newRateLimiter(backend string, store db.Store) (ratelimit.Limiter, error) {
+	switch backend {
+	case "", rateLimitBackendMemory:
+		return ratelimit.NewMemoryLimiter(), nil
+	case rateLimitBackendPostgres:
+		return ratelimit.NewPostgresLimiter(store), nil
+	}
+
+	return nil, fmt.Errorf("unknown rate limit backend %q", backend)
+}
+
+// newRateLimits reads the limits of the route groups from the config, a zero limit is disabled
+func newRateLimits(config utils.Config) map[string]ratelimit.Limit {
+	return map[string]ratelimit.Limit{
+		rateLimitPublic:   {Requests: config.RateLimitPublicRequests, Period: config.RateLimitPublicPeriod},
+		rateLimitTransfer: {Requests: config.RateLimitTransferRequests, Period: config.RateLimitTransferPeriod},
+		rateLimitAPI:      {Requests: config.RateLimitAPIRequests, Period: config.RateLimitAPIPeriod},
+	}
+}
+
+// rateLimitKey is the bucket of a client in a route group
+func rateLimitKey(group string, payload *token.Payload, clientIP string) string {
+	if payload != nil {
+		return group + ":user:" + payload.Username
+	}
+
+	return group + ":ip:" + clientIP
+}
+
+// allowRequest takes a token from the bucket of the client in the route group.
+// It returns how long to wait along with the error when the request is denied.
+// The request is let through when the limiter fails, the rate limit must not take the API down with its backend.
+func (s *Server) allowRequest(ctx context.Context, group string, key string) (time.Duration, *apierror.Error) {
+	result, err := s.limiter.Allow(ctx, key, s.rateLimits[group])
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot apply rate limit", slog.String("group", group), slog.Any("error", err))
+		return 0, nil
+	}
+
+	if result.Allowed {
+		return 0, nil
+	}
+
+	return result.RetryAfter, apierror.New(apierror.CodeRateLimited, fmt.Sprintf("too many requests, retry in %ss", retryAfterSeconds(result.RetryAfter)))
+}
+
+// retryAfterSeconds rounds the wait up to the whole seconds of the Retry-After header
+func retryAfterSeconds(wait time.Duration) string {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.FormatInt(seconds, 10)
+}
+
+// rateLimitMiddleware limits the requests of a route group.
+// It must run after authMiddleware on the authenticated routes so the requests are counted per user.
+func (s *Server) rateLimitMiddleware(group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload *token.Payload
+		if value, ok := ctx.Get(authorizationPayloadKey); ok {
+			payload = value.(*token.Payload)
+		}
+
+		wait, err := s.allowRequest(ctx, group, rateLimitKey(group, payload, ctx.ClientIP()))
+		if err != nil {
+			ctx.Header(retryAfterHeaderKey, retryAfterSeconds(wait))
+			writeError(ctx, err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// rateLimitInterceptor is the gRPC counterpart of rateLimitMiddleware, it runs after authInterceptor
+func (s *Server) rateLimitInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		groups, ok := grpcRateLimitGroups[info.FullMethod]
+		if !ok {
+			groups = []string{rateLimitAPI}
+		}
+
+		var payload *token.Payload
+		if !grpcPublicMethods[info.FullMethod] {
+			payload = grpcAuthPayload(ctx)
+		}
+		_, clientIP := s.grpcClientInfo(ctx)
+
+		for _, group := range groups {
+			wait, err := s.allowRequest(ctx, group, rateLimitKey(group, payload, clientIP))
+			if err != nil {
+				grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeaderKey, retryAfterSeconds(wait)))
+				return nil, grpcError(err)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// pruneRateLimits drops the full buckets of the limiter until the context is canceled
+func (s *Server) pruneRateLimits(ctx context.Context) {
+	ticker := time.NewTicker(defaultRateLimitPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.limiter.Prune(ctx); err != nil {
+			slog.ErrorContext(ctx, "cannot prune rate limit buckets", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/ratelimit"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// failingLimiter is a limiter whose backend is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func (failingLimiter) Prune(context.Context) error {
+	return nil
+}
+
+func TestRateLimitMiddlewarePublic(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.rateLimits[rateLimitPublic] = ratelimit.Limit{Requests: 2, Period: time.Minute}
+
+	login := func(clientIP string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		require.NoError(t, err)
+		request.RemoteAddr = clientIP + ":1234"
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		recorder := login("10.0.0.1")
+		requireProblem(t, recorder, apierror.CodeValidationFailed)
+		require.Empty(t, recorder.Header().Get(retryAfterHeaderKey))
+	}
+
+	recorder := login("10.0.0.1")
+	requireProblem(t, recorder, apierror.CodeRateLimited)
+	require.Equal(t, "30", recorder.Header().Get(retryAfterHeaderKey))
+
+	// the other clients have their own bucket
+	recorder = login("10.0.0.2")
+	requireProblem(t, recorder, apierror.CodeValidationFailed)
+}
+
+func TestRateLimitMiddlewareForwardedFor(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.rateLimits[rateLimitPublic] = ratelimit.Limit{Requests: 1, Period: time.Minute}
+
+	login := func(forwardedFor string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		require.NoError(t, err)
+		request.RemoteAddr = "203.0.113.7:1234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// a client that is not a trusted proxy cannot pick its own bucket
+	requireProblem(t, login("10.0.0.1"), apierror.CodeValidationFailed)
+	requireProblem(t, login("10.0.0.2"), apierror.CodeRateLimited)
+}
+
+func TestRateLimitMiddlewarePerUser(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.rateLimits[rateLimitAPI] = ratelimit.Limit{Requests: 1, Period: time.Minute}
+
+	getAccount := func(username string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/accounts/0", nil)
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	user1 := utils.RandomOwner()
+	user2 := user1 + "x"
+
+	requireProblem(t, getAccount(user1), apierror.CodeValidationFailed)
+
+	recorder := getAccount(user1)
+	requireProblem(t, recorder, apierror.CodeRateLimited)
+	require.Equal(t, "60", recorder.Header().Get(retryAfterHeaderKey))
+
+	// the users share the IP address but not their bucket
+	requireProblem(t, getAccount(user2), apierror.CodeValidationFailed)
+
+	// the unauthenticated requests are not counted
+	recorder = httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/accounts/0", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	requireProblem(t, recorder, apierror.CodeUnauthenticated)
+}
+
+func TestRateLimitMiddlewareTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	allowVerifiedEmail(store)
+
+	server := newTestServer(t, store)
+	server.rateLimits[rateLimitTransfer] = ratelimit.Limit{Requests: 1, Period: time.Minute}
+	username := utils.RandomOwner()
+
+	send := func(method string, url string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(method, url, strings.NewReader("{}"))
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	requireProblem(t, send(http.MethodPost, "/transfers"), apierror.CodeValidationFailed)
+	requireProblem(t, send(http.MethodPost, "/transfers/1/reversal"), apierror.CodeRateLimited)
+
+	// the other routes are under the API limit only
+	requireProblem(t, send(http.MethodGet, "/accounts/0"), apierror.CodeValidationFailed)
+}
+
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.limiter = failingLimiter{}
+	server.rateLimits[rateLimitPublic] = ratelimit.Limit{Requests: 1, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		requireProblem(t, recorder, apierror.CodeValidationFailed)
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	limiter, err := newRateLimiter("", nil)
+	require.NoError(t, err)
+	require.IsType(t, &ratelimit.MemoryLimiter{}, limiter)
+
+	limiter, err = newRateLimiter(rateLimitBackendPostgres, nil)
+	require.NoError(t, err)
+	require.IsType(t, &ratelimit.PostgresLimiter{}, limiter)
+
+	_, err = newRateLimiter("redis", nil)
+	require.EqualError(t, err, `unknown rate limit backend "redis"`)
+
+	_, err = NewServer(utils.Config{TokenSymmectricKey: utils.RandomString(32), RateLimitBackend: "redis"}, nil)
+	require.Error(t, err)
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	require.Equal(t, "1", retryAfterSeconds(0))
+	require.Equal(t, "1", retryAfterSeconds(time.Millisecond))
+	require.Equal(t, "2", retryAfterSeconds(1500*time.Millisecond))
+	require.Equal(t, "60", retryAfterSeconds(time.Minute))
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := utils.RandomOwner()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Owner: username, Limit: 5, Offset: 0})).
+		Times(1).
+		Return([]db.Account{}, nil)
+
+	server := newTestServer(t, store)
+	server.rateLimits[rateLimitAPI] = ratelimit.Limit{Requests: 1, Period: time.Minute}
+	client := newTestGRPCClient(t, server)
+
+	ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+	_, err := client.ListAccounts(ctx, &pb.ListAccountsRequest{PageId: 1, PageSize: 5})
+	requireGRPCCode(t, err, codes.OK)
+
+	var header metadata.MD
+	_, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageId: 1, PageSize: 5}, grpc.Header(&header))
+	requireGRPCCode(t, err, codes.ResourceExhausted)
+	require.Equal(t, []string{"60"}, header.Get(retryAfterHeaderKey))
+}
+
+func TestRateLimitInterceptorPublic(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.rateLimits[rateLimitPublic] = ratelimit.Limit{Requests: 1, Period: time.Minute}
+	client := newTestGRPCClient(t, server)
+
+	_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{})
+	requireGRPCCode(t, err, codes.InvalidArgument)
+
+	_, err = client.CreateUser(context.Background(), &pb.CreateUserRequest{})
+	requireGRPCCode(t, err, codes.ResourceExhausted)
+}
+
+func TestRateLimitKey(t *testing.T) {
+	require.Equal(t, "api:ip:10.0.0.1", rateLimitKey(rateLimitAPI, nil, "10.0.0.1"))
+
+	server := newTestServer(t, nil)
+	_, payload, err := server.tokenMaker.CreateToken("alice", utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%s:user:alice", rateLimitTransfer), rateLimitKey(rateLimitTransfer, payload, "10.0.0.1"))
+}
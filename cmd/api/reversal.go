@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+// Reversal statuses of a transfer
+const (
+	reversalStatusNone      = "none"
+	reversalStatusPartial   = "partially_reversed"
+	reversalStatusCompleted = "reversed"
+)
+
+type transferResponse struct {
+	ID                 int64      `json:"id"`
+	FromAccountID      int64      `json:"from_account_id"`
+	ToAccountID        int64      `json:"to_account_id"`
+	Amount             int64      `json:"amount"`
+	ConvertedAmount    *int64     `json:"converted_amount,omitempty"`
+	ExchangeRate       string     `json:"exchange_rate,omitempty"`
+	SpreadBps          *int32     `json:"spread_bps,omitempty"`
+	QuoteID            *uuid.UUID `json:"quote_id,omitempty"`
+	ReversesTransferID *int64     `json:"reverses_transfer_id,omitempty"`
+	ReversedAmount     int64      `json:"reversed_amount"`
+	ReversalStatus     string     `json:"reversal_status"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+func newTransferResponse(transfer db.Transfer) transferResponse {
+	rsp := transferResponse{
+		ID:             transfer.ID,
+		FromAccountID:  transfer.FromAccountID,
+		ToAccountID:    transfer.ToAccountID,
+		Amount:         transfer.Amount,
+		ExchangeRate:   transfer.ExchangeRate.String,
+		ReversedAmount: transfer.ReversedAmount,
+		ReversalStatus: reversalStatusNone,
+		CreatedAt:      transfer.CreatedAt,
+	}
+
+	received := transfer.Amount
+	if transfer.ConvertedAmount.Valid {
+		rsp.ConvertedAmount = &transfer.ConvertedAmount.Int64
+		received = transfer.ConvertedAmount.Int64
+	}
+	if transfer.SpreadBps.Valid {
+		rsp.SpreadBps = &transfer.SpreadBps.Int32
+	}
+	if transfer.QuoteID.Valid {
+		rsp.QuoteID = &transfer.QuoteID.UUID
+	}
+	if transfer.ReversesTransferID.Valid {
+		rsp.ReversesTransferID = &transfer.ReversesTransferID.Int64
+	}
+
+	switch {
+	case transfer.ReversedAmount >= received:
+		rsp.ReversalStatus = reversalStatusCompleted
+	case transfer.ReversedAmount > 0:
+		rsp.ReversalStatus = reversalStatusPartial
+	}
+
+	return rsp
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getTransfer handle get a transfer by id, for the owner of either of its accounts
+func (s *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	transfer, valid := s.validTransfer(ctx, req.ID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canReadAnyAccount(authPayload) {
+		owned, err := s.ownsAnyAccount(ctx, authPayload.Username, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			writeError(ctx, apierror.Internal(err))
+			return
+		}
+		if !owned {
+			writeError(ctx, apierror.New(apierror.CodeTransferNotOwned, "transfer doesn't belong to the authorized user"))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newTransferResponse(transfer))
+}
+
+type createReversalRequest struct {
+	// Amount in the destination currency, the whole remaining amount is reversed when omitted
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+type reversalResponse struct {
+	Reversal    transferResponse `json:"reversal"`
+	Original    transferResponse `json:"original"`
+	FromAccount db.Account       `json:"from_account"`
+	FromEntry   db.Entry         `json:"from_entry"`
+	ToEntry     db.Entry         `json:"to_entry"`
+}
+
+// createReversal handle a refund of a transfer initiated by its recipient or by an admin
+func (s *Server) createReversal(ctx *gin.Context) {
+	var uri getTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	// the body is optional, an empty one reverses the whole transfer
+	var req createReversalRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	transfer, valid := s.validTransfer(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	// admins can reverse any transfer, everyone else only the ones they received
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !hasRole(authPayload, utils.AdminRole) {
+		toAccount, err := s.store.GetAccount(ctx, transfer.ToAccountID)
+		if err != nil {
+			writeError(ctx, apierror.Internal(err))
+			return
+		}
+
+		if toAccount.Owner != authPayload.Username {
+			writeError(ctx, apierror.New(apierror.CodeNotTransferRecipient, "only the recipient can reverse a transfer"))
+			return
+		}
+	}
+
+	result, err := s.store.ReversalTx(ctx, db.ReversalTxParams{
+		TransferID: transfer.ID,
+		Amount:     req.Amount,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Transfer))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reversalResponse{
+		Reversal:    newTransferResponse(result.Transfer),
+		Original:    newTransferResponse(result.Original),
+		FromAccount: result.FromAccount,
+		FromEntry:   result.FromEntry,
+		ToEntry:     result.ToEntry,
+	})
+}
+
+func (s *Server) validTransfer(ctx *gin.Context, transferID int64) (db.Transfer, bool) {
+	transfer, err := s.store.GetTransfer(ctx, transferID)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Transfer))
+		return transfer, false
+	}
+
+	return transfer, true
+}
+
+// ownsAnyAccount reports whether the user owns at least one of the given accounts
+func (s *Server) ownsAnyAccount(ctx context.Context, username string, accountIDs ...int64) (bool, error) {
+	for _, id := range accountIDs {
+		account, err := s.store.GetAccount(ctx, id)
+		if err != nil {
+			return false, err
+		}
+
+		if account.Owner == username {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
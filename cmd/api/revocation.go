@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+)
+
+const defaultRevocationSyncInterval = time.Minute
+
+// revocationList keeps the revoked token IDs in memory so authMiddleware does not query the database
+// on every request. It is periodically synced with the revoked_tokens table so revocations made by
+// other instances are picked up, and expired entries are pruned.
+type revocationList struct {
+	store    db.Store
+	interval time.Duration
+
+	mu         sync.RWMutex
+	revoked    map[uuid.UUID]time.Time
+	lastSynced time.Time
+}
+
+func newRevocationList(store db.Store, interval time.Duration) *revocationList {
+	if interval <= 0 {
+		interval = defaultRevocationSyncInterval
+	}
+
+	return &revocationList{
+		store:    store,
+		interval: interval,
+		revoked:  make(map[uuid.UUID]time.Time),
+	}
+}
+
+// IsRevoked reports whether the token with the given payload ID has been revoked
+func (l *revocationList) IsRevoked(id uuid.UUID) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expiresAt, ok := l.revoked[id]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// Add puts revoked tokens into the cache
+func (l *revocationList) Add(tokens ...db.RevokedToken) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, token := range tokens {
+		l.revoked[token.ID] = token.ExpiresAt
+	}
+}
+
+// Sync loads the revocations created since the last sync
+func (l *revocationList) Sync(ctx context.Context) error {
+	// look back one interval so rows committed late by other instances are not missed
+	l.mu.RLock()
+	revokedAfter := l.lastSynced.Add(-l.interval)
+	l.mu.RUnlock()
+
+	tokens, err := l.store.ListRevokedTokens(ctx, revokedAfter)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, token := range tokens {
+		l.revoked[token.ID] = token.ExpiresAt
+		if token.RevokedAt.After(l.lastSynced) {
+			l.lastSynced = token.RevokedAt
+		}
+	}
+
+	return nil
+}
+
+// Prune removes expired revocations from the cache and the database, along with the expired session access tokens
+func (l *revocationList) Prune(ctx context.Context) error {
+	now := time.Now()
+
+	l.mu.Lock()
+	for id, expiresAt := range l.revoked {
+		if !now.Before(expiresAt) {
+			delete(l.revoked, id)
+		}
+	}
+	l.mu.Unlock()
+
+	if err := l.store.DeleteExpiredRevokedTokens(ctx); err != nil {
+		return err
+	}
+
+	// expired access tokens do not need to be revoked with their session anymore
+	return l.store.DeleteExpiredSessionAccessTokens(ctx)
+}
+
+// Run syncs and prunes the revocation list until the context is canceled
+func (l *revocationList) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.Sync(ctx); err != nil {
+			slog.ErrorContext(ctx, "cannot sync revoked tokens", slog.Any("error", err))
+		}
+
+		if err := l.Prune(ctx); err != nil {
+			slog.ErrorContext(ctx, "cannot prune revoked tokens", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRevocationListSync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	active := db.RevokedToken{
+		ID:        uuid.New(),
+		Username:  "user",
+		ExpiresAt: time.Now().Add(time.Minute),
+		RevokedAt: time.Now(),
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.RevokedToken{active}, nil)
+
+	revocations := newRevocationList(store, time.Minute)
+	require.False(t, revocations.IsRevoked(active.ID))
+
+	err := revocations.Sync(context.Background())
+	require.NoError(t, err)
+	require.True(t, revocations.IsRevoked(active.ID))
+	require.False(t, revocations.IsRevoked(uuid.New()))
+}
+
+func TestRevocationListSyncError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+
+	revocations := newRevocationList(store, time.Minute)
+	err := revocations.Sync(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestRevocationListPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		DeleteExpiredSessionAccessTokens(gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	active := db.RevokedToken{ID: uuid.New(), ExpiresAt: time.Now().Add(time.Minute)}
+	expired := db.RevokedToken{ID: uuid.New(), ExpiresAt: time.Now().Add(-time.Minute)}
+
+	revocations := newRevocationList(store, time.Minute)
+	revocations.Add(active, expired)
+	require.True(t, revocations.IsRevoked(active.ID))
+	require.False(t, revocations.IsRevoked(expired.ID))
+
+	err := revocations.Prune(context.Background())
+	require.NoError(t, err)
+	require.Len(t, revocations.revoked, 1)
+	require.Contains(t, revocations.revoked, active.ID)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "github.com/mrohadi/simplebank/db/sqlc"
+)
+
+const (
+	defaultSchedulerInterval = time.Minute
+
+	// standingOrderBatchSize is the number of standing orders claimed in a single transaction
+	standingOrderBatchSize = 10
+
+	// standingOrderLease postpones claimed orders, so an order whose run was interrupted is only retried after the lease
+	standingOrderLease = 10 * time.Minute
+
+	// standingOrderRetryBackoff is the delay before retrying a failed run, doubled after each insufficient funds failure
+	standingOrderRetryBackoff = time.Hour
+
+	// maxStandingOrderFailures is the number of consecutive insufficient funds failures after which an order is suspended
+	maxStandingOrderFailures = 3
+)
+
+// standingOrderScheduler executes the standing orders that are due.
+// Every instance of the server runs one, claimed orders are locked so an order is never executed twice.
+type standingOrderScheduler struct {
+	store    db.Store
+	interval time.Duration
+	metrics  *metrics
+}
+
+func newStandingOrderScheduler(store db.Store, interval time.Duration, metrics *metrics) *standingOrderScheduler {
+	if interval <= 0 {
+		interval = defaultSchedulerInterval
+	}
+
+	return &standingOrderScheduler{
+		store:    store,
+		interval: interval,
+		metrics:  metrics,
+	}
+}
+
+// RunDue executes all the standing orders that are due and returns how many runs were executed
+func (s *standingOrderScheduler) RunDue(ctx context.Context) (int, error) {
+	executed := 0
+	for {
+		claimed, err := s.store.ClaimStandingOrdersTx(ctx, db.ClaimStandingOrdersTxParams{
+			Now:       time.Now(),
+			BatchSize: standingOrderBatchSize,
+			Lease:     standingOrderLease,
+		})
+		if err != nil {
+			return executed, err
+		}
+
+		for _, c := range claimed {
+			if err := s.execute(ctx, c); err != nil {
+				return executed, err
+			}
+			executed++
+		}
+
+		if len(claimed) < standingOrderBatchSize {
+			return executed, nil
+		}
+	}
+}
+
+// execute runs the transfer of a claimed standing order and records the outcome.
+// A successful transfer is recorded in the same transaction, so an order is never paid twice for one run.
+func (s *standingOrderScheduler) execute(ctx context.Context, claimed db.ClaimedStandingOrder) error {
+	order := claimed.Order
+	now := time.Now()
+
+	arg := db.FinishStandingOrderRunTxParams{
+		RunID:   claimed.Run.ID,
+		OrderID: order.ID,
+		Status:  db.StandingOrderActive,
+	}
+
+	after := claimed.Run.ScheduledAt
+	if now.After(after) {
+		after = now
+	}
+	nextRunAt, ok := nextStandingOrderRun(order, after)
+	arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: ok}
+	if !ok {
+		arg.Status = db.StandingOrderCompleted
+	}
+
+	result, err := s.store.RunStandingOrderTx(ctx, db.RunStandingOrderTxParams{
+		Transfer: db.TransferTxParams{
+			FromAccountID: order.FromAccountID,
+			ToAccountID:   order.ToAccountID,
+			Amount:        order.Amount,
+		},
+		Finish: arg,
+	})
+	s.metrics.observeTransferTx(order.Currency, time.Since(now), result.Transfer, err)
+	if err == nil || errors.Is(err, db.ErrStandingOrderRunFinished) {
+		// a run finished by another scheduler whose lease expired is left as it recorded it
+		return nil
+	}
+
+	// the transfer was rolled back, the failure is recorded on its own
+	arg = db.FinishStandingOrderRunTxParams{
+		RunID:        claimed.Run.ID,
+		OrderID:      order.ID,
+		Status:       db.StandingOrderActive,
+		Error:        err.Error(),
+		FailureCount: order.FailureCount,
+	}
+
+	switch {
+	case errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrAccountFrozen):
+		arg.FailureCount++
+		if arg.FailureCount >= maxStandingOrderFailures {
+			arg.Status = db.StandingOrderSuspended
+		} else {
+			backoff := standingOrderRetryBackoff << (arg.FailureCount - 1)
+			arg.NextRunAt = sql.NullTime{Time: now.Add(backoff), Valid: true}
+		}
+	case errors.Is(err, db.ErrAccountNotFound), errors.Is(err, db.ErrAccountClosed), errors.Is(err, db.ErrCurrencyMismatch):
+		// retrying cannot fix these, the owner has to look at the order
+		arg.Status = db.StandingOrderSuspended
+	default:
+		arg.NextRunAt = sql.NullTime{Time: now.Add(standingOrderRetryBackoff), Valid: true}
+	}
+
+	_, err = s.store.FinishStandingOrderRunTx(ctx, arg)
+	if errors.Is(err, db.ErrStandingOrderRunFinished) {
+		return nil
+	}
+	return err
+}
+
+// Run executes the due standing orders every interval until the context is canceled
+func (s *standingOrderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil {
+			slog.ErrorContext(ctx, "cannot run standing orders", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStandingOrderSchedulerRunDue(t *testing.T) {
+	order := randomStandingOrder("owner")
+	run := db.StandingOrderRun{
+		ID:              1,
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+		Status:          db.StandingOrderRunPending,
+	}
+
+	failingOrder := order
+	failingOrder.FailureCount = maxStandingOrderFailures - 1
+
+	testCases := []struct {
+		name       string
+		order      db.StandingOrder
+		buildStubs func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder)
+	}{
+		{
+			name:  "Succeeded",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				transferArg := db.TransferTxParams{
+					FromAccountID: order.FromAccountID,
+					ToAccountID:   order.ToAccountID,
+					Amount:        order.Amount,
+				}
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RunStandingOrderTxParams) (db.RunStandingOrderTxResult, error) {
+						require.Equal(t, transferArg, arg.Transfer)
+						require.Equal(t, run.ID, arg.Finish.RunID)
+						require.Empty(t, arg.Finish.Error)
+						require.Equal(t, db.StandingOrderActive, arg.Finish.Status)
+						require.Zero(t, arg.Finish.FailureCount)
+
+						// the next run stays on the daily schedule of the order
+						require.True(t, arg.Finish.NextRunAt.Valid)
+						require.True(t, arg.Finish.NextRunAt.Time.After(time.Now()))
+						require.Zero(t, arg.Finish.NextRunAt.Time.Sub(order.StartAt)%(24*time.Hour))
+						return db.RunStandingOrderTxResult{Transfer: db.TransferTxResult{Transfer: db.Transfer{ID: 7}}}, nil
+					})
+
+				// the run is recorded by the transaction of the transfer
+				store.EXPECT().FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:  "InsufficientFundsBacksOff",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrInsufficientFunds)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.NotEmpty(t, arg.Error)
+						require.False(t, arg.TransferID.Valid)
+						require.Equal(t, db.StandingOrderActive, arg.Status)
+						require.Equal(t, int32(1), arg.FailureCount)
+						require.WithinDuration(t, time.Now().Add(standingOrderRetryBackoff), arg.NextRunAt.Time, time.Second)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+		{
+			name:  "InsufficientFundsSuspends",
+			order: failingOrder,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrInsufficientFunds)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.Equal(t, db.StandingOrderSuspended, arg.Status)
+						require.Equal(t, int32(maxStandingOrderFailures), arg.FailureCount)
+						require.False(t, arg.NextRunAt.Valid)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+		{
+			name:  "AccountNotFoundSuspends",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrAccountNotFound)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.Equal(t, db.StandingOrderSuspended, arg.Status)
+						require.Zero(t, arg.FailureCount)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+		{
+			name:  "FrozenAccountBacksOff",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrAccountFrozen)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.Equal(t, db.StandingOrderActive, arg.Status)
+						require.Equal(t, int32(1), arg.FailureCount)
+						require.True(t, arg.NextRunAt.Valid)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+		{
+			name:  "ClosedAccountSuspends",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrAccountClosed)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.Equal(t, db.StandingOrderSuspended, arg.Status)
+						require.Zero(t, arg.FailureCount)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+		{
+			name:  "RunFinishedElsewhere",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrStandingOrderRunFinished)
+
+				// the scheduler that finished the run already recorded it
+				store.EXPECT().FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:  "CancelledOrderFails",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, db.ErrStandingOrderNotActive)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.Equal(t, db.ErrStandingOrderNotActive.Error(), arg.Error)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+		{
+			name:  "InternalErrorRetries",
+			order: order,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, order db.StandingOrder) {
+				store.EXPECT().
+					RunStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunStandingOrderTxResult{}, sql.ErrConnDone)
+
+				store.EXPECT().
+					FinishStandingOrderRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.FinishStandingOrderRunTxParams) (db.FinishStandingOrderRunTxResult, error) {
+						require.Equal(t, db.StandingOrderActive, arg.Status)
+						require.Zero(t, arg.FailureCount)
+						require.True(t, arg.NextRunAt.Valid)
+						return db.FinishStandingOrderRunTxResult{}, nil
+					})
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ClaimStandingOrdersTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.ClaimStandingOrdersTxParams) ([]db.ClaimedStandingOrder, error) {
+					require.Equal(t, int32(standingOrderBatchSize), arg.BatchSize)
+					require.Equal(t, standingOrderLease, arg.Lease)
+					return []db.ClaimedStandingOrder{{Order: tc.order, Run: run}}, nil
+				})
+			tc.buildStubs(t, store, tc.order)
+
+			scheduler := newStandingOrderScheduler(store, time.Minute, newMetrics())
+			executed, err := scheduler.RunDue(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, executed)
+		})
+	}
+}
+
+func TestStandingOrderSchedulerClaimsUntilDrained(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	order := randomStandingOrder("owner")
+	batch := make([]db.ClaimedStandingOrder, standingOrderBatchSize)
+	for i := range batch {
+		batch[i] = db.ClaimedStandingOrder{Order: order, Run: db.StandingOrderRun{ID: int64(i + 1)}}
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ClaimStandingOrdersTx(gomock.Any(), gomock.Any()).Times(1).Return(batch, nil),
+		store.EXPECT().ClaimStandingOrdersTx(gomock.Any(), gomock.Any()).Times(1).Return([]db.ClaimedStandingOrder{}, nil),
+	)
+	store.EXPECT().RunStandingOrderTx(gomock.Any(), gomock.Any()).Times(standingOrderBatchSize)
+
+	scheduler := newStandingOrderScheduler(store, time.Minute, newMetrics())
+	executed, err := scheduler.RunDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, standingOrderBatchSize, executed)
+}
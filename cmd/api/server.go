@@ -1,38 +1,85 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/mrohadi/simplebank/apierror"
 	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/ratelimit"
 	"github.com/mrohadi/simplebank/token"
 	"github.com/mrohadi/simplebank/utils"
 )
 
 // Server serve HTTP request for banking system
 type Server struct {
-	config     utils.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config      utils.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations *revocationList
+	scheduler   *standingOrderScheduler
+	dispatcher  *mailDispatcher
+	metrics     *metrics
+	limiter     ratelimit.Limiter
+	rateLimits  map[string]ratelimit.Limit
+	router      *gin.Engine
+
+	// trustedProxies are the only peers whose forwarded client address is used
+	trustedProxies []netip.Prefix
+
+	// shuttingDown is set once the server starts draining, it makes the readiness probe fail
+	shuttingDown atomic.Bool
 }
 
 // NewServer create new HTTP server and routing
 func NewServer(config utils.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmectricKey)
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	mailer, err := newMailSender(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mail sender: %w", err)
+	}
+	limiter, err := newRateLimiter(config.RateLimitBackend, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse trusted proxies: %w", err)
+	}
+	metrics := newMetrics()
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: newRevocationList(store, config.RevocationSyncInterval),
+		scheduler:   newStandingOrderScheduler(store, config.SchedulerInterval, metrics),
+		dispatcher:  newMailDispatcher(store, mailer, config.AppURL, config.MailDispatchInterval),
+		metrics:     metrics,
+		limiter:     limiter,
+		rateLimits:  newRateLimits(config),
+
+		trustedProxies: trustedProxies,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("role", validRole)
+		v.RegisterValidation("username", validUsername)
+		v.RegisterTagNameFunc(apierror.FieldName)
 	}
 
 	server.setupRouter()
@@ -42,30 +89,175 @@ func NewServer(config utils.Config, store db.Store) (*Server, error) {
 
 // setupRouter()
 func (s *Server) setupRouter() {
-	router := gin.Default()
+	logger := slog.Default()
+
+	router := gin.New()
+	// the handlers pass the gin context to the Store, it must carry the request ID of the request context
+	router.ContextWithFallback = true
+
+	// gin trusts every X-Forwarded-For by default, ClientIP must only read it from the trusted proxies.
+	// The prefixes are already validated by parseTrustedProxies.
+	proxies := make([]string, len(s.trustedProxies))
+	for i, proxy := range s.trustedProxies {
+		proxies[i] = proxy.String()
+	}
+	_ = router.SetTrustedProxies(proxies)
+	router.Use(
+		requestIDMiddleware(),
+		loggerMiddleware(logger),
+		recoveryMiddleware(logger),
+		s.metrics.middleware(),
+	)
+
+	// probes routing
+	router.GET("/healthz", s.healthz)
+	router.GET("/readyz", s.readyz)
+	router.GET("/version", s.version)
+	router.GET("/metrics", gin.WrapH(s.metrics.handler()))
+
+	// keys routing
+	router.GET("/.well-known/jwks.json", s.jwks)
 
 	// users routing
-	router.POST("/users", s.createUser)
-	router.POST("/users/login", s.loginUser)
+	router.POST("/users", s.rateLimitMiddleware(rateLimitPublic), s.createUser)
+	router.POST("/users/login", s.rateLimitMiddleware(rateLimitPublic), s.loginUser)
+	router.POST("/users/login/mfa", s.rateLimitMiddleware(rateLimitPublic), s.loginUserMFA)
+	router.POST("/users/password_reset", s.rateLimitMiddleware(rateLimitPublic), s.requestPasswordReset)
+	router.POST("/users/password_reset/confirm", s.rateLimitMiddleware(rateLimitPublic), s.confirmPasswordReset)
+	router.GET("/users/verify_email", s.rateLimitMiddleware(rateLimitPublic), s.verifyEmail)
+
+	// tokens routing
+	router.POST("/tokens/renew_access", s.rateLimitMiddleware(rateLimitPublic), s.renewAccessToken)
 
-	authRoutes := router.Group("/").Use(authMiddleware(s.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(s.tokenMaker, s.revocations), s.rateLimitMiddleware(rateLimitAPI))
+	transferLimit := s.rateLimitMiddleware(rateLimitTransfer)
+	verifiedEmail := s.requireVerifiedEmail()
+	idempotency := idempotencyMiddleware(s.store, s.config.IdempotencyKeyDuration)
+
+	authRoutes.POST("/users/logout", s.logoutUser)
+	authRoutes.POST("/users/logout_all", s.logoutAllSessions)
+	authRoutes.GET("/users/me", s.getCurrentUser)
+	authRoutes.PATCH("/users/me", s.updateCurrentUser)
+	authRoutes.PUT("/users/me/password", s.changePassword)
+	authRoutes.POST("/users/me/totp", s.enrollTOTP)
+	authRoutes.POST("/users/me/totp/confirm", s.confirmTOTP)
+	authRoutes.GET("/users/:username", requireRoles(utils.AdminRole), s.getUser)
+	authRoutes.PATCH("/users/:username/role", requireRoles(utils.AdminRole), s.updateUserRole)
+	authRoutes.GET("/users/:username/lockout", requireRoles(utils.AdminRole), s.getUserLockout)
+	authRoutes.POST("/users/:username/unlock", requireRoles(utils.AdminRole), s.unlockUser)
 
 	// accounts routing
-	authRoutes.POST("/accounts", s.createAccount)
+	authRoutes.POST("/accounts", idempotency, s.createAccount)
 	authRoutes.GET("/accounts/:id", s.getAccount)
 	authRoutes.GET("/accounts", s.listAccount)
+	authRoutes.GET("/accounts/:id/entries", s.listAccountEntries)
+	authRoutes.GET("/accounts/:id/transfers", s.listAccountTransfers)
+	authRoutes.POST("/accounts/:id/deposits", requireRoles(utils.BankerRole, utils.AdminRole), transferLimit, idempotency, s.createDeposit)
+	authRoutes.POST("/accounts/:id/withdrawals", requireRoles(utils.BankerRole, utils.AdminRole), transferLimit, idempotency, s.createWithdrawal)
+	authRoutes.POST("/accounts/:id/freeze", requireRoles(utils.AdminRole), s.freezeAccount)
+	authRoutes.POST("/accounts/:id/unfreeze", requireRoles(utils.AdminRole), s.unfreezeAccount)
+	authRoutes.POST("/accounts/:id/close", idempotency, s.closeAccount)
 
 	// transfer routing
-	authRoutes.POST("/transfers", s.createTransfer)
+	authRoutes.POST("/transfers", transferLimit, verifiedEmail, idempotency, s.createTransfer)
+	authRoutes.GET("/transfers/:id", s.getTransfer)
+	authRoutes.POST("/transfers/:id/reversal", transferLimit, verifiedEmail, idempotency, s.createReversal)
+	authRoutes.POST("/rate_quotes", s.createRateQuote)
+
+	// standing orders routing
+	authRoutes.POST("/standing_orders", transferLimit, verifiedEmail, s.createStandingOrder)
+	authRoutes.GET("/standing_orders", s.listStandingOrders)
+	authRoutes.GET("/standing_orders/:id", s.getStandingOrder)
+	authRoutes.PATCH("/standing_orders/:id", s.updateStandingOrder)
+	authRoutes.DELETE("/standing_orders/:id", s.deleteStandingOrder)
+	authRoutes.GET("/standing_orders/:id/runs", s.listStandingOrderRuns)
 
 	s.router = router
 }
 
-// Start runs the HTTP server on a specific address.
-func (s *Server) Start(addr string) error {
-	return s.router.Run(addr)
+const defaultShutdownTimeout = 30 * time.Second
+
+// Start runs the HTTP server on a specific address until ctx is canceled.
+// The in-flight requests are then drained within the shutdown timeout before the background workers are stopped.
+func (s *Server) Start(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot create HTTP listener: %w", err)
+	}
+
+	return s.serve(ctx, listener)
+}
+
+func (s *Server) serve(ctx context.Context, listener net.Listener) error {
+	// the workers outlive ctx so the requests being drained still see the revoked tokens
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(5)
+	go func() {
+		defer workers.Done()
+		s.revocations.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		s.scheduler.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		s.pruneRateLimits(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		s.dispatcher.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		s.pruneIdempotencyKeys(workerCtx)
+	}()
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+
+	httpServer := &http.Server{
+		Handler:      s.router,
+		ReadTimeout:  s.config.HTTPReadTimeout,
+		WriteTimeout: s.config.HTTPWriteTimeout,
+		IdleTimeout:  s.config.HTTPIdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("cannot serve HTTP: %w", err)
+	case <-ctx.Done():
+	}
+
+	// keep serving while the load balancers notice the failing readiness probe
+	s.shuttingDown.Store(true)
+	time.Sleep(s.config.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("cannot drain HTTP connections: %w", err)
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("cannot serve HTTP: %w", err)
+	}
+
+	return nil
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.config.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+
+	return s.config.ShutdownTimeout
 }
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newLifecycleTestServer creates a server whose background workers find nothing to do
+func newLifecycleTestServer(t *testing.T, shutdownTimeout time.Duration) *Server {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListRevokedTokens(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.RevokedToken{}, nil)
+	store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).AnyTimes().Return(nil)
+	store.EXPECT().DeleteExpiredSessionAccessTokens(gomock.Any()).AnyTimes().Return(nil)
+	store.EXPECT().ClaimStandingOrdersTx(gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+	store.EXPECT().ClaimVerifyEmailsTx(gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+
+	server, err := NewServer(utils.Config{
+		TokenSymmectricKey:   utils.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		ShutdownTimeout:      shutdownTimeout,
+	}, store)
+	require.NoError(t, err)
+
+	return server
+}
+
+// addSlowRoute registers a route that blocks until release is closed, started is closed once it is in-flight
+func addSlowRoute(server *Server, path string) (started chan struct{}, release chan struct{}) {
+	started = make(chan struct{})
+	release = make(chan struct{})
+	server.router.GET(path, func(ctx *gin.Context) {
+		close(started)
+		<-release
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	return started, release
+}
+
+func TestServerShutdownDrainsInFlightRequests(t *testing.T) {
+	server := newLifecycleTestServer(t, 5*time.Second)
+	started, release := addSlowRoute(server, "/slow")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(ctx, listener)
+	}()
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		rsp, err := http.Get(fmt.Sprintf("http://%s/slow", addr))
+		if err == nil {
+			responses <- rsp
+		}
+		close(responses)
+	}()
+
+	<-started
+	cancel()
+
+	// the listener is closed right away, so new connections are refused
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-served:
+		t.Fatalf("server stopped before the in-flight request completed: %v", err)
+	default:
+	}
+
+	close(release)
+
+	rsp, ok := <-responses
+	require.True(t, ok)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	rsp.Body.Close()
+
+	require.NoError(t, <-served)
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	server := newLifecycleTestServer(t, 50*time.Millisecond)
+	started, release := addSlowRoute(server, "/slow")
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(ctx, listener)
+	}()
+
+	go http.Get(fmt.Sprintf("http://%s/slow", listener.Addr()))
+
+	<-started
+	cancel()
+
+	err = <-served
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGRPCServerShutdown(t *testing.T) {
+	server := newLifecycleTestServer(t, 5*time.Second)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serveGRPC(ctx, listener)
+	}()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// a public method answers as long as the server runs
+	_, err = pb.NewSimpleBankClient(conn).LoginUser(context.Background(), &pb.LoginUserRequest{})
+	requireGRPCCode(t, err, codes.InvalidArgument)
+
+	cancel()
+	require.NoError(t, <-served)
+}
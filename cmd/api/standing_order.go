@@ -0,0 +1,472 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+)
+
+type createStandingOrderRequest struct {
+	FromAccountID int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64      `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64      `json:"amount" binding:"required,gt=0"`
+	Currency      string     `json:"currency" binding:"required,currency"`
+	Frequency     string     `json:"frequency" binding:"required,oneof=once daily weekly monthly"`
+	DayOfMonth    *int32     `json:"day_of_month" binding:"omitempty,min=1,max=31"`
+	StartAt       time.Time  `json:"start_at" binding:"required"`
+	EndAt         *time.Time `json:"end_at"`
+	// TOTPCode is the one-time code required for the amounts above the step-up threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+type standingOrderResponse struct {
+	ID            int64      `json:"id"`
+	Owner         string     `json:"owner"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	Currency      string     `json:"currency"`
+	Frequency     string     `json:"frequency"`
+	DayOfMonth    *int32     `json:"day_of_month,omitempty"`
+	StartAt       time.Time  `json:"start_at"`
+	EndAt         *time.Time `json:"end_at,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	Status        string     `json:"status"`
+	FailureCount  int32      `json:"failure_count"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func newStandingOrderResponse(order db.StandingOrder) standingOrderResponse {
+	rsp := standingOrderResponse{
+		ID:            order.ID,
+		Owner:         order.Owner,
+		FromAccountID: order.FromAccountID,
+		ToAccountID:   order.ToAccountID,
+		Amount:        order.Amount,
+		Currency:      order.Currency,
+		Frequency:     order.Frequency,
+		StartAt:       order.StartAt,
+		Status:        order.Status,
+		FailureCount:  order.FailureCount,
+		CreatedAt:     order.CreatedAt,
+		UpdatedAt:     order.UpdatedAt,
+	}
+	if order.DayOfMonth.Valid {
+		rsp.DayOfMonth = &order.DayOfMonth.Int32
+	}
+	if order.EndAt.Valid {
+		rsp.EndAt = &order.EndAt.Time
+	}
+	if order.NextRunAt.Valid {
+		rsp.NextRunAt = &order.NextRunAt.Time
+	}
+
+	return rsp
+}
+
+// createStandingOrder handle create a standing order
+func (s *Server) createStandingOrder(ctx *gin.Context) {
+	var req createStandingOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if (req.Frequency == db.StandingOrderMonthly) != (req.DayOfMonth != nil) {
+		writeError(ctx, apierror.Validation("day_of_month", "required_if", "is required for monthly orders and not allowed otherwise"))
+		return
+	}
+
+	if !req.StartAt.After(time.Now()) {
+		writeError(ctx, apierror.Validation("start_at", "future", "must be in the future"))
+		return
+	}
+
+	if req.EndAt != nil && req.EndAt.Before(req.StartAt) {
+		writeError(ctx, apierror.Validation("end_at", "gtefield", "must not be before start_at"))
+		return
+	}
+
+	fromAccount, valid := s.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		writeError(ctx, apierror.New(apierror.CodeAccountNotOwned, "from account doesn't belong to authenticated user"))
+		return
+	}
+
+	_, valid = s.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	arg := db.CreateStandingOrderParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Frequency:     req.Frequency,
+		StartAt:       req.StartAt,
+	}
+	if req.DayOfMonth != nil {
+		arg.DayOfMonth = sql.NullInt32{Int32: *req.DayOfMonth, Valid: true}
+	}
+	if req.EndAt != nil {
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+
+	nextRunAt, ok := nextStandingOrderRun(db.StandingOrder{
+		Frequency:  arg.Frequency,
+		DayOfMonth: arg.DayOfMonth,
+		StartAt:    arg.StartAt,
+		EndAt:      arg.EndAt,
+	}, req.StartAt.Add(-time.Nanosecond))
+	if !ok {
+		writeError(ctx, apierror.Validation("end_at", "schedule", "must leave at least one run after start_at"))
+		return
+	}
+	arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: true}
+
+	// the one-time code is checked last so it is not used up by an order failing validation
+	if apiErr := s.checkStepUp(ctx, authPayload.Username, req.Amount, req.TOTPCode); apiErr != nil {
+		writeError(ctx, apiErr)
+		return
+	}
+
+	order, err := s.store.CreateStandingOrder(ctx, arg)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.StandingOrder))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newStandingOrderResponse(order))
+}
+
+type getStandingOrderRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getStandingOrder handle get a standing order by id
+func (s *Server) getStandingOrder(ctx *gin.Context) {
+	order, valid := s.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newStandingOrderResponse(order))
+}
+
+type listStandingOrdersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listStandingOrders handle get list of standing orders of the authenticated user
+func (s *Server) listStandingOrders(ctx *gin.Context) {
+	var req listStandingOrdersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	orders, err := s.store.ListStandingOrders(ctx, db.ListStandingOrdersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.StandingOrder))
+		return
+	}
+
+	rsp := make([]standingOrderResponse, len(orders))
+	for i, order := range orders {
+		rsp[i] = newStandingOrderResponse(order)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateStandingOrderRequest struct {
+	Amount *int64     `json:"amount" binding:"omitempty,gt=0"`
+	EndAt  *time.Time `json:"end_at"`
+	Status *string    `json:"status" binding:"omitempty,oneof=active suspended"`
+	// TOTPCode is the one-time code required when the amount is raised above the step-up threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+// updateStandingOrder handle changing the amount or end date of a standing order, and suspending or resuming it.
+// Resuming an order resets its failure count and schedules its next run from now on.
+func (s *Server) updateStandingOrder(ctx *gin.Context) {
+	var req updateStandingOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	order, valid := s.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	if order.Status == db.StandingOrderCompleted || order.Status == db.StandingOrderCancelled {
+		writeError(ctx, apierror.New(apierror.CodeStandingOrderFinished, fmt.Sprintf("standing order is %s", order.Status)))
+		return
+	}
+
+	arg := db.UpdateStandingOrderParams{
+		ID:           order.ID,
+		Amount:       order.Amount,
+		EndAt:        order.EndAt,
+		Status:       order.Status,
+		NextRunAt:    order.NextRunAt,
+		FailureCount: order.FailureCount,
+		UpdatedAt:    order.UpdatedAt,
+	}
+	if req.Amount != nil {
+		arg.Amount = *req.Amount
+	}
+	if req.EndAt != nil {
+		if req.EndAt.Before(order.StartAt) {
+			writeError(ctx, apierror.Validation("end_at", "gtefield", "must not be before start_at"))
+			return
+		}
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+	if req.Status != nil {
+		arg.Status = *req.Status
+	}
+
+	switch {
+	case arg.Status == db.StandingOrderSuspended:
+		arg.NextRunAt = sql.NullTime{}
+	case order.Status == db.StandingOrderSuspended:
+		// resuming skips the runs missed while the order was suspended
+		order.EndAt = arg.EndAt
+		nextRunAt, ok := nextStandingOrderRun(order, time.Now())
+		arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: ok}
+		arg.FailureCount = 0
+	case arg.EndAt.Valid && arg.NextRunAt.Valid && arg.NextRunAt.Time.After(arg.EndAt.Time):
+		arg.NextRunAt = sql.NullTime{}
+	}
+
+	if arg.Status == db.StandingOrderActive && !arg.NextRunAt.Valid {
+		arg.Status = db.StandingOrderCompleted
+	}
+
+	if arg.Amount > order.Amount {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if apiErr := s.checkStepUp(ctx, authPayload.Username, arg.Amount, req.TOTPCode); apiErr != nil {
+			writeError(ctx, apiErr)
+			return
+		}
+	}
+
+	order, err := s.store.UpdateStandingOrder(ctx, arg)
+	if err != nil {
+		writeError(ctx, standingOrderUpdateError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newStandingOrderResponse(order))
+}
+
+// deleteStandingOrder handle cancelling a standing order. The order is kept so its runs can still be listed.
+func (s *Server) deleteStandingOrder(ctx *gin.Context) {
+	order, valid := s.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	if order.Status == db.StandingOrderCompleted {
+		writeError(ctx, apierror.New(apierror.CodeStandingOrderFinished, fmt.Sprintf("standing order is %s", order.Status)))
+		return
+	}
+
+	order, err := s.store.UpdateStandingOrder(ctx, db.UpdateStandingOrderParams{
+		ID:           order.ID,
+		Amount:       order.Amount,
+		EndAt:        order.EndAt,
+		Status:       db.StandingOrderCancelled,
+		NextRunAt:    sql.NullTime{},
+		FailureCount: order.FailureCount,
+		UpdatedAt:    order.UpdatedAt,
+	})
+	if err != nil {
+		writeError(ctx, standingOrderUpdateError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newStandingOrderResponse(order))
+}
+
+type listStandingOrderRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+type standingOrderRunResponse struct {
+	ID          int64      `json:"id"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	Status      string     `json:"status"`
+	TransferID  *int64     `json:"transfer_id,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+func newStandingOrderRunResponse(run db.StandingOrderRun) standingOrderRunResponse {
+	rsp := standingOrderRunResponse{
+		ID:          run.ID,
+		ScheduledAt: run.ScheduledAt,
+		Status:      run.Status,
+		Error:       run.Error.String,
+	}
+	if run.TransferID.Valid {
+		rsp.TransferID = &run.TransferID.Int64
+	}
+	if run.FinishedAt.Valid {
+		rsp.FinishedAt = &run.FinishedAt.Time
+	}
+
+	return rsp
+}
+
+// listStandingOrderRuns handle get the runs of a standing order, most recent first
+func (s *Server) listStandingOrderRuns(ctx *gin.Context) {
+	var req listStandingOrderRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	order, valid := s.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	runs, err := s.store.ListStandingOrderRuns(ctx, db.ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           req.PageSize,
+		Offset:          (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.StandingOrder))
+		return
+	}
+
+	rsp := make([]standingOrderRunResponse, len(runs))
+	for i, run := range runs {
+		rsp[i] = newStandingOrderRunResponse(run)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ownedStandingOrder loads the standing order from the URI and checks it belongs to the authenticated user
+func (s *Server) ownedStandingOrder(ctx *gin.Context) (db.StandingOrder, bool) {
+	var req getStandingOrderRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return db.StandingOrder{}, false
+	}
+
+	order, err := s.store.GetStandingOrder(ctx, req.ID)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.StandingOrder))
+		return order, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if order.Owner != authPayload.Username {
+		writeError(ctx, apierror.New(apierror.CodeStandingOrderNotOwned, "standing order doesn't belong to the authorized user"))
+		return order, false
+	}
+
+	return order, true
+}
+
+// standingOrderUpdateError converts an error of UpdateStandingOrder.
+// No row is updated when the scheduler or another request changed the order since it was read, the request can be retried.
+func standingOrderUpdateError(err error) *apierror.Error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.New(apierror.CodeTransactionConflict, "standing order was changed concurrently")
+	}
+
+	return apierror.FromDB(err, apierror.StandingOrder)
+}
+
+// nextStandingOrderRun returns the first run of the order strictly after the given time.
+// Runs are anchored on start_at so that late or retried runs do not shift the schedule.
+// It returns false when the order has no run left before its end date.
+func nextStandingOrderRun(order db.StandingOrder, after time.Time) (time.Time, bool) {
+	start := order.StartAt.UTC()
+	after = after.UTC()
+
+	var next time.Time
+	switch order.Frequency {
+	case db.StandingOrderOnce:
+		next = start
+		if !next.After(after) {
+			return time.Time{}, false
+		}
+	case db.StandingOrderDaily, db.StandingOrderWeekly:
+		days := 1
+		if order.Frequency == db.StandingOrderWeekly {
+			days = 7
+		}
+
+		next = start
+		if !next.After(after) {
+			periods := int(after.Sub(start) / (time.Duration(days) * 24 * time.Hour))
+			next = start.AddDate(0, 0, periods*days)
+			for !next.After(after) {
+				periods++
+				next = start.AddDate(0, 0, periods*days)
+			}
+		}
+	case db.StandingOrderMonthly:
+		from := start
+		if after.After(from) {
+			from = after
+		}
+
+		for months := 0; ; months++ {
+			next = monthlyRun(start, from.Year(), from.Month()+time.Month(months), int(order.DayOfMonth.Int32))
+			if !next.Before(start) && next.After(after) {
+				break
+			}
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if order.EndAt.Valid && next.After(order.EndAt.Time) {
+		return time.Time{}, false
+	}
+
+	return next, true
+}
+
+// monthlyRun returns the run of a monthly order in the given month at the start time of day.
+// The day is clamped to the last day of shorter months.
+func monthlyRun(start time.Time, year int, month time.Month, day int) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,723 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateStandingOrderAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = utils.USD
+	account2.Currency = utils.USD
+
+	startAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Created",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderMonthly,
+				"day_of_month":    31,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				firstRun := monthlyRun(startAt, startAt.Year(), startAt.Month(), 31)
+				arg := db.CreateStandingOrderParams{
+					Owner:         user1.Username,
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        10,
+					Currency:      utils.USD,
+					Frequency:     db.StandingOrderMonthly,
+					DayOfMonth:    sql.NullInt32{Int32: 31, Valid: true},
+					StartAt:       startAt,
+					NextRunAt:     sql.NullTime{Time: firstRun, Valid: true},
+				}
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.StandingOrder{ID: 1, Owner: arg.Owner, Status: db.StandingOrderActive, NextRunAt: arg.NextRunAt}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp standingOrderResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, db.StandingOrderActive, rsp.Status)
+				require.NotNil(t, rsp.NextRunAt)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderDaily,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeUnauthenticated)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderDaily,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeAccountNotOwned)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.EUR,
+				"frequency":       db.StandingOrderDaily,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeCurrencyMismatch)
+			},
+		},
+		{
+			name: "MonthlyWithoutDay",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderMonthly,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "InvalidFrequency",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       "hourly",
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "StartInThePast",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderOnce,
+				"start_at":        time.Now().Add(-time.Hour),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "EndBeforeStart",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderDaily,
+				"start_at":        startAt,
+				"end_at":          startAt.Add(-time.Minute),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          10,
+				"currency":        utils.USD,
+				"frequency":       db.StandingOrderWeekly,
+				"start_at":        startAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(1).Return(db.StandingOrder{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			allowVerifiedEmail(store)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/standing_orders", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetStandingOrderAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+	order := randomStandingOrder(user.Username)
+
+	testCases := []struct {
+		name          string
+		orderID       int64
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			orderID:  order.ID,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp standingOrderResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, order.ID, rsp.ID)
+				require.Equal(t, order.Amount, rsp.Amount)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			orderID:  order.ID,
+			username: otherUser.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeStandingOrderNotOwned)
+			},
+		},
+		{
+			name:     "NotFound",
+			orderID:  order.ID,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(db.StandingOrder{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeStandingOrderNotFound)
+			},
+		},
+		{
+			name:     "InvalidID",
+			orderID:  0,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/standing_orders/%d", tc.orderID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateStandingOrderAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	order := randomStandingOrder(user.Username)
+
+	suspended := order
+	suspended.Status = db.StandingOrderSuspended
+	suspended.NextRunAt = sql.NullTime{}
+	suspended.FailureCount = maxStandingOrderFailures
+
+	cancelled := order
+	cancelled.Status = db.StandingOrderCancelled
+	cancelled.NextRunAt = sql.NullTime{}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ChangeAmount",
+			body: gin.H{"amount": 42},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+
+				arg := db.UpdateStandingOrderParams{
+					ID:           order.ID,
+					Amount:       42,
+					Status:       db.StandingOrderActive,
+					NextRunAt:    order.NextRunAt,
+					FailureCount: order.FailureCount,
+					UpdatedAt:    order.UpdatedAt,
+				}
+				store.EXPECT().UpdateStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(order, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Suspend",
+			body: gin.H{"status": db.StandingOrderSuspended},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+
+				arg := db.UpdateStandingOrderParams{
+					ID:           order.ID,
+					Amount:       order.Amount,
+					Status:       db.StandingOrderSuspended,
+					FailureCount: order.FailureCount,
+					UpdatedAt:    order.UpdatedAt,
+				}
+				store.EXPECT().UpdateStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(suspended, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Resume",
+			body: gin.H{"status": db.StandingOrderActive},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(suspended, nil)
+				store.EXPECT().
+					UpdateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, db.StandingOrderActive, arg.Status)
+						require.Zero(t, arg.FailureCount)
+						require.True(t, arg.NextRunAt.Valid)
+						require.True(t, arg.NextRunAt.Time.After(time.Now()))
+						return order, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "EndBeforeNextRun",
+			body: gin.H{"end_at": order.NextRunAt.Time.Add(-time.Minute)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+				store.EXPECT().
+					UpdateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, db.StandingOrderCompleted, arg.Status)
+						require.False(t, arg.NextRunAt.Valid)
+						return order, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ChangedConcurrently",
+			body: gin.H{"amount": 42},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+				store.EXPECT().
+					UpdateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeTransactionConflict)
+			},
+		},
+		{
+			name: "Cancelled",
+			body: gin.H{"amount": 42},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().UpdateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeStandingOrderFinished)
+			},
+		},
+		{
+			name: "InvalidStatus",
+			body: gin.H{"status": db.StandingOrderCompleted},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/standing_orders/%d", order.ID)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteStandingOrderAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	order := randomStandingOrder(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+
+	arg := db.UpdateStandingOrderParams{
+		ID:           order.ID,
+		Amount:       order.Amount,
+		Status:       db.StandingOrderCancelled,
+		FailureCount: order.FailureCount,
+		UpdatedAt:    order.UpdatedAt,
+	}
+	cancelled := order
+	cancelled.Status = db.StandingOrderCancelled
+	cancelled.NextRunAt = sql.NullTime{}
+	store.EXPECT().UpdateStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(cancelled, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/standing_orders/%d", order.ID)
+	request, err := http.NewRequest(http.MethodDelete, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp standingOrderResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, db.StandingOrderCancelled, rsp.Status)
+	require.Nil(t, rsp.NextRunAt)
+}
+
+func TestListStandingOrderRunsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	order := randomStandingOrder(user.Username)
+	runs := []db.StandingOrderRun{
+		{
+			ID:              2,
+			StandingOrderID: order.ID,
+			Status:          db.StandingOrderRunFailed,
+			Error:           sql.NullString{String: "insufficient funds", Valid: true},
+		},
+		{
+			ID:              1,
+			StandingOrderID: order.ID,
+			Status:          db.StandingOrderRunSucceeded,
+			TransferID:      sql.NullInt64{Int64: 7, Valid: true},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).Times(1).Return(order, nil)
+
+	arg := db.ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           5,
+		Offset:          0,
+	}
+	store.EXPECT().ListStandingOrderRuns(gomock.Any(), gomock.Eq(arg)).Times(1).Return(runs, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/standing_orders/%d/runs?page_id=1&page_size=5", order.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []standingOrderRunResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp, 2)
+	require.Equal(t, "insufficient funds", rsp[0].Error)
+	require.Equal(t, int64(7), *rsp[1].TransferID)
+}
+
+func TestNextStandingOrderRun(t *testing.T) {
+	start := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		order    db.StandingOrder
+		after    time.Time
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "OnceBeforeStart",
+			order:    db.StandingOrder{Frequency: db.StandingOrderOnce, StartAt: start},
+			after:    start.Add(-time.Hour),
+			expected: start,
+			ok:       true,
+		},
+		{
+			name:  "OnceAfterStart",
+			order: db.StandingOrder{Frequency: db.StandingOrderOnce, StartAt: start},
+			after: start,
+		},
+		{
+			name:     "Daily",
+			order:    db.StandingOrder{Frequency: db.StandingOrderDaily, StartAt: start},
+			after:    start.Add(50 * time.Hour),
+			expected: start.AddDate(0, 0, 3),
+			ok:       true,
+		},
+		{
+			name:     "WeeklyOnRunTime",
+			order:    db.StandingOrder{Frequency: db.StandingOrderWeekly, StartAt: start},
+			after:    start.AddDate(0, 0, 7),
+			expected: start.AddDate(0, 0, 14),
+			ok:       true,
+		},
+		{
+			name: "MonthlyClampedToLastDay",
+			order: db.StandingOrder{
+				Frequency:  db.StandingOrderMonthly,
+				DayOfMonth: sql.NullInt32{Int32: 31, Valid: true},
+				StartAt:    start,
+			},
+			after:    start,
+			expected: time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name: "MonthlyFirstRunAfterStart",
+			order: db.StandingOrder{
+				Frequency:  db.StandingOrderMonthly,
+				DayOfMonth: sql.NullInt32{Int32: 15, Valid: true},
+				StartAt:    start,
+			},
+			after:    start.Add(-time.Nanosecond),
+			expected: time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name: "MonthlyAcrossYear",
+			order: db.StandingOrder{
+				Frequency:  db.StandingOrderMonthly,
+				DayOfMonth: sql.NullInt32{Int32: 1, Valid: true},
+				StartAt:    start,
+			},
+			after:    time.Date(2026, 12, 1, 9, 0, 0, 0, time.UTC),
+			expected: time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name: "PastEndDate",
+			order: db.StandingOrder{
+				Frequency: db.StandingOrderDaily,
+				StartAt:   start,
+				EndAt:     sql.NullTime{Time: start.AddDate(0, 0, 2), Valid: true},
+			},
+			after: start.AddDate(0, 0, 2),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, ok := nextStandingOrderRun(tc.order, tc.after)
+			require.Equal(t, tc.ok, ok)
+			if tc.ok {
+				require.True(t, tc.expected.Equal(next), "expected %s, got %s", tc.expected, next)
+			}
+		})
+	}
+}
+
+func randomStandingOrder(owner string) db.StandingOrder {
+	startAt := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	return db.StandingOrder{
+		ID:            utils.RandomInt(1, 1000),
+		Owner:         owner,
+		FromAccountID: utils.RandomInt(1, 1000),
+		ToAccountID:   utils.RandomInt(1, 1000),
+		Amount:        utils.RandomMoney(),
+		Currency:      utils.USD,
+		Frequency:     db.StandingOrderDaily,
+		StartAt:       startAt,
+		NextRunAt:     sql.NullTime{Time: startAt.AddDate(0, 0, 2), Valid: true},
+		Status:        db.StandingOrderActive,
+		UpdatedAt:     startAt,
+	}
+}
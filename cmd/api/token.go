@@ -0,0 +1,116 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+)
+
+// newTokenMaker creates the maker of the configured token type.
+// During a migration the tokens of the previous type are still accepted while only the new type is issued.
+func newTokenMaker(config utils.Config) (token.Maker, error) {
+	signingKeys, err := token.ParseEd25519Keys(config.TokenSigningKeys)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read signing keys: %w", err)
+	}
+
+	maker, err := token.NewMaker(config.TokenType, config.TokenSymmectricKey, signingKeys)
+	if err != nil || len(config.TokenPreviousType) == 0 {
+		return maker, err
+	}
+
+	tokenType := config.TokenType
+	if len(tokenType) == 0 {
+		tokenType = token.TypePaseto
+	}
+	if config.TokenPreviousType == tokenType {
+		return nil, fmt.Errorf("the previous token type must differ from %q", tokenType)
+	}
+
+	previous, err := token.NewMaker(config.TokenPreviousType, config.TokenSymmectricKey, signingKeys)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create maker of the previous token type: %w", err)
+	}
+
+	return token.NewMigrationMaker(maker, previous), nil
+}
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// renewAccessToken handle issuing a new access token from a valid refresh token
+func (s *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		writeError(ctx, apierror.FromToken(err))
+		return
+	}
+
+	session, err := s.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Session))
+		return
+	}
+
+	if session.IsBlocked {
+		writeError(ctx, apierror.New(apierror.CodeSessionBlocked, "blocked session"))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		writeError(ctx, apierror.New(apierror.CodeSessionNotOwned, "incorrect session user"))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		writeError(ctx, apierror.New(apierror.CodeSessionExpired, "expired session"))
+		return
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(refreshPayload.Username, refreshPayload.Role, s.config.AccessTokenDuration)
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	// no row is recorded when the session was blocked since it was read
+	_, err = s.store.CreateSessionAccessToken(ctx, db.CreateSessionAccessTokenParams{
+		ID:        accessPayload.ID,
+		ExpiresAt: accessPayload.ExpiredAt,
+		SessionID: session.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(ctx, apierror.New(apierror.CodeSessionBlocked, "blocked session"))
+		return
+	}
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
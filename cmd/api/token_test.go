@@ -0,0 +1,384 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, tokenMaker token.Maker) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{
+						Username:  user.Username,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil)
+				expectSessionAccessToken(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotZero(t, rsp.AccessTokenExpiresAt)
+			},
+		},
+		{
+			name: "BadRequest",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeValidationFailed)
+			},
+		},
+		{
+			name: "InvalidToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": "invalid"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeTokenInvalid)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeSessionNotFound)
+			},
+		},
+		{
+			name: "InternalError",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{
+						Username:  user.Username,
+						IsBlocked: true,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeSessionBlocked)
+			},
+		},
+		{
+			name: "SessionBlockedConcurrently",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{
+						Username:  user.Username,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil)
+				store.EXPECT().
+					CreateSessionAccessToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.SessionAccessToken{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeSessionBlocked)
+			},
+		},
+		{
+			name: "RecordAccessTokenError",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{
+						Username:  user.Username,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil)
+				store.EXPECT().
+					CreateSessionAccessToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.SessionAccessToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeInternal)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{
+						Username:  "another",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeSessionNotOwned)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"refresh_token": createRefreshToken(t, tokenMaker, user.Username)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{
+						Username:  user.Username,
+						ExpiresAt: time.Now().Add(-time.Minute),
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeSessionExpired)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.buildBody(t, server.tokenMaker))
+			require.NoError(t, err)
+
+			url := "/tokens/renew_access"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// expectSessionAccessToken expects the access token of a login or a renewal to be recorded against its session
+func expectSessionAccessToken(store *mockdb.MockStore) {
+	store.EXPECT().
+		CreateSessionAccessToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateSessionAccessTokenParams) (db.SessionAccessToken, error) {
+			return db.SessionAccessToken{ID: arg.ID, SessionID: arg.SessionID, ExpiresAt: arg.ExpiresAt}, nil
+		})
+}
+
+func createRefreshToken(t *testing.T, tokenMaker token.Maker, username string) string {
+	refreshToken, payload, err := tokenMaker.CreateToken(username, utils.DepositorRole, time.Hour)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	return refreshToken
+}
+
+func TestNewTokenMaker(t *testing.T) {
+	key, err := token.GenerateEd25519Key("key")
+	require.NoError(t, err)
+
+	symmetricKey := utils.RandomString(32)
+
+	testCases := []struct {
+		name        string
+		config      utils.Config
+		checkResult func(t *testing.T, maker token.Maker, err error)
+	}{
+		{
+			name:   "DefaultType",
+			config: utils.Config{TokenSymmectricKey: symmetricKey},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.PasetoMaker{}, maker)
+			},
+		},
+		{
+			name:   "JWT",
+			config: utils.Config{TokenType: token.TypeJWT, TokenSymmectricKey: symmetricKey},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.JWTMaker{}, maker)
+			},
+		},
+		{
+			name:   "PasetoPublic",
+			config: utils.Config{TokenType: token.TypePasetoPublic, TokenSigningKeys: key.String()},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.PasetoPublicMaker{}, maker)
+			},
+		},
+		{
+			name:   "JWTEdDSA",
+			config: utils.Config{TokenType: token.TypeJWTEdDSA, TokenSigningKeys: key.String()},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.JWTEdDSAMaker{}, maker)
+			},
+		},
+		{
+			name:   "UnknownType",
+			config: utils.Config{TokenType: "unknown", TokenSymmectricKey: symmetricKey},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:   "InvalidSymmetricKeySize",
+			config: utils.Config{TokenType: token.TypePaseto, TokenSymmectricKey: "short"},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:   "MissingSigningKeys",
+			config: utils.Config{TokenType: token.TypePasetoPublic, TokenSymmectricKey: symmetricKey},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:   "InvalidSigningKeys",
+			config: utils.Config{TokenType: token.TypeJWTEdDSA, TokenSigningKeys: "key:short"},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "Migration",
+			config: utils.Config{
+				TokenType:          token.TypePasetoPublic,
+				TokenPreviousType:  token.TypePaseto,
+				TokenSymmectricKey: symmetricKey,
+				TokenSigningKeys:   key.String(),
+			},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.MigrationMaker{}, maker)
+
+				previous, err := token.NewPasetoMaker(symmetricKey)
+				require.NoError(t, err)
+				oldToken, _, err := previous.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+				require.NoError(t, err)
+
+				payload, err := maker.VerifyToken(oldToken)
+				require.NoError(t, err)
+				require.NotEmpty(t, payload)
+
+				newToken, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				_, err = previous.VerifyToken(newToken)
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "SamePreviousType",
+			config: utils.Config{
+				TokenPreviousType:  token.TypePaseto,
+				TokenSymmectricKey: symmetricKey,
+			},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "InvalidPreviousType",
+			config: utils.Config{
+				TokenType:          token.TypePaseto,
+				TokenPreviousType:  "unknown",
+				TokenSymmectricKey: symmetricKey,
+			},
+			checkResult: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := newTokenMaker(tc.config)
+			tc.checkResult(t, maker, err)
+		})
+	}
+}
@@ -1,12 +1,13 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
 	db "github.com/mrohadi/simplebank/db/sqlc"
 	"github.com/mrohadi/simplebank/token"
 )
@@ -16,13 +17,17 @@ type transferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// QuoteID turns the request into a cross-currency transfer, Currency is then the source account currency
+	QuoteID string `json:"quote_id" binding:"omitempty,uuid"`
+	// TOTPCode is the one-time code required for the amounts above the step-up threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
 }
 
 // createAccount handle create account
 func (s *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
@@ -33,13 +38,7 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("from account doesn't belong to authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
-		return
-	}
-
-	_, valid = s.validAccount(ctx, req.ToAccountID, req.Currency)
-	if !valid {
+		writeError(ctx, apierror.New(apierror.CodeAccountNotOwned, "from account doesn't belong to authenticated user"))
 		return
 	}
 
@@ -49,9 +48,33 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 		Amount:        req.Amount,
 	}
 
+	toCurrency := req.Currency
+	if req.QuoteID != "" {
+		quote, valid := s.validRateQuote(ctx, req.QuoteID, authPayload.Username, req.Currency)
+		if !valid {
+			return
+		}
+
+		toCurrency = quote.ToCurrency
+		arg.QuoteID = uuid.NullUUID{UUID: quote.ID, Valid: true}
+	}
+
+	_, valid = s.validAccount(ctx, req.ToAccountID, toCurrency)
+	if !valid {
+		return
+	}
+
+	// the one-time code is checked last so it is not used up by a transfer failing validation
+	if apiErr := s.checkStepUp(ctx, authPayload.Username, req.Amount, req.TOTPCode); apiErr != nil {
+		writeError(ctx, apiErr)
+		return
+	}
+
+	start := time.Now()
 	result, err := s.store.TransferTx(ctx, arg)
+	s.metrics.observeTransferTx(req.Currency, time.Since(start), result, err)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, apierror.FromDB(err, apierror.Transfer))
 		return
 	}
 
@@ -61,20 +84,43 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 func (s *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	account, err := s.store.GetAccount(ctx, accountID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, apierror.FromDB(err, apierror.Account))
 		return account, false
 	}
 
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		detail := fmt.Sprintf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		writeError(ctx, apierror.New(apierror.CodeCurrencyMismatch, detail))
 		return account, false
 	}
 
 	return account, true
 }
+
+// validRateQuote checks that a rate quote exists, belongs to the user and converts from the given currency
+func (s *Server) validRateQuote(ctx *gin.Context, quoteID string, username string, fromCurrency string) (db.RateQuote, bool) {
+	id, err := uuid.Parse(quoteID)
+	if err != nil {
+		writeError(ctx, apierror.Validation("quote_id", "uuid", "must be a valid UUID"))
+		return db.RateQuote{}, false
+	}
+
+	quote, err := s.store.GetRateQuote(ctx, id)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.RateQuote))
+		return quote, false
+	}
+
+	if quote.Username != username {
+		writeError(ctx, apierror.New(apierror.CodeRateQuoteNotOwned, "rate quote doesn't belong to authenticated user"))
+		return quote, false
+	}
+
+	if quote.FromCurrency != fromCurrency {
+		detail := fmt.Sprintf("rate quote currency mismatch: %s vs %s", quote.FromCurrency, fromCurrency)
+		writeError(ctx, apierror.New(apierror.CodeRateQuoteMismatch, detail))
+		return quote, false
+	}
+
+	return quote, true
+}
@@ -1,18 +1,24 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/apierror"
 	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/mail"
+	"github.com/mrohadi/simplebank/token"
 	"github.com/mrohadi/simplebank/utils"
 )
 
 type createUserParams struct {
-	Username string `json:"username" binding:"required,alphanum"`
+	Username string `json:"username" binding:"required,alphanum,username"`
 	Password string `json:"password" binding:"required,min=6"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
@@ -22,8 +28,11 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	IsTotpEnabled     bool      `json:"is_totp_enabled"`
 }
 
 func newUserResponse(user db.User) userResponse {
@@ -31,48 +40,174 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
+		IsEmailVerified:   user.IsEmailVerified,
+		IsTotpEnabled:     user.IsTotpEnabled,
 	}
 }
 
-// createUser handle create account
+// createUser handle create account.
+// The verification email is sent in the background, the user cannot move money until the address is verified.
 func (s *Server) createUser(ctx *gin.Context) {
 	var req createUserParams
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, apierror.Internal(err))
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	result, err := s.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		ExpiresAt: time.Now().Add(s.verifyEmailDuration()),
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
 	}
 
-	user, err := s.store.CreateUser(ctx, arg)
+	s.dispatcher.Notify()
+
+	rsp := newUserResponse(result.User)
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// getCurrentUser handle reading the profile of the authorized user
+func (s *Server) getCurrentUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.GetUser(ctx, authPayload.Username)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateCurrentUserRequest struct {
+	FullName *string `json:"full_name" binding:"required_without=Email,omitempty,min=1"`
+	Email    *string `json:"email" binding:"required_without=FullName,omitempty,email"`
+	// CurrentPassword and TOTPCode are only required to change the email address
+	CurrentPassword string `json:"current_password" binding:"required_with=Email"`
+	TOTPCode        string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+// updateCurrentUser handle changing the profile of the authorized user, the fields left out are unchanged.
+// A new email address must be verified again before the user can move money, and the previous address is told about the change.
+func (s *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{Username: authPayload.Username},
+	}
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+	if req.Email != nil {
+		// the password reset goes to the new address, a stolen access token alone must not be enough to change it
+		if apiErr := s.checkEmailChange(ctx, authPayload.Username, req.CurrentPassword, req.TOTPCode); apiErr != nil {
+			writeError(ctx, apiErr)
+			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+		arg.ExpiresAt = time.Now().Add(s.verifyEmailDuration())
+	}
+
+	result, err := s.store.UpdateUserTx(ctx, arg)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.UserProfile))
 		return
 	}
 
-	rsp := newUserResponse(user)
-	ctx.JSON(http.StatusCreated, rsp)
+	if result.EmailChanged {
+		s.dispatcher.Notify()
+		if !s.dispatcher.Enqueue(emailChangedMessage(result.User, result.PreviousEmail)) {
+			slog.ErrorContext(ctx, "cannot queue email changed notice", slog.String("username", result.User.Username))
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+// checkEmailChange verifies the password of the user, and the one-time code when two-factor authentication is enabled.
+// Wrong credentials count toward the lockout of the user.
+func (s *Server) checkEmailChange(ctx context.Context, username string, password string, code string) *apierror.Error {
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		return apierror.FromDB(err, apierror.User)
+	}
+
+	user, apiErr := s.checkPassword(ctx, user, password, apierror.New(apierror.CodeInvalidCredentials, "incorrect current password"))
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if !user.IsTotpEnabled {
+		return nil
+	}
+
+	if len(code) == 0 {
+		return apierror.New(apierror.CodeMFARequired, "changing the email address requires a one-time code")
+	}
+
+	if apiErr := s.verifyTOTP(ctx, user, code); apiErr != nil {
+		if apiErr.Code != apierror.CodeMFACodeInvalid {
+			return apiErr
+		}
+		_, apiErr = s.recordFailedLogin(ctx, user, time.Now(), apiErr)
+		return apiErr
+	}
+
+	return nil
+}
+
+func emailChangedMessage(user db.User, previousEmail string) mail.Message {
+	return mail.Message{
+		To:      previousEmail,
+		Subject: "Your Simple Bank email address was changed",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"The email address of your account was changed to %s.\n\n"+
+			"If you didn't make this change, contact us right away to secure your account.\n",
+			user.FullName, user.Email),
+	}
+}
+
+type getUserUri struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// getUser handle reading the profile of any user by an admin
+func (s *Server) getUser(ctx *gin.Context) {
+	var uri getUserUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	user, err := s.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
 
 type loginUserParams struct {
@@ -81,43 +216,213 @@ type loginUserParams struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
+// loginUser handle checking the password of a user.
+// A user with two-factor authentication gets a challenge token to send along with a one-time code to loginUserMFA,
+// the other users get their session right away.
 func (s *Server) loginUser(ctx *gin.Context) {
 	var req loginUserParams
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
-	user, err := s.store.GetUser(ctx, req.Username)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+	user, apiErr := s.authenticateUser(ctx, req.Username, req.Password)
+	if apiErr != nil {
+		writeError(ctx, apiErr)
+		return
+	}
+
+	if user.IsTotpEnabled {
+		mfaToken, challenge, err := s.newMFAChallenge(ctx, user.Username)
+		if err != nil {
+			writeError(ctx, apierror.Internal(err))
 			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		rsp := mfaChallengeResponse{
+			MFAToken:          mfaToken,
+			MFATokenExpiresAt: challenge.ExpiresAt,
+		}
+		ctx.JSON(http.StatusAccepted, rsp)
 		return
 	}
 
-	err = utils.CheckPassword(req.Password, user.HashedPassword)
+	login, err := s.createLoginSession(ctx, user, ctx.Request.UserAgent(), ctx.ClientIP())
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(ctx, apierror.Internal(err))
 		return
 	}
 
-	accessToken, err := s.tokenMaker.CreateToken(user.Username, s.config.AccessTokenDuration)
+	ctx.JSON(http.StatusOK, newLoginUserResponse(login, user))
+}
+
+// loginSession holds the tokens issued to a user once it is fully authenticated
+type loginSession struct {
+	session        db.Session
+	accessToken    string
+	accessPayload  *token.Payload
+	refreshToken   string
+	refreshPayload *token.Payload
+}
+
+// createLoginSession issues the access and refresh tokens of a user, both tokens are tracked by a new session
+func (s *Server) createLoginSession(ctx context.Context, user db.User, userAgent string, clientIP string) (loginSession, error) {
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, s.config.AccessTokenDuration)
+	if err != nil {
+		return loginSession{}, err
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, s.config.RefreshTokenDuration)
+	if err != nil {
+		return loginSession{}, err
+	}
+
+	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:        refreshPayload.ID,
+		Username:  user.Username,
+		UserAgent: userAgent,
+		ClientIp:  clientIP,
+		IsBlocked: false,
+		ExpiresAt: refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return loginSession{}, err
+	}
+
+	_, err = s.store.CreateSessionAccessToken(ctx, db.CreateSessionAccessTokenParams{
+		ID:        accessPayload.ID,
+		ExpiresAt: accessPayload.ExpiredAt,
+		SessionID: session.ID,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return loginSession{}, err
+	}
+
+	return loginSession{
+		session:        session,
+		accessToken:    accessToken,
+		accessPayload:  accessPayload,
+		refreshToken:   refreshToken,
+		refreshPayload: refreshPayload,
+	}, nil
+}
+
+func newLoginUserResponse(login loginSession, user db.User) loginUserResponse {
+	return loginUserResponse{
+		SessionID:             login.session.ID,
+		AccessToken:           login.accessToken,
+		AccessTokenExpiresAt:  login.accessPayload.ExpiredAt,
+		RefreshToken:          login.refreshToken,
+		RefreshTokenExpiresAt: login.refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	}
+}
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type logoutUserResponse struct {
+	RevokedSessions int `json:"revoked_sessions"`
+}
+
+// logoutUser handle revoking the current session and access token
+func (s *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
 		return
 	}
 
-	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		writeError(ctx, apierror.FromToken(err))
+		return
 	}
+
+	if refreshPayload.Username != authPayload.Username {
+		writeError(ctx, apierror.New(apierror.CodeSessionNotOwned, "refresh token doesn't belong to the authorized user"))
+		return
+	}
+
+	result, err := s.store.LogoutTx(ctx, db.LogoutTxParams{
+		Username:             authPayload.Username,
+		SessionID:            refreshPayload.ID,
+		AccessTokenID:        authPayload.ID,
+		AccessTokenExpiresAt: authPayload.ExpiredAt,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.Session))
+		return
+	}
+
+	s.revocations.Add(result.RevokedTokens...)
+
+	rsp := logoutUserResponse{RevokedSessions: len(result.Sessions)}
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+// logoutAllSessions handle revoking every session of the authorized user
+func (s *Server) logoutAllSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := s.store.LogoutAllTx(ctx, db.LogoutTxParams{
+		Username:             authPayload.Username,
+		AccessTokenID:        authPayload.ID,
+		AccessTokenExpiresAt: authPayload.ExpiredAt,
+	})
+	if err != nil {
+		writeError(ctx, apierror.Internal(err))
+		return
+	}
+
+	s.revocations.Add(result.RevokedTokens...)
+
+	rsp := logoutUserResponse{RevokedSessions: len(result.Sessions)}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateUserRoleUri struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+// updateUserRole handle changing the role of a user by an admin.
+// Every session of the user is blocked and its tokens revoked so the new role applies from the next login.
+func (s *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	result, err := s.store.ChangeUserRoleTx(ctx, db.ChangeUserRoleTxParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.User))
+		return
+	}
+
+	s.revocations.Add(result.RevokedTokens...)
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
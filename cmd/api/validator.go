@@ -1,7 +1,10 @@
 package api
 
 import (
+	"strings"
+
 	"github.com/go-playground/validator/v10"
+	db "github.com/mrohadi/simplebank/db/sqlc"
 	"github.com/mrohadi/simplebank/utils"
 )
 
@@ -13,3 +16,20 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 
 	return false
 }
+
+var validRole validator.Func = func(fl validator.FieldLevel) bool {
+	if role, ok := fl.Field().Interface().(string); ok {
+		return utils.IsSupportedRole(role)
+	}
+
+	return false
+}
+
+// validUsername rejects the username of the settlement accounts in any case, a customer must not pass for it
+var validUsername validator.Func = func(fl validator.FieldLevel) bool {
+	if username, ok := fl.Field().Interface().(string); ok {
+		return !strings.EqualFold(username, db.SystemAccountOwner)
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/token"
+)
+
+const (
+	defaultVerifyEmailDuration = 24 * time.Hour
+	verifyEmailSecretCodeSize  = 32
+)
+
+type verifyEmailRequest struct {
+	ID         int64  `form:"id" binding:"required,min=1"`
+	SecretCode string `form:"code" binding:"required"`
+}
+
+// verifyEmail handle confirming the email address of a user with the code mailed on sign up.
+// It is reached from the link of the email, so it doesn't require the user to be logged in.
+func (s *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	result, err := s.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		ID:             req.ID,
+		SecretCodeHash: hashSecret(req.SecretCode),
+	})
+	if err != nil {
+		writeError(ctx, apierror.FromDB(err, apierror.VerifyEmail))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+// requireVerifiedEmail aborts the request unless the authorized user has verified its email address.
+// It must run after authMiddleware.
+func (s *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if apiErr := s.checkEmailVerified(ctx, authPayload.Username); apiErr != nil {
+			writeError(ctx, apiErr)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// checkEmailVerified returns an error unless the user has verified its email address, unverified users cannot move money
+func (s *Server) checkEmailVerified(ctx context.Context, username string) *apierror.Error {
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		return apierror.FromDB(err, apierror.User)
+	}
+
+	if !user.IsEmailVerified {
+		return apierror.New(apierror.CodeEmailNotVerified, "the email address must be verified before moving money")
+	}
+
+	return nil
+}
+
+func (s *Server) verifyEmailDuration() time.Duration {
+	if s.config.VerifyEmailDuration <= 0 {
+		return defaultVerifyEmailDuration
+	}
+
+	return s.config.VerifyEmailDuration
+}
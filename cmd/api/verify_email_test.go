@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrohadi/simplebank/apierror"
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/mrohadi/simplebank/pb"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+)
+
+// allowVerifiedEmail lets the authorized user through requireVerifiedEmail
+func allowVerifiedEmail(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ any, username string) (db.User, error) {
+			return db.User{Username: username, Role: utils.DepositorRole, IsEmailVerified: true}, nil
+		})
+}
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	verifiedUser := user
+	verifiedUser.IsEmailVerified = true
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "id=7&code=secret",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(db.VerifyEmailTxParams{ID: 7, SecretCodeHash: hashSecret("secret")})).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: verifiedUser}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, user.Username, rsp.Username)
+				require.True(t, rsp.IsEmailVerified)
+			},
+		},
+		{
+			name:  "InvalidCode",
+			query: "id=7&code=wrong",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, db.ErrVerifyEmailInvalid)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeVerifyEmailInvalid)
+			},
+		},
+		{
+			name:  "Expired",
+			query: "id=7&code=secret",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, db.ErrVerifyEmailExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeVerifyEmailExpired)
+			},
+		},
+		{
+			name:  "AlreadyUsed",
+			query: "id=7&code=secret",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, db.ErrVerifyEmailUsed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeVerifyEmailUsed)
+			},
+		},
+		{
+			name:  "NotFound",
+			query: "id=7&code=secret",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, apierror.CodeVerifyEmailNotFound)
+			},
+		},
+		{
+			name:  "MissingCode",
+			query: "id=7",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, apierror.CodeValidationFailed)
+				require.Equal(t, "code", problem.Errors[0].Field)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/verify_email?"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateUserNotifiesDispatcher(t *testing.T) {
+	user, password := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateUserTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.CreateUserTxResult{User: user}, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"username":  user.Username,
+		"password":  password,
+		"full_name": user.FullName,
+		"email":     user.Email,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	// the email is not sent by the request, the dispatcher is woken up to send it
+	select {
+	case <-server.dispatcher.wake:
+	default:
+		t.Fatal("the dispatcher was not notified")
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+
+	send := func(url string, body gin.H) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	transfer := gin.H{
+		"from_account_id": account.ID,
+		"to_account_id":   account.ID + 1,
+		"amount":          10,
+		"currency":        account.Currency,
+	}
+	requireProblem(t, send("/transfers", transfer), apierror.CodeEmailNotVerified)
+	requireProblem(t, send("/standing_orders", transfer), apierror.CodeEmailNotVerified)
+	requireProblem(t, send("/transfers/1/reversal", gin.H{}), apierror.CodeEmailNotVerified)
+
+	client := newTestGRPCClient(t, server)
+	ctx := newGRPCAuthContext(t, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	_, err := client.CreateTransfer(ctx, &pb.CreateTransferRequest{
+		FromAccountId: account.ID,
+		ToAccountId:   account.ID + 1,
+		Amount:        10,
+		Currency:      account.Currency,
+	})
+	requireGRPCCode(t, err, codes.PermissionDenied)
+}
+
+func TestVerifyEmailMessage(t *testing.T) {
+	dispatcher := newMailDispatcher(nil, nil, "https://bank.example/", 0)
+	email := db.VerifyEmail{ID: 7, Username: "alice", Email: "alice@example.com", ExpiresAt: time.Now()}
+
+	msg := dispatcher.message(email, "a+b")
+	require.Equal(t, email.Email, msg.To)
+	require.Contains(t, msg.Body, fmt.Sprintf("https://bank.example/users/verify_email?code=a%%2Bb&id=%d", email.ID))
+}
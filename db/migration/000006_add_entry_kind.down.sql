@@ -0,0 +1,12 @@
+DELETE FROM "transfers" WHERE "from_account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = 'system')
+OR "to_account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = 'system');
+
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = 'system');
+
+DELETE FROM "accounts" WHERE "owner" = 'system';
+
+DELETE FROM "users" WHERE "username" = 'system';
+
+ALTER TABLE IF EXISTS "entries" DROP CONSTRAINT IF EXISTS "entries_kind_check";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "kind";
@@ -0,0 +1,21 @@
+ALTER TABLE "entries" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'transfer';
+
+ALTER TABLE "entries" ADD CONSTRAINT "entries_kind_check" CHECK ("kind" IN ('transfer', 'deposit', 'withdrawal'));
+
+COMMENT ON COLUMN "entries"."kind" IS 'transfer, deposit or withdrawal';
+
+-- settlement accounts representing cash entering and leaving the bank, one per currency.
+-- A customer already registered as system would own them, the migration stops instead.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "users" WHERE "username" = 'system' OR "email" = 'system@simplebank.local') THEN
+    RAISE EXCEPTION 'the username "system" is reserved for the settlement accounts, rename the user registered with it before migrating';
+  END IF;
+END $$;
+
+-- the empty password is not a bcrypt hash, no password matches it so the user cannot log in
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email")
+VALUES ('system', '', 'Settlement', 'system@simplebank.local');
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+VALUES ('system', 0, 'USD'), ('system', 0, 'EUR'), ('system', 0, 'CAD');
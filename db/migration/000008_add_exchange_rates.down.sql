@@ -0,0 +1,11 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "quote_id";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "spread_bps";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "exchange_rate";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "converted_amount";
+
+DROP TABLE IF EXISTS "rate_quotes";
+
+DROP TABLE IF EXISTS "exchange_rates";
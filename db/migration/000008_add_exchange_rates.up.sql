@@ -0,0 +1,59 @@
+CREATE TABLE "exchange_rates" (
+  "id" bigserial PRIMARY KEY,
+  "base_currency" varchar NOT NULL,
+  "quote_currency" varchar NOT NULL,
+  "rate" numeric(20, 10) NOT NULL,
+  "spread_bps" integer NOT NULL DEFAULT 0,
+  "effective_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "exchange_rates" ADD CONSTRAINT "exchange_rates_rate_check" CHECK ("rate" > 0);
+
+ALTER TABLE "exchange_rates" ADD CONSTRAINT "exchange_rates_spread_bps_check" CHECK ("spread_bps" >= 0 AND "spread_bps" < 10000);
+
+CREATE INDEX ON "exchange_rates" ("base_currency", "quote_currency", "effective_at");
+
+COMMENT ON COLUMN "exchange_rates"."rate" IS 'units of quote currency for one unit of base currency';
+
+COMMENT ON COLUMN "exchange_rates"."spread_bps" IS 'margin taken by the bank in basis points';
+
+CREATE TABLE "rate_quotes" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" numeric(20, 10) NOT NULL,
+  "spread_bps" integer NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "rate_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "rate_quotes" ("username");
+
+COMMENT ON COLUMN "rate_quotes"."used_at" IS 'set once the quote has been applied to a transfer';
+
+ALTER TABLE "transfers" ADD COLUMN "converted_amount" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric(20, 10);
+
+ALTER TABLE "transfers" ADD COLUMN "spread_bps" integer;
+
+ALTER TABLE "transfers" ADD COLUMN "quote_id" uuid;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("quote_id") REFERENCES "rate_quotes" ("id");
+
+COMMENT ON COLUMN "transfers"."converted_amount" IS 'amount credited in the destination currency, null when no conversion took place';
+
+-- initial rates, replaced by inserting rows with a later effective_at
+INSERT INTO "exchange_rates" ("base_currency", "quote_currency", "rate", "spread_bps")
+VALUES
+  ('USD', 'EUR', 0.92, 50),
+  ('EUR', 'USD', 1.087, 50),
+  ('USD', 'CAD', 1.36, 50),
+  ('CAD', 'USD', 0.735, 50),
+  ('EUR', 'CAD', 1.478, 50),
+  ('CAD', 'EUR', 0.6766, 50);
@@ -0,0 +1,69 @@
+CREATE TABLE "standing_orders" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "frequency" varchar NOT NULL,
+  "day_of_month" integer,
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "next_run_at" timestamptz,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "failure_count" integer NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD CONSTRAINT "standing_orders_amount_check" CHECK ("amount" > 0);
+
+ALTER TABLE "standing_orders" ADD CONSTRAINT "standing_orders_frequency_check" CHECK ("frequency" IN ('once', 'daily', 'weekly', 'monthly'));
+
+ALTER TABLE "standing_orders" ADD CONSTRAINT "standing_orders_day_of_month_check" CHECK (
+  ("frequency" = 'monthly' AND "day_of_month" BETWEEN 1 AND 31)
+  OR ("frequency" <> 'monthly' AND "day_of_month" IS NULL)
+);
+
+ALTER TABLE "standing_orders" ADD CONSTRAINT "standing_orders_status_check" CHECK ("status" IN ('active', 'suspended', 'completed', 'cancelled'));
+
+CREATE INDEX ON "standing_orders" ("owner");
+
+CREATE INDEX ON "standing_orders" ("next_run_at") WHERE "status" = 'active';
+
+COMMENT ON COLUMN "standing_orders"."frequency" IS 'once, daily, weekly or monthly';
+
+COMMENT ON COLUMN "standing_orders"."day_of_month" IS 'day of the month for monthly orders, clamped to the last day of shorter months';
+
+COMMENT ON COLUMN "standing_orders"."next_run_at" IS 'null once the order has no more runs';
+
+COMMENT ON COLUMN "standing_orders"."status" IS 'active, suspended, completed or cancelled';
+
+COMMENT ON COLUMN "standing_orders"."failure_count" IS 'consecutive runs failed for insufficient funds';
+
+CREATE TABLE "standing_order_runs" (
+  "id" bigserial PRIMARY KEY,
+  "standing_order_id" bigint NOT NULL,
+  "scheduled_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "error" varchar,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "finished_at" timestamptz
+);
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("standing_order_id") REFERENCES "standing_orders" ("id");
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "standing_order_runs" ADD CONSTRAINT "standing_order_runs_status_check" CHECK ("status" IN ('pending', 'succeeded', 'failed'));
+
+CREATE INDEX ON "standing_order_runs" ("standing_order_id", "id");
+
+COMMENT ON COLUMN "standing_order_runs"."status" IS 'pending, succeeded or failed';
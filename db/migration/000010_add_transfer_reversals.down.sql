@@ -0,0 +1,9 @@
+ALTER TABLE "entries" DROP CONSTRAINT "entries_kind_check";
+
+ALTER TABLE "entries" ADD CONSTRAINT "entries_kind_check" CHECK ("kind" IN ('transfer', 'deposit', 'withdrawal'));
+
+COMMENT ON COLUMN "entries"."kind" IS 'transfer, deposit or withdrawal';
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reversed_amount";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reverses_transfer_id";
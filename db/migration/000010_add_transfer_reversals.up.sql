@@ -0,0 +1,17 @@
+ALTER TABLE "transfers" ADD COLUMN "reverses_transfer_id" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "reversed_amount" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reverses_transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfers" ("reverses_transfer_id");
+
+COMMENT ON COLUMN "transfers"."reverses_transfer_id" IS 'original transfer compensated by this one';
+
+COMMENT ON COLUMN "transfers"."reversed_amount" IS 'sum of the reversals of this transfer, in the destination currency';
+
+ALTER TABLE "entries" DROP CONSTRAINT "entries_kind_check";
+
+ALTER TABLE "entries" ADD CONSTRAINT "entries_kind_check" CHECK ("kind" IN ('transfer', 'deposit', 'withdrawal', 'reversal'));
+
+COMMENT ON COLUMN "entries"."kind" IS 'transfer, deposit, withdrawal or reversal';
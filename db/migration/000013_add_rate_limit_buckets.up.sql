@@ -0,0 +1,12 @@
+CREATE TABLE "rate_limit_buckets" (
+  "key" varchar PRIMARY KEY,
+  "tokens" double precision NOT NULL,
+  "refilled_at" timestamptz NOT NULL DEFAULT (now()),
+  "expires_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "rate_limit_buckets" ("expires_at");
+
+COMMENT ON COLUMN "rate_limit_buckets"."key" IS 'route group and client of the bucket';
+
+COMMENT ON COLUMN "rate_limit_buckets"."expires_at" IS 'the bucket is full again from then on and can be dropped';
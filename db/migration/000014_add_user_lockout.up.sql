@@ -0,0 +1,11 @@
+ALTER TABLE "users" ADD COLUMN "failed_login_attempts" integer NOT NULL DEFAULT 0;
+
+ALTER TABLE "users" ADD COLUMN "last_failed_login_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z';
+
+ALTER TABLE "users" ADD COLUMN "lockouts" integer NOT NULL DEFAULT 0;
+
+ALTER TABLE "users" ADD COLUMN "locked_until" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z';
+
+COMMENT ON COLUMN "users"."failed_login_attempts" IS 'failed logins since the last success, lockout or end of the attempt window';
+
+COMMENT ON COLUMN "users"."lockouts" IS 'lockouts since the last successful login, each one lasts twice as long as the previous one';
@@ -0,0 +1,25 @@
+CREATE TABLE "verify_emails" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "secret_code" varchar NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "send_attempts" integer NOT NULL DEFAULT 0,
+  "next_send_at" timestamptz DEFAULT (now()),
+  "sent_at" timestamptz,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "verify_emails" ("username");
+
+CREATE INDEX ON "verify_emails" ("next_send_at");
+
+COMMENT ON COLUMN "verify_emails"."next_send_at" IS 'when the email is due to be sent, null once it is sent or given up';
+
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT false;
+
+-- the users created before the verification existed keep moving money
+UPDATE "users" SET "is_email_verified" = true;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "mfa_challenges";
+
+DROP TABLE IF EXISTS "recovery_codes";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "totp_last_used_step";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_totp_enabled";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "totp_secret";
@@ -0,0 +1,38 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "users" ADD COLUMN "is_totp_enabled" boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "users" ADD COLUMN "totp_last_used_step" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'base32 encoded TOTP secret, set when the enrollment starts and only used once it is confirmed';
+
+COMMENT ON COLUMN "users"."totp_last_used_step" IS 'period of the last accepted TOTP code, the codes up to it cannot be replayed';
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'hex encoded SHA-256 of the recovery code, the code itself is only shown to the user once';
+
+CREATE TABLE "mfa_challenges" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "mfa_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "mfa_challenges" ("username");
+
+COMMENT ON COLUMN "mfa_challenges"."token_hash" IS 'hex encoded SHA-256 of the challenge token returned by the password step of the login';
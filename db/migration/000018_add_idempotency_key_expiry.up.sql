@@ -0,0 +1,5 @@
+ALTER TABLE "idempotency_keys" ADD COLUMN "expires_at" timestamptz NOT NULL DEFAULT (now() + interval '24 hours');
+
+CREATE INDEX ON "idempotency_keys" ("expires_at");
+
+COMMENT ON COLUMN "idempotency_keys"."expires_at" IS 'the key can be reused and the row dropped from then on';
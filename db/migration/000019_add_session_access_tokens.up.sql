@@ -0,0 +1,14 @@
+CREATE TABLE "session_access_tokens" (
+  "id" uuid PRIMARY KEY,
+  "session_id" uuid NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "session_access_tokens" ADD FOREIGN KEY ("session_id") REFERENCES "sessions" ("id");
+
+CREATE INDEX ON "session_access_tokens" ("session_id");
+
+CREATE INDEX ON "session_access_tokens" ("expires_at");
+
+COMMENT ON COLUMN "session_access_tokens"."id" IS 'access token payload id';
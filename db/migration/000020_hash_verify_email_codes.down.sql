@@ -0,0 +1,4 @@
+ALTER TABLE "verify_emails" ALTER COLUMN "secret_code_hash" DROP DEFAULT;
+
+-- the mailed codes cannot be recovered from their hash, the pending verifications have to be requested again
+ALTER TABLE "verify_emails" RENAME COLUMN "secret_code_hash" TO "secret_code";
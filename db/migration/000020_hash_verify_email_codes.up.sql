@@ -0,0 +1,8 @@
+ALTER TABLE "verify_emails" RENAME COLUMN "secret_code" TO "secret_code_hash";
+
+-- the codes already mailed keep working
+UPDATE "verify_emails" SET "secret_code_hash" = encode(sha256(convert_to("secret_code_hash", 'UTF8')), 'hex');
+
+ALTER TABLE "verify_emails" ALTER COLUMN "secret_code_hash" SET DEFAULT '';
+
+COMMENT ON COLUMN "verify_emails"."secret_code_hash" IS 'hex encoded SHA-256 of the code mailed by the last send, empty until the email is sent';
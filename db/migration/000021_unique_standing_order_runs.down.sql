@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "standing_order_runs_standing_order_id_scheduled_at_idx";
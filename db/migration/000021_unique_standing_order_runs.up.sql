@@ -0,0 +1 @@
+CREATE UNIQUE INDEX ON "standing_order_runs" ("standing_order_id", "scheduled_at");
@@ -17,6 +17,11 @@ SELECT * FROM accounts
 WHERE id = $1
 FOR NO KEY UPDATE;
 
+-- name: GetAccountByOwner :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 AND status <> 'closed'
+LIMIT 1;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -36,6 +41,12 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+SET status = $2
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  kind
 ) VALUES (
-  $1, $2
+  $1, $2, $3
 )
 RETURNING *;
 
@@ -17,6 +18,25 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountEntries :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+AND (
+  sqlc.narg(direction)::varchar IS NULL
+  OR (sqlc.narg(direction) = 'in' AND amount > 0)
+  OR (sqlc.narg(direction) = 'out' AND amount < 0)
+)
+AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount))
+AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount))
+AND (
+  sqlc.narg(cursor_created_at)::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
 -- name: UpdateEntry :one
 UPDATE entries
 SET amount = $2
@@ -0,0 +1,19 @@
+-- name: CreateExchangeRate :one
+INSERT INTO exchange_rates (
+  base_currency,
+  quote_currency,
+  rate,
+  spread_bps,
+  effective_at
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetExchangeRate :one
+SELECT * FROM exchange_rates
+WHERE base_currency = sqlc.arg(base_currency)
+AND quote_currency = sqlc.arg(quote_currency)
+AND effective_at <= sqlc.arg(effective_at)
+ORDER BY effective_at DESC, id DESC
+LIMIT 1;
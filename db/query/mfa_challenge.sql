@@ -0,0 +1,21 @@
+-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (
+  username,
+  token_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: UseMFAChallenge :one
+-- No row is returned when the challenge was already used.
+UPDATE mfa_challenges
+SET used_at = now()
+WHERE id = $1
+AND used_at IS NULL
+RETURNING *;
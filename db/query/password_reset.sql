@@ -0,0 +1,20 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+  username,
+  token_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetPasswordResetForUpdate :one
+SELECT * FROM password_resets
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UsePasswordResets :exec
+UPDATE password_resets
+SET used_at = now()
+WHERE username = $1
+AND used_at IS NULL;
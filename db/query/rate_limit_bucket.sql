@@ -0,0 +1,26 @@
+-- name: TakeRateLimitToken :one
+-- refills the bucket of the key at rate tokens per second up to capacity tokens, then takes one token from it.
+-- No row is returned when the bucket holds less than a token.
+INSERT INTO rate_limit_buckets (
+  key,
+  tokens,
+  refilled_at,
+  expires_at
+) VALUES (
+  sqlc.arg(key), sqlc.arg(capacity)::float8 - 1, now(), now() + make_interval(secs => 1 / sqlc.arg(rate)::float8)
+)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.refilled_at)::float8 * sqlc.arg(rate)::float8) - 1,
+    refilled_at = now(),
+    expires_at = now() + make_interval(secs => (sqlc.arg(capacity)::float8 - LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.refilled_at)::float8 * sqlc.arg(rate)::float8) + 1) / sqlc.arg(rate)::float8)
+WHERE LEAST(sqlc.arg(capacity)::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.refilled_at)::float8 * sqlc.arg(rate)::float8) >= 1
+RETURNING *;
+
+-- name: GetRateLimitBucket :one
+SELECT * FROM rate_limit_buckets
+WHERE key = $1
+LIMIT 1;
+
+-- name: DeleteExpiredRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE expires_at < now();
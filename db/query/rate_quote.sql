@@ -0,0 +1,28 @@
+-- name: CreateRateQuote :one
+INSERT INTO rate_quotes (
+  id,
+  username,
+  from_currency,
+  to_currency,
+  rate,
+  spread_bps,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetRateQuote :one
+SELECT * FROM rate_quotes
+WHERE id = $1 LIMIT 1;
+
+-- name: GetRateQuoteForUpdate :one
+SELECT * FROM rate_quotes
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkRateQuoteUsed :one
+UPDATE rate_quotes
+SET used_at = now()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,26 @@
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :one
+-- No row is returned when the code doesn't exist or was already used.
+UPDATE recovery_codes
+SET used_at = now()
+WHERE username = $1
+AND code_hash = $2
+AND used_at IS NULL
+RETURNING *;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT count(*) FROM recovery_codes
+WHERE username = $1
+AND used_at IS NULL;
@@ -0,0 +1,21 @@
+-- name: CreateRevokedToken :one
+INSERT INTO revoked_tokens (
+  id,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (id) DO UPDATE
+SET expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListRevokedTokens :many
+SELECT * FROM revoked_tokens
+WHERE revoked_at > sqlc.arg(revoked_after)
+AND expires_at > now()
+ORDER BY revoked_at;
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at <= now();
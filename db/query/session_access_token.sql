@@ -0,0 +1,23 @@
+-- name: CreateSessionAccessToken :one
+-- the session row is locked so the token cannot be recorded after a concurrent transaction blocked the session
+INSERT INTO session_access_tokens (
+  id,
+  session_id,
+  expires_at
+)
+SELECT sqlc.arg(id), sessions.id, sqlc.arg(expires_at)
+FROM sessions
+WHERE sessions.id = sqlc.arg(session_id)
+AND sessions.is_blocked = false
+FOR UPDATE
+RETURNING *;
+
+-- name: ListSessionAccessTokens :many
+SELECT * FROM session_access_tokens
+WHERE session_id = $1
+AND expires_at > now()
+ORDER BY created_at;
+
+-- name: DeleteExpiredSessionAccessTokens :exec
+DELETE FROM session_access_tokens
+WHERE expires_at <= now();
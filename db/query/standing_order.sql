@@ -0,0 +1,97 @@
+-- name: CreateStandingOrder :one
+INSERT INTO standing_orders (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  frequency,
+  day_of_month,
+  start_at,
+  end_at,
+  next_run_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: GetStandingOrder :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1;
+
+-- name: GetStandingOrderForUpdate :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListStandingOrders :many
+SELECT * FROM standing_orders
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateStandingOrder :one
+UPDATE standing_orders
+SET
+  amount = $2,
+  end_at = $3,
+  status = $4,
+  next_run_at = $5,
+  failure_count = $6,
+  updated_at = now()
+WHERE id = $1
+AND updated_at = $7
+RETURNING *;
+
+-- name: ScheduleStandingOrder :one
+UPDATE standing_orders
+SET
+  status = $2,
+  next_run_at = $3,
+  failure_count = $4,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ClaimDueStandingOrders :many
+SELECT * FROM standing_orders
+WHERE status = 'active'
+AND next_run_at <= sqlc.arg(now)
+ORDER BY next_run_at
+LIMIT sqlc.arg(batch_size)
+FOR UPDATE SKIP LOCKED;
+
+-- name: CreateStandingOrderRun :one
+INSERT INTO standing_order_runs (
+  standing_order_id,
+  scheduled_at
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: GetPendingStandingOrderRun :one
+SELECT * FROM standing_order_runs
+WHERE standing_order_id = $1
+AND status = 'pending'
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: FinishStandingOrderRun :one
+UPDATE standing_order_runs
+SET
+  status = $2,
+  transfer_id = $3,
+  error = $4,
+  finished_at = now()
+WHERE id = $1
+AND status = 'pending'
+RETURNING *;
+
+-- name: ListStandingOrderRuns :many
+SELECT * FROM standing_order_runs
+WHERE standing_order_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -2,9 +2,14 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  converted_amount,
+  exchange_rate,
+  spread_bps,
+  quote_id,
+  reverses_transfer_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7, $8
 )
 RETURNING *;
 
@@ -12,12 +17,65 @@ RETURNING *;
 SELECT * FROM transfers
 WHERE id = $1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1
+FOR NO KEY UPDATE;
+
+-- name: AddTransferReversedAmount :one
+UPDATE transfers
+SET reversed_amount = reversed_amount + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountTransfers :many
+-- Each direction is read from its own (account, created_at, id) index up to the page limit,
+-- so a page never sorts more than twice its size however many transfers the account has.
+SELECT * FROM (
+  (
+    SELECT * FROM transfers
+    WHERE from_account_id = sqlc.arg(account_id)
+    AND (sqlc.narg(direction)::varchar IS NULL OR sqlc.narg(direction) = 'out')
+    AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR amount >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR amount <= sqlc.narg(max_amount))
+    AND (
+      sqlc.narg(cursor_created_at)::timestamp IS NULL
+      OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint)
+    )
+    ORDER BY created_at DESC, id DESC
+    LIMIT sqlc.arg(page_limit)
+  )
+  UNION ALL
+  (
+    -- the amount credited to the account is compared, converted in its currency
+    SELECT * FROM transfers
+    WHERE to_account_id = sqlc.arg(account_id)
+    AND (sqlc.narg(direction)::varchar IS NULL OR sqlc.narg(direction) = 'in')
+    -- a transfer to the same account is already listed as outgoing
+    AND (sqlc.narg(direction)::varchar IS NOT NULL OR from_account_id <> sqlc.arg(account_id))
+    AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR COALESCE(converted_amount, amount) >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR COALESCE(converted_amount, amount) <= sqlc.narg(max_amount))
+    AND (
+      sqlc.narg(cursor_created_at)::timestamp IS NULL
+      OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint)
+    )
+    ORDER BY created_at DESC, id DESC
+    LIMIT sqlc.arg(page_limit)
+  )
+) AS account_transfers
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
 -- name: UpdateTransfer :one
 UPDATE transfers 
 SET amount = $2
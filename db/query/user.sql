@@ -12,3 +12,91 @@ RETURNING *;
 -- name: GetUser :one
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUser :one
+-- updates the profile of a user, the fields given as NULL are left unchanged
+UPDATE users
+SET
+  full_name = COALESCE(sqlc.narg(full_name), full_name),
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateUserLoginAttempts :one
+UPDATE users
+SET failed_login_attempts = $2,
+    last_failed_login_at = $3,
+    lockouts = $4,
+    locked_until = $5
+WHERE username = $1
+RETURNING *;
+
+-- name: ResetUserLoginAttempts :one
+UPDATE users
+SET failed_login_attempts = 0,
+    lockouts = 0,
+    locked_until = '0001-01-01 00:00:00Z'
+WHERE username = $1
+RETURNING *;
+
+-- name: ResetUnlockedUserLoginAttempts :one
+-- No row is returned when the user is locked, a lock set by a concurrent failed login is kept.
+UPDATE users
+SET failed_login_attempts = 0,
+    lockouts = 0
+WHERE username = sqlc.arg(username)
+AND locked_until <= sqlc.arg(now)
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2,
+    password_changed_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserTOTPSecret :one
+UPDATE users
+SET totp_secret = $2,
+    is_totp_enabled = false,
+    totp_last_used_step = 0
+WHERE username = $1
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users
+SET is_totp_enabled = true,
+    totp_last_used_step = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UseUserTOTPStep :one
+-- records the period of an accepted TOTP code.
+-- No row is returned when a code of this period or a later one was already used.
+UPDATE users
+SET totp_last_used_step = $2
+WHERE username = $1
+AND totp_last_used_step < $2
+RETURNING *;
@@ -0,0 +1,57 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT * FROM verify_emails
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1
+RETURNING *;
+
+-- name: ExpireUserVerifyEmails :exec
+-- expires the pending verifications of a user, the codes mailed to a previous address cannot be used anymore
+UPDATE verify_emails
+SET expires_at = now(),
+    next_send_at = NULL
+WHERE username = $1
+AND is_used = false;
+
+-- name: ClaimVerifyEmailsToSend :many
+SELECT * FROM verify_emails
+WHERE next_send_at <= sqlc.arg(now)
+AND is_used = false
+AND expires_at > sqlc.arg(now)
+ORDER BY next_send_at
+LIMIT sqlc.arg(batch_size)
+FOR UPDATE SKIP LOCKED;
+
+-- name: ScheduleVerifyEmailSend :one
+UPDATE verify_emails
+SET next_send_at = $2,
+    send_attempts = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateVerifyEmailSecretCode :one
+UPDATE verify_emails
+SET secret_code_hash = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkVerifyEmailSent :one
+UPDATE verify_emails
+SET sent_at = now(),
+    next_send_at = NULL
+WHERE id = $1
+RETURNING *;
@@ -11,11 +11,15 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, utils.RandomMoney(), utils.RandomCurrency())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64, currency string) Account {
 	user := createRandomUser(t)
 	args := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  utils.RandomMoney(),
-		Currency: utils.RandomCurrency(),
+		Balance:  balance,
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), args)
@@ -25,6 +29,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, args.Owner, account.Owner)
 	require.Equal(t, args.Balance, account.Balance)
 	require.Equal(t, args.Currency, account.Currency)
+	require.Equal(t, AccountStatusActive, account.Status)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -14,6 +14,7 @@ func createRandomEntry(t *testing.T) Entry {
 	arg := CreateEntryParams{
 		AccountID: createRandomAccount(t).ID,
 		Amount:    utils.RandomMoney(),
+		Kind:      EntryKindTransfer,
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -22,6 +23,7 @@ func createRandomEntry(t *testing.T) Entry {
 
 	require.Equal(t, arg.AccountID, entry.AccountID)
 	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, arg.Kind, entry.Kind)
 
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
@@ -92,3 +94,54 @@ func TestListEntry(t *testing.T) {
 		require.NotEmpty(t, entry)
 	}
 }
+
+func TestListAccountEntries(t *testing.T) {
+	account := createRandomAccount(t)
+	for i := 0; i < 6; i++ {
+		amount := int64(10 * (i + 1))
+		if i%2 == 1 {
+			amount = -amount
+		}
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+			Kind:      EntryKindTransfer,
+		})
+		require.NoError(t, err)
+	}
+
+	arg := ListAccountEntriesParams{
+		AccountID: account.ID,
+		PageLimit: 4,
+	}
+	page1, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page1, 4)
+
+	last := page1[len(page1)-1]
+	arg.CursorCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+	arg.CursorID = sql.NullInt64{Int64: last.ID, Valid: true}
+	page2, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page2, 2)
+
+	seen := make(map[int64]bool)
+	for _, entry := range append(page1, page2...) {
+		require.Equal(t, account.ID, entry.AccountID)
+		require.False(t, seen[entry.ID])
+		seen[entry.ID] = true
+	}
+
+	incoming, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: sql.NullString{String: "in", Valid: true},
+		MinAmount: sql.NullInt64{Int64: 20, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, incoming, 2)
+	for _, entry := range incoming {
+		require.Greater(t, entry.Amount, int64(0))
+		require.GreaterOrEqual(t, entry.Amount, int64(20))
+	}
+}
@@ -1,2 +1,65 @@
 package db
 
+import "errors"
+
+// Different type of errors returned by the TransferTx function
+var (
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrCurrencyMismatch  = errors.New("account currency mismatch")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrAccountClosed     = errors.New("account is closed")
+)
+
+// Different type of errors returned by the LogoutTx function
+var (
+	ErrSessionNotOwned = errors.New("session doesn't belong to the user")
+)
+
+// Different type of errors returned by the DepositTx and WithdrawalTx functions
+var (
+	ErrSettlementAccountNotFound = errors.New("settlement account not found")
+)
+
+// Different type of errors returned by the TransferTx function when a rate quote is applied
+var (
+	ErrRateQuoteNotFound       = errors.New("rate quote not found")
+	ErrRateQuoteExpired        = errors.New("rate quote expired")
+	ErrRateQuoteUsed           = errors.New("rate quote already used")
+	ErrRateQuoteMismatch       = errors.New("rate quote doesn't match account currencies")
+	ErrConvertedAmountTooSmall = errors.New("converted amount is too small")
+)
+
+// Different type of errors returned by the ReversalTx function
+var (
+	ErrTransferNotFound        = errors.New("transfer not found")
+	ErrTransferIsReversal      = errors.New("a reversal cannot be reversed")
+	ErrTransferIsCash          = errors.New("a deposit or withdrawal cannot be reversed")
+	ErrTransferAlreadyReversed = errors.New("transfer already fully reversed")
+	ErrReversalExceedsTransfer = errors.New("reversal exceeds the amount left on the transfer")
+)
+
+// Different type of errors returned by the RunStandingOrderTx and FinishStandingOrderRunTx functions
+var (
+	ErrStandingOrderNotActive   = errors.New("standing order is not active")
+	ErrStandingOrderRunFinished = errors.New("standing order run already finished")
+)
+
+// Different type of errors returned by the ChangeAccountStatusTx and CloseAccountTx functions
+var (
+	ErrInvalidStatusTransition = errors.New("invalid account status transition")
+	ErrAccountBalanceNotZero   = errors.New("account balance is not zero")
+)
+
+// Different type of errors returned by the ResetPasswordTx function
+var (
+	ErrPasswordResetExpired = errors.New("password reset token expired")
+	ErrPasswordResetUsed    = errors.New("password reset token already used")
+)
+
+// Different type of errors returned by the VerifyEmailTx function
+var (
+	ErrVerifyEmailInvalid = errors.New("verify email secret code invalid")
+	ErrVerifyEmailExpired = errors.New("verify email expired")
+	ErrVerifyEmailUsed    = errors.New("verify email already used")
+)
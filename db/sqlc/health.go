@@ -0,0 +1,18 @@
+package db
+
+import "context"
+
+// SchemaVersion is the version of the latest migration in db/migration.
+// It must be bumped with every new migration so readiness fails until the database is migrated.
+const SchemaVersion = 21
+
+// Ping verifies the connection to the database is still alive
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the version recorded by golang-migrate and whether its last run failed half-way
+func (s *SQLStore) MigrationVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	err = s.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	return
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	store := NewStore(testDBConn)
+	require.NoError(t, store.Ping(context.Background()))
+}
+
+func TestMigrationVersion(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	version, dirty, err := store.MigrationVersion(context.Background())
+	require.NoError(t, err)
+	require.False(t, dirty)
+	require.Equal(t, int64(SchemaVersion), version)
+}
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	files, err := os.ReadDir("../migration")
+	require.NoError(t, err)
+
+	var latest int64
+	for _, file := range files {
+		prefix, _, found := strings.Cut(file.Name(), "_")
+		require.True(t, found, file.Name())
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		require.NoError(t, err, file.Name())
+		latest = max(latest, version)
+	}
+
+	require.Equal(t, int64(SchemaVersion), latest)
+}
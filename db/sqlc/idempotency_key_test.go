@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomIdempotencyKey(t *testing.T) IdempotencyKey {
+	user := createRandomUser(t)
+	arg := CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.IdempotencyKey, key.IdempotencyKey)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.False(t, key.ResponseStatus.Valid)
+	require.Empty(t, key.ResponseBody)
+	require.NotZero(t, key.CreatedAt)
+	require.WithinDuration(t, arg.ExpiresAt, key.ExpiresAt, time.Second)
+
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	createRandomIdempotencyKey(t)
+}
+
+func TestCreateIdempotencyKeyConflict(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t)
+
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, key2)
+}
+
+func TestCreateIdempotencyKeyExpired(t *testing.T) {
+	user := createRandomUser(t)
+	key1, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpdateIdempotencyKeyResponse(context.Background(), UpdateIdempotencyKeyResponseParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		ResponseStatus: sql.NullInt32{Int32: 201, Valid: true},
+		ResponseBody:   []byte(`{"id":1}`),
+	})
+	require.NoError(t, err)
+
+	arg := CreateIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, key2.RequestHash)
+	require.False(t, key2.ResponseStatus.Valid)
+	require.Empty(t, key2.ResponseBody)
+	require.WithinDuration(t, arg.ExpiresAt, key2.ExpiresAt, time.Second)
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t)
+
+	key2, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key1.Username, key2.Username)
+	require.Equal(t, key1.IdempotencyKey, key2.IdempotencyKey)
+	require.Equal(t, key1.RequestHash, key2.RequestHash)
+	require.WithinDuration(t, key1.CreatedAt, key2.CreatedAt, time.Second)
+}
+
+func TestUpdateIdempotencyKeyResponse(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t)
+
+	arg := UpdateIdempotencyKeyResponseParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		ResponseStatus: sql.NullInt32{Int32: 201, Valid: true},
+		ResponseBody:   []byte(`{"id":1}`),
+	}
+
+	key2, err := testQueries.UpdateIdempotencyKeyResponse(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ResponseStatus, key2.ResponseStatus)
+	require.Equal(t, arg.ResponseBody, key2.ResponseBody)
+	require.Equal(t, key1.RequestHash, key2.RequestHash)
+}
+
+func TestDeleteIdempotencyKey(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t)
+
+	err := testQueries.DeleteIdempotencyKey(context.Background(), DeleteIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.NoError(t, err)
+
+	key2, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, key2)
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	user := createRandomUser(t)
+	expired, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	active := createRandomIdempotencyKey(t)
+
+	err = testQueries.DeleteExpiredIdempotencyKeys(context.Background())
+	require.NoError(t, err)
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       expired.Username,
+		IdempotencyKey: expired.IdempotencyKey,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       active.Username,
+		IdempotencyKey: active.IdempotencyKey,
+	})
+	require.NoError(t, err)
+}
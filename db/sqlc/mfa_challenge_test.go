@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAChallenge(t *testing.T) {
+	user := createRandomUser(t)
+	arg := CreateMFAChallengeParams{
+		Username:  user.Username,
+		TokenHash: utils.RandomString(64),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	challenge, err := testQueries.CreateMFAChallenge(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, challenge.ID)
+	require.Equal(t, arg.Username, challenge.Username)
+	require.WithinDuration(t, arg.ExpiresAt, challenge.ExpiresAt, time.Second)
+	require.False(t, challenge.UsedAt.Valid)
+
+	found, err := testQueries.GetMFAChallenge(context.Background(), arg.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, challenge.ID, found.ID)
+
+	used, err := testQueries.UseMFAChallenge(context.Background(), challenge.ID)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+
+	// a challenge completes a single login
+	_, err = testQueries.UseMFAChallenge(context.Background(), challenge.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
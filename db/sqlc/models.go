@@ -5,7 +5,10 @@
 package db
 
 import (
+	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +17,21 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// active, frozen or closed
+	Status string `json:"status"`
+}
+
+type AccountStatusChange struct {
+	ID         int64  `json:"id"`
+	AccountID  int64  `json:"account_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	// user who requested the transition
+	ChangedBy string `json:"changed_by"`
+	Reason    string `json:"reason"`
+	// transfer moving the remaining balance out of a closed account
+	SweepTransferID sql.NullInt64 `json:"sweep_transfer_id"`
+	CreatedAt       time.Time     `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,6 +40,146 @@ type Entry struct {
 	// can be positive or negative
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// transfer, deposit, withdrawal or reversal
+	Kind string `json:"kind"`
+}
+
+type ExchangeRate struct {
+	ID            int64  `json:"id"`
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+	// units of quote currency for one unit of base currency
+	Rate string `json:"rate"`
+	// margin taken by the bank in basis points
+	SpreadBps   int32     `json:"spread_bps"`
+	EffectiveAt time.Time `json:"effective_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
+	RequestHash    string `json:"request_hash"`
+	// null while the first request is in progress
+	ResponseStatus sql.NullInt32 `json:"response_status"`
+	ResponseBody   []byte        `json:"response_body"`
+	CreatedAt      time.Time     `json:"created_at"`
+	// the key can be reused and the row dropped from then on
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type MfaChallenge struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// hex encoded SHA-256 of the challenge token returned by the password step of the login
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// hex encoded SHA-256 of the reset token, the token itself is only mailed to the user
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// set once the password is changed, the token cannot be used anymore
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RateLimitBucket struct {
+	// route group and client of the bucket
+	Key        string    `json:"key"`
+	Tokens     float64   `json:"tokens"`
+	RefilledAt time.Time `json:"refilled_at"`
+	// the bucket is full again from then on and can be dropped
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RateQuote struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	SpreadBps    int32     `json:"spread_bps"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// set once the quote has been applied to a transfer
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// hex encoded SHA-256 of the recovery code, the code itself is only shown to the user once
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	// token payload id
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Session struct {
+	// refresh token payload id
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SessionAccessToken struct {
+	// access token payload id
+	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type StandingOrder struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// once, daily, weekly or monthly
+	Frequency string `json:"frequency"`
+	// day of the month for monthly orders, clamped to the last day of shorter months
+	DayOfMonth sql.NullInt32 `json:"day_of_month"`
+	StartAt    time.Time     `json:"start_at"`
+	EndAt      sql.NullTime  `json:"end_at"`
+	// null once the order has no more runs
+	NextRunAt sql.NullTime `json:"next_run_at"`
+	// active, suspended, completed or cancelled
+	Status string `json:"status"`
+	// consecutive runs failed for insufficient funds
+	FailureCount int32     `json:"failure_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type StandingOrderRun struct {
+	ID              int64     `json:"id"`
+	StandingOrderID int64     `json:"standing_order_id"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	// pending, succeeded or failed
+	Status     string         `json:"status"`
+	TransferID sql.NullInt64  `json:"transfer_id"`
+	Error      sql.NullString `json:"error"`
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt sql.NullTime   `json:"finished_at"`
 }
 
 type Transfer struct {
@@ -31,6 +189,15 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// amount credited in the destination currency, null when no conversion took place
+	ConvertedAmount sql.NullInt64  `json:"converted_amount"`
+	ExchangeRate    sql.NullString `json:"exchange_rate"`
+	SpreadBps       sql.NullInt32  `json:"spread_bps"`
+	QuoteID         uuid.NullUUID  `json:"quote_id"`
+	// original transfer compensated by this one
+	ReversesTransferID sql.NullInt64 `json:"reverses_transfer_id"`
+	// sum of the reversals of this transfer, in the destination currency
+	ReversedAmount int64 `json:"reversed_amount"`
 }
 
 type User struct {
@@ -40,4 +207,33 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// depositor, banker or admin
+	Role string `json:"role"`
+	// failed logins since the last success, lockout or end of the attempt window
+	FailedLoginAttempts int32     `json:"failed_login_attempts"`
+	LastFailedLoginAt   time.Time `json:"last_failed_login_at"`
+	// lockouts since the last successful login, each one lasts twice as long as the previous one
+	Lockouts        int32     `json:"lockouts"`
+	LockedUntil     time.Time `json:"locked_until"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+	// base32 encoded TOTP secret, set when the enrollment starts and only used once it is confirmed
+	TotpSecret    string `json:"totp_secret"`
+	IsTotpEnabled bool   `json:"is_totp_enabled"`
+	// period of the last accepted TOTP code, the codes up to it cannot be replayed
+	TotpLastUsedStep int64 `json:"totp_last_used_step"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// hex encoded SHA-256 of the code mailed by the last send, empty until the email is sent
+	SecretCodeHash string `json:"secret_code_hash"`
+	IsUsed         bool   `json:"is_used"`
+	SendAttempts   int32  `json:"send_attempts"`
+	// when the email is due to be sent, null once it is sent or given up
+	NextSendAt sql.NullTime `json:"next_send_at"`
+	SentAt     sql.NullTime `json:"sent_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	CreatedAt  time.Time    `json:"created_at"`
 }
@@ -6,28 +6,117 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AddTransferReversedAmount(ctx context.Context, arg AddTransferReversedAmountParams) (Transfer, error)
+	BlockOtherUserSessions(ctx context.Context, arg BlockOtherUserSessionsParams) ([]Session, error)
+	BlockSession(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) ([]Session, error)
+	ClaimDueStandingOrders(ctx context.Context, arg ClaimDueStandingOrdersParams) ([]StandingOrder, error)
+	ClaimVerifyEmailsToSend(ctx context.Context, arg ClaimVerifyEmailsToSendParams) ([]VerifyEmail, error)
+	CountUnusedRecoveryCodes(ctx context.Context, username string) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountStatusChange(ctx context.Context, arg CreateAccountStatusChangeParams) (AccountStatusChange, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateExchangeRate(ctx context.Context, arg CreateExchangeRateParams) (ExchangeRate, error)
+	// an expired key is taken over by the new request.
+	// No row is returned when the key is still in use.
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateRateQuote(ctx context.Context, arg CreateRateQuoteParams) (RateQuote, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) (RevokedToken, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	// the session row is locked so the token cannot be recorded after a concurrent transaction blocked the session
+	CreateSessionAccessToken(ctx context.Context, arg CreateSessionAccessTokenParams) (SessionAccessToken, error)
+	CreateStandingOrder(ctx context.Context, arg CreateStandingOrderParams) (StandingOrder, error)
+	CreateStandingOrderRun(ctx context.Context, arg CreateStandingOrderRunParams) (StandingOrderRun, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
 	DeleteEntry(ctx context.Context, id int64) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) error
+	DeleteExpiredRateLimitBuckets(ctx context.Context) error
+	DeleteExpiredRevokedTokens(ctx context.Context) error
+	DeleteExpiredSessionAccessTokens(ctx context.Context) error
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
 	DeleteTransfer(ctx context.Context, id int64) error
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (User, error)
+	// expires the pending verifications of a user, the codes mailed to a previous address cannot be used anymore
+	ExpireUserVerifyEmails(ctx context.Context, username string) error
+	FinishStandingOrderRun(ctx context.Context, arg FinishStandingOrderRunParams) (StandingOrderRun, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByOwner(ctx context.Context, arg GetAccountByOwnerParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetExchangeRate(ctx context.Context, arg GetExchangeRateParams) (ExchangeRate, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetMFAChallenge(ctx context.Context, tokenHash string) (MfaChallenge, error)
+	GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error)
+	GetPendingStandingOrderRun(ctx context.Context, standingOrderID int64) (StandingOrderRun, error)
+	GetRateLimitBucket(ctx context.Context, key string) (RateLimitBucket, error)
+	GetRateQuote(ctx context.Context, id uuid.UUID) (RateQuote, error)
+	GetRateQuoteForUpdate(ctx context.Context, id uuid.UUID) (RateQuote, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetStandingOrder(ctx context.Context, id int64) (StandingOrder, error)
+	GetStandingOrderForUpdate(ctx context.Context, id int64) (StandingOrder, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetVerifyEmailForUpdate(ctx context.Context, id int64) (VerifyEmail, error)
+	ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error)
+	ListAccountStatusChanges(ctx context.Context, accountID int64) ([]AccountStatusChange, error)
+	ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListRevokedTokens(ctx context.Context, revokedAfter time.Time) ([]RevokedToken, error)
+	ListSessionAccessTokens(ctx context.Context, sessionID uuid.UUID) ([]SessionAccessToken, error)
+	ListStandingOrderRuns(ctx context.Context, arg ListStandingOrderRunsParams) ([]StandingOrderRun, error)
+	ListStandingOrders(ctx context.Context, arg ListStandingOrdersParams) ([]StandingOrder, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	MarkRateQuoteUsed(ctx context.Context, id uuid.UUID) (RateQuote, error)
+	MarkVerifyEmailSent(ctx context.Context, id int64) (VerifyEmail, error)
+	// No row is returned when the user is locked, a lock set by a concurrent failed login is kept.
+	ResetUnlockedUserLoginAttempts(ctx context.Context, arg ResetUnlockedUserLoginAttemptsParams) (User, error)
+	ResetUserLoginAttempts(ctx context.Context, username string) (User, error)
+	ScheduleStandingOrder(ctx context.Context, arg ScheduleStandingOrderParams) (StandingOrder, error)
+	ScheduleVerifyEmailSend(ctx context.Context, arg ScheduleVerifyEmailSendParams) (VerifyEmail, error)
+	// refills the bucket of the key at rate tokens per second up to capacity tokens, then takes one token from it.
+	// No row is returned when the bucket holds less than a token.
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
 	UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) (IdempotencyKey, error)
+	UpdateStandingOrder(ctx context.Context, arg UpdateStandingOrderParams) (StandingOrder, error)
 	UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error)
+	// updates the profile of a user, the fields given as NULL are left unchanged
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserLoginAttempts(ctx context.Context, arg UpdateUserLoginAttemptsParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateUserTOTPSecret(ctx context.Context, arg UpdateUserTOTPSecretParams) (User, error)
+	UpdateVerifyEmailSecretCode(ctx context.Context, arg UpdateVerifyEmailSecretCodeParams) (VerifyEmail, error)
+	// No row is returned when the challenge was already used.
+	UseMFAChallenge(ctx context.Context, id int64) (MfaChallenge, error)
+	UsePasswordResets(ctx context.Context, username string) error
+	// No row is returned when the code doesn't exist or was already used.
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	// records the period of an accepted TOTP code.
+	// No row is returned when a code of this period or a later one was already used.
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (User, error)
+	UseVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error)
+	VerifyUserEmail(ctx context.Context, username string) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
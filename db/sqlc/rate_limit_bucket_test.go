@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	arg := TakeRateLimitTokenParams{
+		Key:      utils.RandomString(16),
+		Capacity: 2,
+		Rate:     0.01,
+	}
+
+	bucket1, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Key, bucket1.Key)
+	require.Equal(t, float64(1), bucket1.Tokens)
+	require.WithinDuration(t, time.Now(), bucket1.RefilledAt, time.Second)
+	require.WithinDuration(t, bucket1.RefilledAt.Add(100*time.Second), bucket1.ExpiresAt, time.Second)
+
+	bucket2, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.InDelta(t, 0, bucket2.Tokens, 0.01)
+	require.WithinDuration(t, bucket2.RefilledAt.Add(200*time.Second), bucket2.ExpiresAt, time.Second)
+
+	// the bucket is empty, no row is updated
+	bucket3, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, bucket3)
+
+	bucket4, err := testQueries.GetRateLimitBucket(context.Background(), arg.Key)
+	require.NoError(t, err)
+	require.Equal(t, bucket2.Tokens, bucket4.Tokens)
+	require.WithinDuration(t, bucket2.RefilledAt, bucket4.RefilledAt, time.Millisecond)
+}
+
+func TestDeleteExpiredRateLimitBuckets(t *testing.T) {
+	arg := TakeRateLimitTokenParams{
+		Key:      utils.RandomString(16),
+		Capacity: 1,
+		Rate:     1000,
+	}
+
+	_, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+
+	err = testQueries.DeleteExpiredRateLimitBuckets(context.Background())
+	require.NoError(t, err)
+
+	bucket, err := testQueries.GetRateLimitBucket(context.Background(), arg.Key)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, bucket)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRateQuote(t *testing.T, username, fromCurrency, toCurrency string, expiresAt time.Time) RateQuote {
+	arg := CreateRateQuoteParams{
+		ID:           uuid.New(),
+		Username:     username,
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Rate:         "0.9",
+		SpreadBps:    100,
+		ExpiresAt:    expiresAt,
+	}
+
+	quote, err := testQueries.CreateRateQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, quote)
+
+	require.Equal(t, arg.ID, quote.ID)
+	require.Equal(t, arg.Username, quote.Username)
+	require.Equal(t, arg.FromCurrency, quote.FromCurrency)
+	require.Equal(t, arg.ToCurrency, quote.ToCurrency)
+	require.Equal(t, arg.SpreadBps, quote.SpreadBps)
+	require.WithinDuration(t, arg.ExpiresAt, quote.ExpiresAt, time.Second)
+	require.False(t, quote.UsedAt.Valid)
+
+	return quote
+}
+
+func TestGetExchangeRate(t *testing.T) {
+	now := time.Now()
+	older, err := testQueries.CreateExchangeRate(context.Background(), CreateExchangeRateParams{
+		BaseCurrency:  "CAD",
+		QuoteCurrency: "EUR",
+		Rate:          "0.5",
+		SpreadBps:     10,
+		EffectiveAt:   now.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateExchangeRate(context.Background(), CreateExchangeRateParams{
+		BaseCurrency:  "CAD",
+		QuoteCurrency: "EUR",
+		Rate:          "0.7",
+		SpreadBps:     10,
+		EffectiveAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	rate, err := testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		BaseCurrency:  "CAD",
+		QuoteCurrency: "EUR",
+		EffectiveAt:   now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, older.ID, rate.ID)
+	require.Equal(t, older.Rate, rate.Rate)
+}
+
+func TestMarkRateQuoteUsed(t *testing.T) {
+	user := createRandomUser(t)
+	quote1 := createRandomRateQuote(t, user.Username, "USD", "EUR", time.Now().Add(time.Minute))
+
+	quote2, err := testQueries.MarkRateQuoteUsed(context.Background(), quote1.ID)
+	require.NoError(t, err)
+	require.Equal(t, quote1.ID, quote2.ID)
+	require.True(t, quote2.UsedAt.Valid)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t)
+	arg := CreateSessionParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		UserAgent: "go-test",
+		ClientIp:  "127.0.0.1",
+		IsBlocked: false,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	session1 := createRandomSession(t)
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, session2)
+
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.Username, session2.Username)
+	require.Equal(t, session1.IsBlocked, session2.IsBlocked)
+	require.WithinDuration(t, session1.ExpiresAt, session2.ExpiresAt, time.Second)
+	require.WithinDuration(t, session1.CreatedAt, session2.CreatedAt, time.Second)
+}
+
+func createRandomSessionAccessToken(t *testing.T, session Session) SessionAccessToken {
+	arg := CreateSessionAccessTokenParams{
+		ID:        uuid.New(),
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+		SessionID: session.ID,
+	}
+
+	accessToken, err := testQueries.CreateSessionAccessToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, accessToken.ID)
+	require.Equal(t, arg.SessionID, accessToken.SessionID)
+	require.WithinDuration(t, arg.ExpiresAt, accessToken.ExpiresAt, time.Second)
+	require.NotZero(t, accessToken.CreatedAt)
+
+	return accessToken
+}
+
+func TestListSessionAccessTokens(t *testing.T) {
+	session := createRandomSession(t)
+	accessToken := createRandomSessionAccessToken(t, session)
+
+	_, err := testQueries.CreateSessionAccessToken(context.Background(), CreateSessionAccessTokenParams{
+		ID:        uuid.New(),
+		ExpiresAt: time.Now().Add(-time.Minute),
+		SessionID: session.ID,
+	})
+	require.NoError(t, err)
+
+	// the expired access token is left out
+	accessTokens, err := testQueries.ListSessionAccessTokens(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.Len(t, accessTokens, 1)
+	require.Equal(t, accessToken.ID, accessTokens[0].ID)
+}
+
+func TestCreateSessionAccessTokenBlockedSession(t *testing.T) {
+	session := createRandomSession(t)
+
+	_, err := testQueries.BlockSession(context.Background(), session.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateSessionAccessToken(context.Background(), CreateSessionAccessTokenParams{
+		ID:        uuid.New(),
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+		SessionID: session.ID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -3,13 +3,39 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // Store provide all functions to execute db queries and transactions
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	DepositTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error)
+	WithdrawalTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error)
+	LogoutTx(ctx context.Context, arg LogoutTxParams) (LogoutTxResult, error)
+	LogoutAllTx(ctx context.Context, arg LogoutTxParams) (LogoutTxResult, error)
+	ClaimStandingOrdersTx(ctx context.Context, arg ClaimStandingOrdersTxParams) ([]ClaimedStandingOrder, error)
+	FinishStandingOrderRunTx(ctx context.Context, arg FinishStandingOrderRunTxParams) (FinishStandingOrderRunTxResult, error)
+	RunStandingOrderTx(ctx context.Context, arg RunStandingOrderTxParams) (RunStandingOrderTxResult, error)
+	ReversalTx(ctx context.Context, arg ReversalTxParams) (ReversalTxResult, error)
+	ChangeUserRoleTx(ctx context.Context, arg ChangeUserRoleTxParams) (ChangeUserRoleTxResult, error)
+	RecordFailedLoginTx(ctx context.Context, arg RecordFailedLoginTxParams) (RecordFailedLoginTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (PasswordTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (PasswordTxResult, error)
+	ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error)
+	CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	ClaimVerifyEmailsTx(ctx context.Context, arg ClaimVerifyEmailsTxParams) ([]VerifyEmail, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version int64, dirty bool, err error)
 }
 
 // SQLStore provide all functions to execute SQL queries and transactions
@@ -36,8 +62,10 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 	err = fn(q)
 	if err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
+			slog.ErrorContext(ctx, "cannot rollback transaction", slog.Any("error", err), slog.Any("rollback_error", rbErr))
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 		}
+		slog.DebugContext(ctx, "transaction rolled back", slog.Any("error", err))
 		return err
 	}
 
@@ -45,10 +73,12 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 }
 
 // TransferTxParams contains the input parameter of the transfer transaction
+// A valid QuoteID converts the amount between the currencies of the two accounts using the quoted rate
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	QuoteID       uuid.NullUUID `json:"quote_id"`
 }
 
 // TransferTxResult is the result of the transaction
@@ -58,55 +88,169 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// LockWait is the time spent acquiring the row locks of both accounts
+	LockWait time.Duration `json:"-"`
 }
 
+// Kinds of entries recorded on an account
+const (
+	EntryKindTransfer   = "transfer"
+	EntryKindDeposit    = "deposit"
+	EntryKindWithdrawal = "withdrawal"
+	EntryKindReversal   = "reversal"
+)
+
 var txKey = struct{}{}
 
 // TransferTx performs a money transfer from one account to another.
 // It create a transfer record, add account entity, and upte account's balance within a single database transaction
+// It returns ErrAccountNotFound, ErrAccountFrozen, ErrAccountClosed, ErrCurrencyMismatch or ErrInsufficientFunds when the transfer is not allowed,
+// and one of the ErrRateQuote errors when the quote given for a cross-currency transfer cannot be applied
 func (s *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 	err := s.execTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = moveMoney(ctx, q, arg, EntryKindTransfer, true)
+		return err
+	})
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	return result, err
+}
+
+// moveMoney records a transfer with its two balanced entries of the given kind and updates both balances.
+// When checkFunds is false the source account is allowed to go negative, which is only meant for settlement accounts.
+func moveMoney(ctx context.Context, q *Queries, arg TransferTxParams, kind string, checkFunds bool) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	lockStart := time.Now()
+	fromAccount, toAccount, err := lockTransferAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+	lockWait := time.Since(lockStart)
+
+	if err := checkAccountStatus(fromAccount, toAccount); err != nil {
+		return result, err
+	}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
+	transferArg := CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+	}
+
+	if arg.QuoteID.Valid {
+		quote, err := useRateQuote(ctx, q, arg.QuoteID.UUID, fromAccount.Currency, toAccount.Currency)
 		if err != nil {
-			return err
+			return result, err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		toAmount, err := convertAmount(arg.Amount, quote.Rate, quote.SpreadBps)
 		if err != nil {
-			return err
+			return result, err
 		}
 
-		// to avoid deadlock when upudating two account concurrently
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+		transferArg.ConvertedAmount = sql.NullInt64{Int64: toAmount, Valid: true}
+		transferArg.ExchangeRate = sql.NullString{String: quote.Rate, Valid: true}
+		transferArg.SpreadBps = sql.NullInt32{Int32: quote.SpreadBps, Valid: true}
+		transferArg.QuoteID = arg.QuoteID
+	} else if fromAccount.Currency != toAccount.Currency {
+		return result, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, fromAccount.Currency, toAccount.Currency)
+	}
+
+	result, err = postTransfer(ctx, q, transferArg, fromAccount, kind, checkFunds)
+	result.LockWait = lockWait
+	return result, err
+}
+
+// lockTransferAccounts locks the source and destination accounts of a transfer in a consistent order to avoid deadlock
+func lockTransferAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount Account, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, toAccount, err = lockAccounts(ctx, q, fromAccountID, toAccountID)
+	} else {
+		toAccount, fromAccount, err = lockAccounts(ctx, q, toAccountID, fromAccountID)
+	}
+	return
+}
+
+// postTransfer creates the transfer between two locked accounts with its two balanced entries and updates both balances.
+// The destination account is credited with the converted amount when there is one.
+func postTransfer(ctx context.Context, q *Queries, arg CreateTransferParams, fromAccount Account, kind string, checkFunds bool) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
+
+	if checkFunds && fromAccount.Balance < arg.Amount {
+		return result, fmt.Errorf("%w: account [%d] balance %d is less than %d", ErrInsufficientFunds, fromAccount.ID, fromAccount.Balance, arg.Amount)
+	}
 
-		return nil
+	// amount credited to the destination account, which only differs from the debited one on conversion
+	toAmount := arg.Amount
+	if arg.ConvertedAmount.Valid {
+		toAmount = arg.ConvertedAmount.Int64
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, arg)
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount,
+		Kind:      kind,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    toAmount,
+		Kind:      kind,
 	})
+	if err != nil {
+		return result, err
+	}
+
+	// to avoid deadlock when upudating two account concurrently
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, toAmount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, toAmount, arg.FromAccountID, -arg.Amount)
+	}
 
 	return result, err
 }
 
+// lockAccounts acquires row locks on two accounts in the given order.
+// Callers must pass the lower account ID first so that concurrent transactions never wait on each other in a cycle.
+func lockAccounts(
+	ctx context.Context,
+	q *Queries,
+	accountID1 int64,
+	accountID2 int64,
+) (account1 Account, account2 Account, err error) {
+	account1, err = lockAccount(ctx, q, accountID1)
+	if err != nil {
+		return
+	}
+
+	account2, err = lockAccount(ctx, q, accountID2)
+	return
+}
+
+func lockAccount(ctx context.Context, q *Queries, accountID int64) (Account, error) {
+	account, err := q.GetAccountForUpdate(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, fmt.Errorf("%w: account [%d]", ErrAccountNotFound, accountID)
+		}
+		return account, err
+	}
+
+	return account, nil
+}
+
 func addMoney(
 	ctx context.Context,
 	q *Queries,
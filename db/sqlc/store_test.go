@@ -5,14 +5,15 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/mrohadi/simplebank/utils"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDBConn)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 1000, utils.USD)
 
 	// run n concurrent transfer transactions
 	n := 5
@@ -44,6 +45,7 @@ func TestTransferTx(t *testing.T) {
 
 		result := <-results
 		require.NotEmpty(t, result)
+		require.Positive(t, result.LockWait)
 
 		// check individual transfer
 		transfer := result.Transfer
@@ -62,6 +64,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, fromEntry)
 		require.Equal(t, account1.ID, fromEntry.AccountID)
 		require.Equal(t, -amount, fromEntry.Amount)
+		require.Equal(t, EntryKindTransfer, fromEntry.Kind)
 		require.NotZero(t, fromEntry.ID)
 		require.NotZero(t, fromEntry.CreatedAt)
 
@@ -73,6 +76,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toEntry)
 		require.Equal(t, account2.ID, toEntry.AccountID)
 		require.Equal(t, amount, toEntry.Amount)
+		require.Equal(t, EntryKindTransfer, toEntry.Kind)
 		require.NotZero(t, toEntry.ID)
 		require.NotZero(t, toEntry.CreatedAt)
 
@@ -114,8 +118,8 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDBConn)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 1000, utils.USD)
 
 	// run n concurrent transfer transactions
 	n := 10
@@ -160,3 +164,93 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	// account1 can only afford half of the transfers
+	n := 10
+	amount := int64(10)
+	account1 := createRandomAccountWithBalance(t, int64(n/2)*amount, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.USD)
+
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		txName := fmt.Sprintf("tx %d", i+1)
+		go func() {
+			ctx := context.WithValue(context.Background(), txKey, txName)
+			result, err := store.TransferTx(ctx, TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+			if err == nil && result.FromAccount.Balance < 0 {
+				err = fmt.Errorf("account [%d] balance went negative: %d", account1.ID, result.FromAccount.Balance)
+			}
+
+			errs <- err
+		}()
+	}
+
+	// check results
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+	}
+	require.Equal(t, n/2, succeeded)
+
+	// check the final updated balances
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(n/2)*amount, updatedAccount2.Balance)
+}
+
+func TestTransferTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 100, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 100, utils.EUR)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// nothing must be committed
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxAccountNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 100, utils.USD)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID + 1000000,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
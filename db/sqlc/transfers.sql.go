@@ -7,27 +7,80 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
 )
 
+const addTransferReversedAmount = `-- name: AddTransferReversedAmount :one
+UPDATE transfers
+SET reversed_amount = reversed_amount + $1
+WHERE id = $2
+RETURNING id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount
+`
+
+type AddTransferReversedAmountParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddTransferReversedAmount(ctx context.Context, arg AddTransferReversedAmountParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, addTransferReversedAmount, arg.Amount, arg.ID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ConvertedAmount,
+		&i.ExchangeRate,
+		&i.SpreadBps,
+		&i.QuoteID,
+		&i.ReversesTransferID,
+		&i.ReversedAmount,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  converted_amount,
+  exchange_rate,
+  spread_bps,
+  quote_id,
+  reverses_transfer_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING id, from_account_id, to_account_id, amount, created_at
+RETURNING id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID      int64          `json:"from_account_id"`
+	ToAccountID        int64          `json:"to_account_id"`
+	Amount             int64          `json:"amount"`
+	ConvertedAmount    sql.NullInt64  `json:"converted_amount"`
+	ExchangeRate       sql.NullString `json:"exchange_rate"`
+	SpreadBps          sql.NullInt32  `json:"spread_bps"`
+	QuoteID            uuid.NullUUID  `json:"quote_id"`
+	ReversesTransferID sql.NullInt64  `json:"reverses_transfer_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ConvertedAmount,
+		arg.ExchangeRate,
+		arg.SpreadBps,
+		arg.QuoteID,
+		arg.ReversesTransferID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -35,6 +88,12 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ConvertedAmount,
+		&i.ExchangeRate,
+		&i.SpreadBps,
+		&i.QuoteID,
+		&i.ReversesTransferID,
+		&i.ReversedAmount,
 	)
 	return i, err
 }
@@ -50,7 +109,7 @@ func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount FROM transfers
 WHERE id = $1
 `
 
@@ -63,12 +122,143 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ConvertedAmount,
+		&i.ExchangeRate,
+		&i.SpreadBps,
+		&i.QuoteID,
+		&i.ReversesTransferID,
+		&i.ReversedAmount,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount FROM transfers
+WHERE id = $1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ConvertedAmount,
+		&i.ExchangeRate,
+		&i.SpreadBps,
+		&i.QuoteID,
+		&i.ReversesTransferID,
+		&i.ReversedAmount,
 	)
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount FROM (
+  (
+    SELECT id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount FROM transfers
+    WHERE from_account_id = $1
+    AND ($2::varchar IS NULL OR $2 = 'out')
+    AND ($3::timestamp IS NULL OR created_at >= $3)
+    AND ($4::timestamp IS NULL OR created_at < $4)
+    AND ($5::bigint IS NULL OR amount >= $5)
+    AND ($6::bigint IS NULL OR amount <= $6)
+    AND (
+      $7::timestamp IS NULL
+      OR (created_at, id) < ($7, $8::bigint)
+    )
+    ORDER BY created_at DESC, id DESC
+    LIMIT $9
+  )
+  UNION ALL
+  (
+    -- the amount credited to the account is compared, converted in its currency
+    SELECT id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount FROM transfers
+    WHERE to_account_id = $1
+    AND ($2::varchar IS NULL OR $2 = 'in')
+    -- a transfer to the same account is already listed as outgoing
+    AND ($2::varchar IS NOT NULL OR from_account_id <> $1)
+    AND ($3::timestamp IS NULL OR created_at >= $3)
+    AND ($4::timestamp IS NULL OR created_at < $4)
+    AND ($5::bigint IS NULL OR COALESCE(converted_amount, amount) >= $5)
+    AND ($6::bigint IS NULL OR COALESCE(converted_amount, amount) <= $6)
+    AND (
+      $7::timestamp IS NULL
+      OR (created_at, id) < ($7, $8::bigint)
+    )
+    ORDER BY created_at DESC, id DESC
+    LIMIT $9
+  )
+) AS account_transfers
+ORDER BY created_at DESC, id DESC
+LIMIT $9
+`
+
+type ListAccountTransfersParams struct {
+	AccountID       int64          `json:"account_id"`
+	Direction       sql.NullString `json:"direction"`
+	CreatedFrom     sql.NullTime   `json:"created_from"`
+	CreatedTo       sql.NullTime   `json:"created_to"`
+	MinAmount       sql.NullInt64  `json:"min_amount"`
+	MaxAmount       sql.NullInt64  `json:"max_amount"`
+	CursorCreatedAt sql.NullTime   `json:"cursor_created_at"`
+	CursorID        sql.NullInt64  `json:"cursor_id"`
+	PageLimit       int32          `json:"page_limit"`
+}
+
+// Each direction is read from its own (account, created_at, id) index up to the page limit,
+// so a page never sorts more than twice its size however many transfers the account has.
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.AccountID,
+		arg.Direction,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ConvertedAmount,
+			&i.ExchangeRate,
+			&i.SpreadBps,
+			&i.QuoteID,
+			&i.ReversesTransferID,
+			&i.ReversedAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -94,6 +284,12 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ConvertedAmount,
+			&i.ExchangeRate,
+			&i.SpreadBps,
+			&i.QuoteID,
+			&i.ReversesTransferID,
+			&i.ReversedAmount,
 		); err != nil {
 			return nil, err
 		}
@@ -112,7 +308,7 @@ const updateTransfer = `-- name: UpdateTransfer :one
 UPDATE transfers 
 SET amount = $2
 WHERE id = $1
-RETURNING id, from_account_id, to_account_id, amount, created_at
+RETURNING id, from_account_id, to_account_id, amount, created_at, converted_amount, exchange_rate, spread_bps, quote_id, reverses_transfer_id, reversed_amount
 `
 
 type UpdateTransferParams struct {
@@ -129,6 +325,12 @@ func (q *Queries) UpdateTransfer(ctx context.Context, arg UpdateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ConvertedAmount,
+		&i.ExchangeRate,
+		&i.SpreadBps,
+		&i.QuoteID,
+		&i.ReversesTransferID,
+		&i.ReversedAmount,
 	)
 	return i, err
 }
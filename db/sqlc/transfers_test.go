@@ -95,3 +95,112 @@ func TestListTransfers(t *testing.T) {
 		require.NotEmpty(t, transfer)
 	}
 }
+
+func TestListAccountTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		_, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        utils.RandomMoney(),
+		})
+		require.NoError(t, err)
+
+		_, err = testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+			FromAccountID: account2.ID,
+			ToAccountID:   account1.ID,
+			Amount:        utils.RandomMoney(),
+		})
+		require.NoError(t, err)
+	}
+
+	all, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, all, 6)
+
+	// both directions are merged in order before the page is cut
+	page, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		PageLimit: 4,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 4)
+	for i := range page {
+		require.Equal(t, all[i].ID, page[i].ID)
+	}
+
+	outgoing, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Direction: sql.NullString{String: "out", Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, outgoing, 3)
+	for _, transfer := range outgoing {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
+
+	last := all[1]
+	rest, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID:       account1.ID,
+		CursorCreatedAt: sql.NullTime{Time: last.CreatedAt, Valid: true},
+		CursorID:        sql.NullInt64{Int64: last.ID, Valid: true},
+		PageLimit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rest, 4)
+	require.Equal(t, all[2].ID, rest[0].ID)
+}
+
+func TestListAccountTransfersConvertedAmount(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID:   account1.ID,
+		ToAccountID:     account2.ID,
+		Amount:          100,
+		ConvertedAmount: sql.NullInt64{Int64: 250, Valid: true},
+		ExchangeRate:    sql.NullString{String: "2.5", Valid: true},
+	})
+	require.NoError(t, err)
+
+	// the receiving account filters on the amount it was credited
+	incoming, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account2.ID,
+		MinAmount: sql.NullInt64{Int64: 200, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, incoming, 1)
+	require.Equal(t, transfer.ID, incoming[0].ID)
+
+	incoming, err = testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account2.ID,
+		MaxAmount: sql.NullInt64{Int64: 150, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, incoming)
+
+	// the sending account filters on the amount it was debited
+	outgoing, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		MaxAmount: sql.NullInt64{Int64: 150, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, outgoing, 1)
+	require.Equal(t, transfer.ID, outgoing[0].ID)
+
+	outgoing, err = testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		MinAmount: sql.NullInt64{Int64: 200, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, outgoing)
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Statuses of an account
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// ChangeAccountStatusTxParams contains the input parameter of the change account status transaction
+type ChangeAccountStatusTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Status is either frozen or active, accounts are closed with CloseAccountTx
+	Status    string `json:"status"`
+	ChangedBy string `json:"changed_by"`
+	Reason    string `json:"reason"`
+}
+
+// ChangeAccountStatusTxResult is the result of the change account status transaction
+type ChangeAccountStatusTxResult struct {
+	Account Account             `json:"account"`
+	Change  AccountStatusChange `json:"change"`
+}
+
+// ChangeAccountStatusTx freezes an active account or unfreezes a frozen one and records the transition.
+// It returns ErrAccountNotFound, or ErrInvalidStatusTransition when the account is not in the expected status.
+func (s *SQLStore) ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error) {
+	var result ChangeAccountStatusTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		account, err := lockAccount(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var from string
+		switch arg.Status {
+		case AccountStatusFrozen:
+			from = AccountStatusActive
+		case AccountStatusActive:
+			from = AccountStatusFrozen
+		default:
+			return fmt.Errorf("%w: cannot move to %s", ErrInvalidStatusTransition, arg.Status)
+		}
+
+		if account.Status != from {
+			return fmt.Errorf("%w: account [%d] is %s", ErrInvalidStatusTransition, account.ID, account.Status)
+		}
+
+		result.Account, result.Change, err = setAccountStatus(ctx, q, account, arg.Status, arg.ChangedBy, arg.Reason, sql.NullInt64{})
+		return err
+	})
+
+	return result, err
+}
+
+// CloseAccountTxParams contains the input parameter of the close account transaction
+type CloseAccountTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// SweepAccountID receives the remaining balance, zero when the account is expected to be empty
+	SweepAccountID int64  `json:"sweep_account_id"`
+	ChangedBy      string `json:"changed_by"`
+	Reason         string `json:"reason"`
+	// CloseFrozen allows closing a frozen account, which is reserved to admins so a freeze cannot be undone by sweeping the balance out
+	CloseFrozen bool `json:"close_frozen"`
+}
+
+// CloseAccountTxResult is the result of the close account transaction
+type CloseAccountTxResult struct {
+	Account Account             `json:"account"`
+	Change  AccountStatusChange `json:"change"`
+	// Sweep is set when a remaining balance was moved to the sweep account
+	Sweep *TransferTxResult `json:"sweep,omitempty"`
+}
+
+// CloseAccountTx closes an active or frozen account and records the transition.
+// The account must have a zero balance unless a sweep account is given, in which case the
+// remaining balance is transferred to it first.
+// It returns ErrAccountNotFound, ErrInvalidStatusTransition when the account is already closed,
+// ErrAccountFrozen when the account is frozen and CloseFrozen is not set, ErrAccountBalanceNotZero, and ErrCurrencyMismatch or ErrAccountClosed when the sweep account cannot receive the balance.
+func (s *SQLStore) CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error) {
+	var result CloseAccountTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var account, sweepAccount Account
+		var err error
+
+		if arg.SweepAccountID != 0 {
+			account, sweepAccount, err = lockTransferAccounts(ctx, q, arg.AccountID, arg.SweepAccountID)
+		} else {
+			account, err = lockAccount(ctx, q, arg.AccountID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if account.Status == AccountStatusClosed {
+			return fmt.Errorf("%w: account [%d] is already closed", ErrInvalidStatusTransition, account.ID)
+		}
+
+		if account.Status == AccountStatusFrozen && !arg.CloseFrozen {
+			return fmt.Errorf("%w: account [%d]", ErrAccountFrozen, account.ID)
+		}
+
+		var sweepTransferID sql.NullInt64
+		if account.Balance != 0 {
+			if arg.SweepAccountID == 0 || account.Balance < 0 {
+				return fmt.Errorf("%w: account [%d] balance is %d", ErrAccountBalanceNotZero, account.ID, account.Balance)
+			}
+
+			if sweepAccount.Currency != account.Currency {
+				return fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, account.Currency, sweepAccount.Currency)
+			}
+
+			if sweepAccount.Status == AccountStatusClosed {
+				return fmt.Errorf("%w: account [%d]", ErrAccountClosed, sweepAccount.ID)
+			}
+
+			// a frozen account closed by an admin can still be emptied
+			sweep, err := postTransfer(ctx, q, CreateTransferParams{
+				FromAccountID: account.ID,
+				ToAccountID:   sweepAccount.ID,
+				Amount:        account.Balance,
+			}, account, EntryKindTransfer, true)
+			if err != nil {
+				return err
+			}
+
+			result.Sweep = &sweep
+			account = sweep.FromAccount
+			sweepTransferID = sql.NullInt64{Int64: sweep.Transfer.ID, Valid: true}
+		}
+
+		result.Account, result.Change, err = setAccountStatus(ctx, q, account, AccountStatusClosed, arg.ChangedBy, arg.Reason, sweepTransferID)
+		return err
+	})
+
+	return result, err
+}
+
+// setAccountStatus updates the status of a locked account and records the transition in the audit trail
+func setAccountStatus(
+	ctx context.Context,
+	q *Queries,
+	account Account,
+	status string,
+	changedBy string,
+	reason string,
+	sweepTransferID sql.NullInt64,
+) (Account, AccountStatusChange, error) {
+	updated, err := q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+		ID:     account.ID,
+		Status: status,
+	})
+	if err != nil {
+		return updated, AccountStatusChange{}, err
+	}
+
+	change, err := q.CreateAccountStatusChange(ctx, CreateAccountStatusChangeParams{
+		AccountID:       account.ID,
+		FromStatus:      account.Status,
+		ToStatus:        status,
+		ChangedBy:       changedBy,
+		Reason:          reason,
+		SweepTransferID: sweepTransferID,
+	})
+	return updated, change, err
+}
+
+// checkAccountStatus returns an error when the source account cannot be debited or the destination one credited.
+// Frozen accounts can still receive money, closed ones can neither send nor receive.
+func checkAccountStatus(fromAccount, toAccount Account) error {
+	switch fromAccount.Status {
+	case AccountStatusFrozen:
+		return fmt.Errorf("%w: account [%d]", ErrAccountFrozen, fromAccount.ID)
+	case AccountStatusClosed:
+		return fmt.Errorf("%w: account [%d]", ErrAccountClosed, fromAccount.ID)
+	}
+
+	if toAccount.Status == AccountStatusClosed {
+		return fmt.Errorf("%w: account [%d]", ErrAccountClosed, toAccount.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeAccountStatusTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 100, utils.USD)
+
+	result, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+		ChangedBy: account.Owner,
+		Reason:    "lost card",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, result.Account.Status)
+	require.Equal(t, account.ID, result.Change.AccountID)
+	require.Equal(t, AccountStatusActive, result.Change.FromStatus)
+	require.Equal(t, AccountStatusFrozen, result.Change.ToStatus)
+	require.Equal(t, account.Owner, result.Change.ChangedBy)
+	require.Equal(t, "lost card", result.Change.Reason)
+	require.False(t, result.Change.SweepTransferID.Valid)
+
+	// freezing twice is not a valid transition
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+		ChangedBy: account.Owner,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+
+	result, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusActive,
+		ChangedBy: account.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, result.Account.Status)
+
+	changes, err := testQueries.ListAccountStatusChanges(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, AccountStatusFrozen, changes[0].ToStatus)
+	require.Equal(t, AccountStatusActive, changes[1].ToStatus)
+}
+
+func TestChangeAccountStatusTxCannotClose(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 0, utils.USD)
+
+	_, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusClosed,
+		ChangedBy: account.Owner,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+}
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 0, utils.EUR)
+
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID: account.ID,
+		ChangedBy: account.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, result.Account.Status)
+	require.Equal(t, AccountStatusClosed, result.Change.ToStatus)
+	require.Nil(t, result.Sweep)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID: account.ID,
+		ChangedBy: account.Owner,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+
+	// the owner can open a new account in the same currency
+	reopened, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Balance:  0,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, account.ID, reopened.ID)
+}
+
+func TestCloseAccountTxBalanceNotZero(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 100, utils.USD)
+
+	_, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID: account.ID,
+		ChangedBy: account.Owner,
+	})
+	require.ErrorIs(t, err, ErrAccountBalanceNotZero)
+
+	notClosed, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, notClosed.Status)
+}
+
+func TestCloseAccountTxSweep(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 100, utils.USD)
+	sweepAccount := createRandomAccountWithBalance(t, 50, utils.USD)
+
+	_, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+		ChangedBy: account.Owner,
+	})
+	require.NoError(t, err)
+
+	// the owner cannot empty a frozen account by closing it
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: sweepAccount.ID,
+		ChangedBy:      account.Owner,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// an admin can
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: sweepAccount.ID,
+		ChangedBy:      "admin",
+		Reason:         "moving out",
+		CloseFrozen:    true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, result.Account.Status)
+	require.Zero(t, result.Account.Balance)
+
+	require.NotNil(t, result.Sweep)
+	require.Equal(t, int64(100), result.Sweep.Transfer.Amount)
+	require.Equal(t, int64(150), result.Sweep.ToAccount.Balance)
+	require.True(t, result.Change.SweepTransferID.Valid)
+	require.Equal(t, result.Sweep.Transfer.ID, result.Change.SweepTransferID.Int64)
+	require.Equal(t, AccountStatusFrozen, result.Change.FromStatus)
+}
+
+func TestCloseAccountTxSweepCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 100, utils.USD)
+	sweepAccount := createRandomAccountWithBalance(t, 0, utils.EUR)
+
+	_, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: sweepAccount.ID,
+		ChangedBy:      account.Owner,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestTransferTxAccountStatus(t *testing.T) {
+	store := NewStore(testDBConn)
+	frozen := createRandomAccountWithBalance(t, 100, utils.USD)
+	closed := createRandomAccountWithBalance(t, 0, utils.USD)
+	active := createRandomAccountWithBalance(t, 100, utils.USD)
+
+	_, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: frozen.ID,
+		Status:    AccountStatusFrozen,
+		ChangedBy: frozen.Owner,
+	})
+	require.NoError(t, err)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID: closed.ID,
+		ChangedBy: closed.Owner,
+	})
+	require.NoError(t, err)
+
+	// a frozen account cannot be debited
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: frozen.ID,
+		ToAccountID:   active.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// but it can still be credited
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: active.ID,
+		ToAccountID:   frozen.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// a closed account can neither be credited nor debited
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: active.ID,
+		ToAccountID:   closed.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: closed.ID,
+		ToAccountID:   active.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+}
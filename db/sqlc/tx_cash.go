@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SystemAccountOwner owns the per-currency settlement accounts used to move cash in and out of the bank
+const SystemAccountOwner = "system"
+
+// CashTxParams contains the input parameter of the deposit and withdrawal transactions
+type CashTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// DepositTx credits an account with cash coming from outside the bank.
+// The matching debit is posted to the settlement account of the same currency,
+// so the result's FromAccount and FromEntry belong to the settlement account.
+func (s *SQLStore) DepositTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		settlement, err := getSettlementAccount(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result, err = moveMoney(ctx, q, TransferTxParams{
+			FromAccountID: settlement.ID,
+			ToAccountID:   arg.AccountID,
+			Amount:        arg.Amount,
+		}, EntryKindDeposit, false)
+		return err
+	})
+
+	return result, err
+}
+
+// WithdrawalTx debits an account for cash leaving the bank.
+// The matching credit is posted to the settlement account of the same currency,
+// so the result's ToAccount and ToEntry belong to the settlement account.
+// It returns ErrInsufficientFunds when the account balance is too low.
+func (s *SQLStore) WithdrawalTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		settlement, err := getSettlementAccount(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result, err = moveMoney(ctx, q, TransferTxParams{
+			FromAccountID: arg.AccountID,
+			ToAccountID:   settlement.ID,
+			Amount:        arg.Amount,
+		}, EntryKindWithdrawal, true)
+		return err
+	})
+
+	return result, err
+}
+
+// getSettlementAccount returns the settlement account matching the currency of the given account
+func getSettlementAccount(ctx context.Context, q *Queries, accountID int64) (Account, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, fmt.Errorf("%w: account [%d]", ErrAccountNotFound, accountID)
+		}
+		return account, err
+	}
+
+	settlement, err := q.GetAccountByOwner(ctx, GetAccountByOwnerParams{
+		Owner:    SystemAccountOwner,
+		Currency: account.Currency,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return settlement, fmt.Errorf("%w: currency %s", ErrSettlementAccountNotFound, account.Currency)
+		}
+		return settlement, err
+	}
+
+	return settlement, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 0, utils.USD)
+	amount := int64(100)
+
+	result, err := store.DepositTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, SystemAccountOwner, result.FromAccount.Owner)
+	require.Equal(t, account.Currency, result.FromAccount.Currency)
+	require.Equal(t, account.ID, result.ToAccount.ID)
+	require.Equal(t, account.Balance+amount, result.ToAccount.Balance)
+
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, amount, result.ToEntry.Amount)
+	require.Equal(t, EntryKindDeposit, result.FromEntry.Kind)
+	require.Equal(t, EntryKindDeposit, result.ToEntry.Kind)
+}
+
+func TestWithdrawalTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 100, utils.EUR)
+	amount := int64(60)
+
+	result, err := store.WithdrawalTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account.ID, result.FromAccount.ID)
+	require.Equal(t, account.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, SystemAccountOwner, result.ToAccount.Owner)
+	require.Equal(t, EntryKindWithdrawal, result.FromEntry.Kind)
+	require.Equal(t, EntryKindWithdrawal, result.ToEntry.Kind)
+
+	// the remaining balance cannot cover a second withdrawal
+	_, err = store.WithdrawalTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance-amount, updatedAccount.Balance)
+}
+
+func TestCashTxAccountNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	_, err := store.DepositTx(context.Background(), CashTxParams{AccountID: -1, Amount: 10})
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	_, err = store.WithdrawalTx(context.Background(), CashTxParams{AccountID: -1, Amount: 10})
+	require.ErrorIs(t, err, ErrAccountNotFound)
+}
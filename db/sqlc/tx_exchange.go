@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// basisPoints is the number of basis points in one unit
+const basisPoints = 10000
+
+// useRateQuote locks a rate quote, checks it can be applied to a transfer between the given currencies and marks it used
+func useRateQuote(ctx context.Context, q *Queries, quoteID uuid.UUID, fromCurrency, toCurrency string) (RateQuote, error) {
+	quote, err := q.GetRateQuoteForUpdate(ctx, quoteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return quote, fmt.Errorf("%w: quote [%s]", ErrRateQuoteNotFound, quoteID)
+		}
+		return quote, err
+	}
+
+	if quote.UsedAt.Valid {
+		return quote, fmt.Errorf("%w: quote [%s]", ErrRateQuoteUsed, quoteID)
+	}
+
+	if time.Now().After(quote.ExpiresAt) {
+		return quote, fmt.Errorf("%w: quote [%s] expired at %s", ErrRateQuoteExpired, quoteID, quote.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if quote.FromCurrency != fromCurrency || quote.ToCurrency != toCurrency {
+		return quote, fmt.Errorf("%w: quote is %s to %s, accounts are %s to %s",
+			ErrRateQuoteMismatch, quote.FromCurrency, quote.ToCurrency, fromCurrency, toCurrency)
+	}
+
+	return q.MarkRateQuoteUsed(ctx, quoteID)
+}
+
+// convertAmount converts an amount with the given rate after taking the spread off.
+// The result is rounded down so that rounding never works against the bank.
+func convertAmount(amount int64, rate string, spreadBps int32) (int64, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok {
+		return 0, fmt.Errorf("invalid exchange rate %q", rate)
+	}
+
+	converted := new(big.Rat).Mul(big.NewRat(amount, 1), r)
+	converted.Mul(converted, big.NewRat(basisPoints-int64(spreadBps), basisPoints))
+
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("converted amount of %d at rate %s overflows", amount, rate)
+	}
+
+	if result.Int64() <= 0 {
+		return 0, fmt.Errorf("%w: %d at rate %s", ErrConvertedAmountTooSmall, amount, rate)
+	}
+
+	return result.Int64(), nil
+}
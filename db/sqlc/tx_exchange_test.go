@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxWithQuote(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.EUR)
+	quote := createRandomRateQuote(t, account1.Owner, utils.USD, utils.EUR, time.Now().Add(time.Minute))
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+	}
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// 1000 * 0.9 minus the 1% spread
+	converted := int64(891)
+
+	transfer := result.Transfer
+	require.Equal(t, int64(1000), transfer.Amount)
+	require.True(t, transfer.ConvertedAmount.Valid)
+	require.Equal(t, converted, transfer.ConvertedAmount.Int64)
+	require.True(t, transfer.ExchangeRate.Valid)
+	require.Equal(t, quote.Rate, transfer.ExchangeRate.String)
+	require.Equal(t, quote.SpreadBps, transfer.SpreadBps.Int32)
+	require.Equal(t, arg.QuoteID, transfer.QuoteID)
+
+	require.Equal(t, int64(-1000), result.FromEntry.Amount)
+	require.Equal(t, converted, result.ToEntry.Amount)
+	require.Equal(t, int64(0), result.FromAccount.Balance)
+	require.Equal(t, converted, result.ToAccount.Balance)
+
+	// a quote can only be applied once
+	account3 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	arg.FromAccountID = account3.ID
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRateQuoteUsed)
+}
+
+func TestTransferTxQuoteExpired(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.EUR)
+	quote := createRandomRateQuote(t, account1.Owner, utils.USD, utils.EUR, time.Now().Add(-time.Second))
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+	})
+	require.ErrorIs(t, err, ErrRateQuoteExpired)
+
+	// nothing was moved and the quote is still unused
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	quote, err = testQueries.GetRateQuote(context.Background(), quote.ID)
+	require.NoError(t, err)
+	require.False(t, quote.UsedAt.Valid)
+}
+
+func TestTransferTxQuoteMismatch(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.CAD)
+	quote := createRandomRateQuote(t, account1.Owner, utils.USD, utils.EUR, time.Now().Add(time.Minute))
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+	})
+	require.ErrorIs(t, err, ErrRateQuoteMismatch)
+}
+
+func TestTransferTxQuoteNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.EUR)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		QuoteID:       uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	})
+	require.ErrorIs(t, err, ErrRateQuoteNotFound)
+}
+
+func TestConvertAmount(t *testing.T) {
+	testCases := []struct {
+		name      string
+		amount    int64
+		rate      string
+		spreadBps int32
+		expected  int64
+		err       error
+	}{
+		{name: "NoSpread", amount: 1000, rate: "1.3600000000", spreadBps: 0, expected: 1360},
+		{name: "WithSpread", amount: 1000, rate: "0.9", spreadBps: 100, expected: 891},
+		{name: "RoundsDown", amount: 3, rate: "0.5", spreadBps: 0, expected: 1},
+		{name: "TooSmall", amount: 1, rate: "0.5", spreadBps: 50, err: ErrConvertedAmountTooSmall},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			converted, err := convertAmount(tc.amount, tc.rate, tc.spreadBps)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, converted)
+		})
+	}
+}
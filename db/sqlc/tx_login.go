@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RecordFailedLoginTxParams contains the input parameter of the record failed login transaction
+type RecordFailedLoginTxParams struct {
+	Username string `json:"username"`
+	// MaxAttempts failed logins within AttemptWindow lock the user
+	MaxAttempts   int32         `json:"max_attempts"`
+	AttemptWindow time.Duration `json:"attempt_window"`
+	// LockoutDuration is the length of the first lockout, it doubles on every lockout up to MaxLockoutDuration
+	LockoutDuration    time.Duration `json:"lockout_duration"`
+	MaxLockoutDuration time.Duration `json:"max_lockout_duration"`
+	Now                time.Time     `json:"now"`
+}
+
+// RecordFailedLoginTxResult is the result of the record failed login transaction
+type RecordFailedLoginTxResult struct {
+	User User `json:"user"`
+	// Locked is set when the user is locked, by this failed login or by a concurrent one
+	Locked bool `json:"locked"`
+}
+
+// RecordFailedLoginTx counts a failed login of the user and locks the user once MaxAttempts failures
+// are counted within the attempt window. The user row is locked so concurrent failures are all counted.
+// A failure checked while a concurrent one locked the user is not counted, the lock is returned as is.
+func (s *SQLStore) RecordFailedLoginTx(ctx context.Context, arg RecordFailedLoginTxParams) (RecordFailedLoginTxResult, error) {
+	var result RecordFailedLoginTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		if arg.Now.Before(user.LockedUntil) {
+			result.User = user
+			result.Locked = true
+			return nil
+		}
+
+		update := UpdateUserLoginAttemptsParams{
+			Username:            user.Username,
+			FailedLoginAttempts: user.FailedLoginAttempts + 1,
+			LastFailedLoginAt:   arg.Now,
+			Lockouts:            user.Lockouts,
+			LockedUntil:         user.LockedUntil,
+		}
+		if arg.Now.Sub(user.LastFailedLoginAt) > arg.AttemptWindow {
+			update.FailedLoginAttempts = 1
+		}
+
+		if update.FailedLoginAttempts >= arg.MaxAttempts {
+			update.FailedLoginAttempts = 0
+			update.Lockouts++
+			update.LockedUntil = arg.Now.Add(lockoutDuration(arg.LockoutDuration, arg.MaxLockoutDuration, update.Lockouts))
+			result.Locked = true
+		}
+
+		result.User, err = q.UpdateUserLoginAttempts(ctx, update)
+		return err
+	})
+
+	return result, err
+}
+
+// lockoutDuration doubles the first lockout for each previous one, without exceeding max when it is set
+func lockoutDuration(first time.Duration, max time.Duration, lockouts int32) time.Duration {
+	duration := first
+	for i := int32(1); i < lockouts; i++ {
+		if max > 0 && duration >= max {
+			break
+		}
+		duration *= 2
+	}
+
+	if max > 0 && duration > max {
+		return max
+	}
+
+	return duration
+}
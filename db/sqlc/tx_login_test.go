@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func recordFailedLogin(t *testing.T, store Store, username string, now time.Time) RecordFailedLoginTxResult {
+	result, err := store.RecordFailedLoginTx(context.Background(), RecordFailedLoginTxParams{
+		Username:           username,
+		MaxAttempts:        3,
+		AttemptWindow:      time.Minute,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: 3 * time.Minute,
+		Now:                now,
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestRecordFailedLoginTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	user := createRandomUser(t)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	for i := int32(1); i < 3; i++ {
+		result := recordFailedLogin(t, store, user.Username, now)
+		require.False(t, result.Locked)
+		require.Equal(t, i, result.User.FailedLoginAttempts)
+		require.WithinDuration(t, now, result.User.LastFailedLoginAt, time.Millisecond)
+		require.True(t, result.User.LockedUntil.IsZero())
+	}
+
+	result := recordFailedLogin(t, store, user.Username, now)
+	require.True(t, result.Locked)
+	require.Zero(t, result.User.FailedLoginAttempts)
+	require.Equal(t, int32(1), result.User.Lockouts)
+	require.WithinDuration(t, now.Add(time.Minute), result.User.LockedUntil, time.Millisecond)
+
+	// the failures checked during the lockout are not counted
+	locked := recordFailedLogin(t, store, user.Username, now)
+	require.True(t, locked.Locked)
+	require.Zero(t, locked.User.FailedLoginAttempts)
+	require.Equal(t, result.User.LockedUntil, locked.User.LockedUntil)
+
+	// the second lockout lasts twice as long
+	later := now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		result = recordFailedLogin(t, store, user.Username, later)
+	}
+	require.True(t, result.Locked)
+	require.Equal(t, int32(2), result.User.Lockouts)
+	require.WithinDuration(t, later.Add(2*time.Minute), result.User.LockedUntil, time.Millisecond)
+
+	// a successful login cannot clear an active lock
+	_, err := testQueries.ResetUnlockedUserLoginAttempts(context.Background(), ResetUnlockedUserLoginAttemptsParams{
+		Username: user.Username,
+		Now:      later,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	unlocked, err := testQueries.ResetUnlockedUserLoginAttempts(context.Background(), ResetUnlockedUserLoginAttemptsParams{
+		Username: user.Username,
+		Now:      later.Add(3 * time.Minute),
+	})
+	require.NoError(t, err)
+	require.Zero(t, unlocked.FailedLoginAttempts)
+	require.Zero(t, unlocked.Lockouts)
+
+	user, err = testQueries.ResetUserLoginAttempts(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.Zero(t, user.Lockouts)
+	require.True(t, user.LockedUntil.IsZero())
+}
+
+func TestRecordFailedLoginTxWindow(t *testing.T) {
+	store := NewStore(testDBConn)
+	user := createRandomUser(t)
+	now := time.Now()
+
+	recordFailedLogin(t, store, user.Username, now)
+	recordFailedLogin(t, store, user.Username, now)
+
+	// the attempts older than the window are not counted
+	result := recordFailedLogin(t, store, user.Username, now.Add(2*time.Minute))
+	require.False(t, result.Locked)
+	require.Equal(t, int32(1), result.User.FailedLoginAttempts)
+}
+
+func TestRecordFailedLoginTxUserNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	_, err := store.RecordFailedLoginTx(context.Background(), RecordFailedLoginTxParams{Username: "unknown", MaxAttempts: 3})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestLockoutDuration(t *testing.T) {
+	require.Equal(t, time.Minute, lockoutDuration(time.Minute, time.Hour, 1))
+	require.Equal(t, 2*time.Minute, lockoutDuration(time.Minute, time.Hour, 2))
+	require.Equal(t, 8*time.Minute, lockoutDuration(time.Minute, time.Hour, 4))
+	require.Equal(t, time.Hour, lockoutDuration(time.Minute, time.Hour, 100))
+	require.Equal(t, 16*time.Minute, lockoutDuration(time.Minute, 0, 5))
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogoutTxParams contains the input parameter of the logout transactions
+type LogoutTxParams struct {
+	Username             string    `json:"username"`
+	SessionID            uuid.UUID `json:"session_id"`
+	AccessTokenID        uuid.UUID `json:"access_token_id"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// LogoutTxResult is the result of the logout transactions
+type LogoutTxResult struct {
+	Sessions      []Session      `json:"sessions"`
+	RevokedTokens []RevokedToken `json:"revoked_tokens"`
+}
+
+// LogoutTx blocks a single session and revokes its refresh and access tokens together with the access token used for the request
+func (s *SQLStore) LogoutTx(ctx context.Context, arg LogoutTxParams) (LogoutTxResult, error) {
+	var result LogoutTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		session, err := q.BlockSession(ctx, arg.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if session.Username != arg.Username {
+			return ErrSessionNotOwned
+		}
+
+		result.Sessions = []Session{session}
+		result.RevokedTokens, err = revokeTokens(ctx, q, arg, result.Sessions)
+		return err
+	})
+
+	return result, err
+}
+
+// LogoutAllTx blocks every active session of the user and revokes their refresh and access tokens
+// together with the access token used for the request. SessionID is ignored.
+func (s *SQLStore) LogoutAllTx(ctx context.Context, arg LogoutTxParams) (LogoutTxResult, error) {
+	var result LogoutTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Sessions, err = q.BlockUserSessions(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.RevokedTokens, err = revokeTokens(ctx, q, arg, result.Sessions)
+		return err
+	})
+
+	return result, err
+}
+
+func revokeTokens(ctx context.Context, q *Queries, arg LogoutTxParams, sessions []Session) ([]RevokedToken, error) {
+	revokedTokens := make([]RevokedToken, 0, len(sessions)+1)
+
+	revokedToken, err := q.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        arg.AccessTokenID,
+		Username:  arg.Username,
+		ExpiresAt: arg.AccessTokenExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	revokedTokens = append(revokedTokens, revokedToken)
+
+	sessionTokens, err := revokeSessionTokens(ctx, q, sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	// the access token used for the request is usually recorded against its session as well
+	for _, token := range sessionTokens {
+		if token.ID != arg.AccessTokenID {
+			revokedTokens = append(revokedTokens, token)
+		}
+	}
+
+	return revokedTokens, nil
+}
+
+// revokeSessionTokens revokes the refresh tokens of the sessions and the unexpired access tokens issued for them
+func revokeSessionTokens(ctx context.Context, q *Queries, sessions []Session) ([]RevokedToken, error) {
+	revokedTokens := make([]RevokedToken, 0, len(sessions))
+	for _, session := range sessions {
+		revokedToken, err := q.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+			ID:        session.ID,
+			Username:  session.Username,
+			ExpiresAt: session.ExpiresAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		revokedTokens = append(revokedTokens, revokedToken)
+
+		accessTokens, err := q.ListSessionAccessTokens(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, accessToken := range accessTokens {
+			revokedToken, err := q.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+				ID:        accessToken.ID,
+				Username:  session.Username,
+				ExpiresAt: accessToken.ExpiresAt,
+			})
+			if err != nil {
+				return nil, err
+			}
+			revokedTokens = append(revokedTokens, revokedToken)
+		}
+	}
+
+	return revokedTokens, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	session := createRandomSession(t)
+
+	arg := LogoutTxParams{
+		Username:             session.Username,
+		SessionID:            session.ID,
+		AccessTokenID:        uuid.New(),
+		AccessTokenExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	result, err := store.LogoutTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, result.Sessions, 1)
+	require.True(t, result.Sessions[0].IsBlocked)
+	require.Len(t, result.RevokedTokens, 2)
+	require.Equal(t, arg.AccessTokenID, result.RevokedTokens[0].ID)
+	require.Equal(t, session.ID, result.RevokedTokens[1].ID)
+
+	blocked, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	revoked, err := testQueries.ListRevokedTokens(context.Background(), time.Time{})
+	require.NoError(t, err)
+
+	ids := make([]uuid.UUID, 0, len(revoked))
+	for _, token := range revoked {
+		ids = append(ids, token.ID)
+	}
+	require.Contains(t, ids, arg.AccessTokenID)
+	require.Contains(t, ids, session.ID)
+}
+
+func TestLogoutTxSessionNotOwned(t *testing.T) {
+	store := NewStore(testDBConn)
+	session := createRandomSession(t)
+	other := createRandomUser(t)
+
+	_, err := store.LogoutTx(context.Background(), LogoutTxParams{
+		Username:             other.Username,
+		SessionID:            session.ID,
+		AccessTokenID:        uuid.New(),
+		AccessTokenExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.ErrorIs(t, err, ErrSessionNotOwned)
+
+	// the session must stay usable
+	notBlocked, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.False(t, notBlocked.IsBlocked)
+}
+
+func TestLogoutAllTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	session1 := createRandomSession(t)
+
+	session2, err := testQueries.CreateSession(context.Background(), CreateSessionParams{
+		ID:        uuid.New(),
+		Username:  session1.Username,
+		UserAgent: "go-test",
+		ClientIp:  "127.0.0.1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// the access token used for the request belongs to session1, session2 has another live access token
+	current := createRandomSessionAccessToken(t, session1)
+	other := createRandomSessionAccessToken(t, session2)
+
+	result, err := store.LogoutAllTx(context.Background(), LogoutTxParams{
+		Username:             session1.Username,
+		AccessTokenID:        current.ID,
+		AccessTokenExpiresAt: current.ExpiresAt,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Sessions, 2)
+	require.Len(t, result.RevokedTokens, 4)
+
+	ids := make([]uuid.UUID, 0, len(result.RevokedTokens))
+	for _, token := range result.RevokedTokens {
+		ids = append(ids, token.ID)
+	}
+	require.ElementsMatch(t, []uuid.UUID{current.ID, session1.ID, session2.ID, other.ID}, ids)
+
+	for _, id := range []uuid.UUID{session1.ID, session2.ID} {
+		session, err := testQueries.GetSession(context.Background(), id)
+		require.NoError(t, err)
+		require.True(t, session.IsBlocked)
+	}
+}
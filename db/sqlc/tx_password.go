@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangePasswordTxParams contains the input parameter of the change password transaction
+type ChangePasswordTxParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+	// KeepSessionID is the session of the caller, it stays active. Every session is blocked when it is nil.
+	KeepSessionID uuid.UUID `json:"keep_session_id"`
+}
+
+// ResetPasswordTxParams contains the input parameter of the reset password transaction
+type ResetPasswordTxParams struct {
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+// PasswordTxResult is the result of the change and reset password transactions
+type PasswordTxResult struct {
+	User          User           `json:"user"`
+	Sessions      []Session      `json:"sessions"`
+	RevokedTokens []RevokedToken `json:"revoked_tokens"`
+}
+
+// ChangePasswordTx updates the password of a user and blocks the other sessions,
+// so that the refresh tokens obtained with the previous password can no longer be renewed.
+func (s *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (PasswordTxResult, error) {
+	var result PasswordTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = changePassword(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// ResetPasswordTx sets the password of the user a reset token was issued to and blocks every session.
+// The token must be unused and unexpired, it is then used up together with the other tokens of the user.
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (PasswordTxResult, error) {
+	var result PasswordTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		reset, err := q.GetPasswordResetForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		if reset.UsedAt.Valid {
+			return ErrPasswordResetUsed
+		}
+
+		if time.Now().After(reset.ExpiresAt) {
+			return ErrPasswordResetExpired
+		}
+
+		result, err = changePassword(ctx, q, ChangePasswordTxParams{
+			Username:       reset.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+func changePassword(ctx context.Context, q *Queries, arg ChangePasswordTxParams) (PasswordTxResult, error) {
+	var result PasswordTxResult
+	var err error
+
+	result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:       arg.Username,
+		HashedPassword: arg.HashedPassword,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// the pending reset tokens were requested for the previous password
+	err = q.UsePasswordResets(ctx, arg.Username)
+	if err != nil {
+		return result, err
+	}
+
+	result.Sessions, err = q.BlockOtherUserSessions(ctx, BlockOtherUserSessionsParams{
+		Username:      arg.Username,
+		KeepSessionID: arg.KeepSessionID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.RevokedTokens, err = revokeSessionTokens(ctx, q, result.Sessions)
+	return result, err
+}
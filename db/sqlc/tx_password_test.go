@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, username string, expiresAt time.Time) PasswordReset {
+	arg := CreatePasswordResetParams{
+		Username:  username,
+		TokenHash: utils.RandomString(64),
+		ExpiresAt: expiresAt,
+	}
+
+	reset, err := testQueries.CreatePasswordReset(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, reset.ID)
+	require.Equal(t, arg.Username, reset.Username)
+	require.Equal(t, arg.TokenHash, reset.TokenHash)
+	require.WithinDuration(t, arg.ExpiresAt, reset.ExpiresAt, time.Second)
+	require.False(t, reset.UsedAt.Valid)
+
+	return reset
+}
+
+func createUserSession(t *testing.T, username string) Session {
+	session, err := testQueries.CreateSession(context.Background(), CreateSessionParams{
+		ID:        uuid.New(),
+		Username:  username,
+		UserAgent: "go-test",
+		ClientIp:  "127.0.0.1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	return session
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	current := createRandomSession(t)
+	other := createUserSession(t, current.Username)
+	reset := createRandomPasswordReset(t, current.Username, time.Now().Add(time.Minute))
+
+	result, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       current.Username,
+		HashedPassword: "new-hash",
+		KeepSessionID:  current.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", result.User.HashedPassword)
+	require.False(t, result.User.PasswordChangedAt.IsZero())
+
+	require.Len(t, result.Sessions, 1)
+	require.Equal(t, other.ID, result.Sessions[0].ID)
+	require.Len(t, result.RevokedTokens, 1)
+	require.Equal(t, other.ID, result.RevokedTokens[0].ID)
+
+	session, err := testQueries.GetSession(context.Background(), current.ID)
+	require.NoError(t, err)
+	require.False(t, session.IsBlocked)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{TokenHash: reset.TokenHash, HashedPassword: "hash"})
+	require.ErrorIs(t, err, ErrPasswordResetUsed)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	session := createRandomSession(t)
+	reset := createRandomPasswordReset(t, session.Username, time.Now().Add(time.Minute))
+
+	arg := ResetPasswordTxParams{TokenHash: reset.TokenHash, HashedPassword: "new-hash"}
+	result, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, session.Username, result.User.Username)
+	require.Equal(t, "new-hash", result.User.HashedPassword)
+	require.Len(t, result.Sessions, 1)
+	require.Equal(t, session.ID, result.Sessions[0].ID)
+	require.Len(t, result.RevokedTokens, 1)
+
+	// the token is single use
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrPasswordResetUsed)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDBConn)
+	user := createRandomUser(t)
+	reset := createRandomPasswordReset(t, user.Username, time.Now().Add(-time.Minute))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{TokenHash: reset.TokenHash, HashedPassword: "new-hash"})
+	require.ErrorIs(t, err, ErrPasswordResetExpired)
+
+	unchanged, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, unchanged.HashedPassword)
+}
+
+func TestResetPasswordTxNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{TokenHash: utils.RandomString(64), HashedPassword: "new-hash"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	user2, err := testQueries.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+}
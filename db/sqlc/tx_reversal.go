@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ReversalTxParams contains the input parameter of the reversal transaction
+type ReversalTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount is taken back from the recipient in the destination currency, zero reverses everything left
+	Amount int64 `json:"amount"`
+}
+
+// ReversalTxResult is the result of the reversal transaction
+type ReversalTxResult struct {
+	TransferTxResult
+	// Original is the reversed transfer with its updated reversed amount
+	Original Transfer `json:"original"`
+}
+
+// ReversalTx creates a compensating transfer from the recipient of a transfer back to its sender.
+// Several partial reversals can be made as long as their sum does not exceed the original transfer.
+// For a converted transfer the sender is refunded in proportion of the original amounts, so a full reversal
+// gives back exactly what was debited.
+// Deposits and withdrawals move cash through the settlement accounts and cannot be reversed.
+// It returns ErrTransferNotFound, ErrTransferIsReversal, ErrTransferIsCash, ErrTransferAlreadyReversed or
+// ErrReversalExceedsTransfer when the reversal is not allowed, ErrAccountFrozen or ErrAccountClosed when one of the accounts cannot be used,
+// and ErrInsufficientFunds when the recipient cannot pay it back.
+func (s *SQLStore) ReversalTx(ctx context.Context, arg ReversalTxParams) (ReversalTxResult, error) {
+	var result ReversalTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		// locking the original transfer serializes concurrent reversals of it
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: transfer [%d]", ErrTransferNotFound, arg.TransferID)
+			}
+			return err
+		}
+
+		if original.ReversesTransferID.Valid {
+			return fmt.Errorf("%w: transfer [%d]", ErrTransferIsReversal, original.ID)
+		}
+
+		received := original.Amount
+		if original.ConvertedAmount.Valid {
+			received = original.ConvertedAmount.Int64
+		}
+
+		remaining := received - original.ReversedAmount
+		if remaining <= 0 {
+			return fmt.Errorf("%w: transfer [%d]", ErrTransferAlreadyReversed, original.ID)
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount > remaining {
+			return fmt.Errorf("%w: %d is more than %d", ErrReversalExceedsTransfer, amount, remaining)
+		}
+
+		fromAccount, toAccount, err := lockTransferAccounts(ctx, q, original.ToAccountID, original.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		// entries aren't linked to their transfer, the settlement owner is what marks a cash movement
+		if fromAccount.Owner == SystemAccountOwner || toAccount.Owner == SystemAccountOwner {
+			return fmt.Errorf("%w: transfer [%d]", ErrTransferIsCash, original.ID)
+		}
+
+		if err := checkAccountStatus(fromAccount, toAccount); err != nil {
+			return err
+		}
+
+		transferArg := CreateTransferParams{
+			FromAccountID:      original.ToAccountID,
+			ToAccountID:        original.FromAccountID,
+			Amount:             amount,
+			ReversesTransferID: sql.NullInt64{Int64: original.ID, Valid: true},
+		}
+		if original.ConvertedAmount.Valid {
+			refund := proportionalRefund(original.Amount, received, original.ReversedAmount, amount)
+			transferArg.ConvertedAmount = sql.NullInt64{Int64: refund, Valid: true}
+			transferArg.ExchangeRate = original.ExchangeRate
+			transferArg.SpreadBps = original.SpreadBps
+		}
+
+		result.TransferTxResult, err = postTransfer(ctx, q, transferArg, fromAccount, EntryKindReversal, true)
+		if err != nil {
+			return err
+		}
+
+		result.Original, err = q.AddTransferReversedAmount(ctx, AddTransferReversedAmountParams{
+			ID:     original.ID,
+			Amount: amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// proportionalRefund returns the amount of the sender currency matching a reversal of a converted transfer.
+// It is computed on the running total so the rounding of partial reversals adds up to the original amount.
+func proportionalRefund(debited, received, reversed, amount int64) int64 {
+	refundedBefore := new(big.Int).Mul(big.NewInt(debited), big.NewInt(reversed))
+	refundedBefore.Quo(refundedBefore, big.NewInt(received))
+
+	refundedAfter := new(big.Int).Mul(big.NewInt(debited), big.NewInt(reversed+amount))
+	refundedAfter.Quo(refundedAfter, big.NewInt(received))
+
+	return refundedAfter.Sub(refundedAfter, refundedBefore).Int64()
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReversalTx(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.USD)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// partial reversal
+	result, err := store.ReversalTx(context.Background(), ReversalTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     30,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account2.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(30), result.Transfer.Amount)
+	require.Equal(t, sql.NullInt64{Int64: transfer.Transfer.ID, Valid: true}, result.Transfer.ReversesTransferID)
+	require.Equal(t, int64(30), result.Original.ReversedAmount)
+	require.Equal(t, EntryKindReversal, result.FromEntry.Kind)
+	require.Equal(t, int64(70), result.FromAccount.Balance)
+	require.Equal(t, int64(930), result.ToAccount.Balance)
+
+	// more than what is left
+	_, err = store.ReversalTx(context.Background(), ReversalTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     71,
+	})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+
+	// the rest
+	result, err = store.ReversalTx(context.Background(), ReversalTxParams{TransferID: transfer.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), result.Transfer.Amount)
+	require.Equal(t, int64(100), result.Original.ReversedAmount)
+	require.Equal(t, int64(0), result.FromAccount.Balance)
+	require.Equal(t, int64(1000), result.ToAccount.Balance)
+
+	_, err = store.ReversalTx(context.Background(), ReversalTxParams{TransferID: transfer.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	// a reversal is not reversible itself
+	_, err = store.ReversalTx(context.Background(), ReversalTxParams{TransferID: result.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsReversal)
+}
+
+func TestReversalTxConcurrent(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.USD)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// only two of the five reversals fit in the transfer
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReversalTx(context.Background(), ReversalTxParams{
+				TransferID: transfer.Transfer.ID,
+				Amount:     50,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+	}
+	require.Equal(t, 2, succeeded)
+
+	original, err := testQueries.GetTransfer(context.Background(), transfer.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), original.ReversedAmount)
+}
+
+func TestReversalTxConverted(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.EUR)
+	quote := createRandomRateQuote(t, account1.Owner, utils.USD, utils.EUR, time.Now().Add(time.Minute))
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(891), transfer.Transfer.ConvertedAmount.Int64)
+
+	result, err := store.ReversalTx(context.Background(), ReversalTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), result.Transfer.Amount)
+	require.Equal(t, int64(1000*100/891), result.Transfer.ConvertedAmount.Int64)
+
+	// rounding of the partial reversals adds up to the original amount
+	result, err = store.ReversalTx(context.Background(), ReversalTxParams{TransferID: transfer.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), result.FromAccount.Balance)
+	require.Equal(t, int64(1000), result.ToAccount.Balance)
+}
+
+func TestReversalTxTransferNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	_, err := store.ReversalTx(context.Background(), ReversalTxParams{TransferID: 0})
+	require.ErrorIs(t, err, ErrTransferNotFound)
+}
+
+func TestProportionalRefund(t *testing.T) {
+	total := int64(0)
+	reversed := int64(0)
+	for _, amount := range []int64{100, 333, 1, 457} {
+		total += proportionalRefund(1000, 891, reversed, amount)
+		reversed += amount
+	}
+	require.Equal(t, int64(891), reversed)
+	require.Equal(t, int64(1000), total)
+}
+
+func TestReversalTxCash(t *testing.T) {
+	store := NewStore(testDBConn)
+	account := createRandomAccountWithBalance(t, 100, utils.USD)
+
+	deposit, err := store.DepositTx(context.Background(), CashTxParams{AccountID: account.ID, Amount: 50})
+	require.NoError(t, err)
+
+	_, err = store.ReversalTx(context.Background(), ReversalTxParams{TransferID: deposit.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsCash)
+
+	withdrawal, err := store.WithdrawalTx(context.Background(), CashTxParams{AccountID: account.ID, Amount: 50})
+	require.NoError(t, err)
+
+	_, err = store.ReversalTx(context.Background(), ReversalTxParams{TransferID: withdrawal.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsCash)
+
+	updated, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), updated.Balance)
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Frequencies of a standing order
+const (
+	StandingOrderOnce    = "once"
+	StandingOrderDaily   = "daily"
+	StandingOrderWeekly  = "weekly"
+	StandingOrderMonthly = "monthly"
+)
+
+// Statuses of a standing order
+const (
+	StandingOrderActive    = "active"
+	StandingOrderSuspended = "suspended"
+	StandingOrderCompleted = "completed"
+	StandingOrderCancelled = "cancelled"
+)
+
+// Statuses of a standing order run
+const (
+	StandingOrderRunPending   = "pending"
+	StandingOrderRunSucceeded = "succeeded"
+	StandingOrderRunFailed    = "failed"
+)
+
+// ClaimStandingOrdersTxParams contains the input parameter of the claim standing orders transaction
+type ClaimStandingOrdersTxParams struct {
+	Now       time.Time `json:"now"`
+	BatchSize int32     `json:"batch_size"`
+	// Lease postpones the next run of the claimed orders so they are not claimed again while being executed
+	Lease time.Duration `json:"lease"`
+}
+
+// ClaimedStandingOrder is a standing order claimed for execution along with its pending run
+type ClaimedStandingOrder struct {
+	Order StandingOrder    `json:"order"`
+	Run   StandingOrderRun `json:"run"`
+}
+
+// ClaimStandingOrdersTx claims up to BatchSize active standing orders that are due and records a pending run for each.
+// Orders locked by another scheduler are skipped, so several instances can run the scheduler concurrently.
+// An order claimed again after its lease expired gets back its pending run, so the run is paid at most once.
+func (s *SQLStore) ClaimStandingOrdersTx(ctx context.Context, arg ClaimStandingOrdersTxParams) ([]ClaimedStandingOrder, error) {
+	var result []ClaimedStandingOrder
+	err := s.execTx(ctx, func(q *Queries) error {
+		orders, err := q.ClaimDueStandingOrders(ctx, ClaimDueStandingOrdersParams{
+			Now:       arg.Now,
+			BatchSize: arg.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		result = make([]ClaimedStandingOrder, 0, len(orders))
+		for _, order := range orders {
+			run, err := q.GetPendingStandingOrderRun(ctx, order.ID)
+			if errors.Is(err, sql.ErrNoRows) {
+				run, err = q.CreateStandingOrderRun(ctx, CreateStandingOrderRunParams{
+					StandingOrderID: order.ID,
+					ScheduledAt:     order.NextRunAt.Time,
+				})
+			}
+			if err != nil {
+				return err
+			}
+
+			order, err = q.ScheduleStandingOrder(ctx, ScheduleStandingOrderParams{
+				ID:           order.ID,
+				Status:       order.Status,
+				NextRunAt:    sql.NullTime{Time: arg.Now.Add(arg.Lease), Valid: true},
+				FailureCount: order.FailureCount,
+			})
+			if err != nil {
+				return err
+			}
+
+			result = append(result, ClaimedStandingOrder{Order: order, Run: run})
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// FinishStandingOrderRunTxParams contains the input parameter of the finish standing order run transaction
+type FinishStandingOrderRunTxParams struct {
+	RunID      int64         `json:"run_id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	// Error is empty when the run succeeded
+	Error string `json:"error"`
+
+	// next state of the standing order
+	OrderID      int64        `json:"order_id"`
+	Status       string       `json:"status"`
+	NextRunAt    sql.NullTime `json:"next_run_at"`
+	FailureCount int32        `json:"failure_count"`
+}
+
+// FinishStandingOrderRunTxResult is the result of the finish standing order run transaction
+type FinishStandingOrderRunTxResult struct {
+	Run   StandingOrderRun `json:"run"`
+	Order StandingOrder    `json:"order"`
+}
+
+// FinishStandingOrderRunTx records the outcome of a run and schedules the next one.
+// An order suspended or cancelled by its owner while the run was executing is left as is.
+// It returns ErrStandingOrderRunFinished when the run was already finished by another scheduler.
+func (s *SQLStore) FinishStandingOrderRunTx(ctx context.Context, arg FinishStandingOrderRunTxParams) (FinishStandingOrderRunTxResult, error) {
+	var result FinishStandingOrderRunTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = recordStandingOrderRun(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// RunStandingOrderTxParams contains the input parameter of the run standing order transaction
+type RunStandingOrderTxParams struct {
+	Transfer TransferTxParams `json:"transfer"`
+	// Finish is the outcome recorded once the transfer is posted, its TransferID is set by the transaction
+	Finish FinishStandingOrderRunTxParams `json:"finish"`
+}
+
+// RunStandingOrderTxResult is the result of the run standing order transaction
+type RunStandingOrderTxResult struct {
+	Transfer TransferTxResult `json:"transfer"`
+	Run      StandingOrderRun `json:"run"`
+	Order    StandingOrder    `json:"order"`
+}
+
+// RunStandingOrderTx posts the transfer of a claimed run and records it as succeeded within a single database transaction,
+// so a run interrupted half-way is retried without paying the order twice.
+// The order is locked before the transfer, an order suspended or cancelled by its owner returns ErrStandingOrderNotActive
+// and a run already finished by another scheduler returns ErrStandingOrderRunFinished.
+// It returns the errors of TransferTx when the transfer is not allowed, nothing is recorded then.
+func (s *SQLStore) RunStandingOrderTx(ctx context.Context, arg RunStandingOrderTxParams) (RunStandingOrderTxResult, error) {
+	var result RunStandingOrderTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		order, err := q.GetStandingOrderForUpdate(ctx, arg.Finish.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != StandingOrderActive {
+			return fmt.Errorf("%w: standing order [%d] is %s", ErrStandingOrderNotActive, order.ID, order.Status)
+		}
+
+		result.Transfer, err = moveMoney(ctx, q, arg.Transfer, EntryKindTransfer, true)
+		if err != nil {
+			return err
+		}
+
+		finishArg := arg.Finish
+		finishArg.TransferID = sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}
+		finishArg.Error = ""
+
+		finished, err := recordStandingOrderRun(ctx, q, finishArg)
+		result.Run = finished.Run
+		result.Order = finished.Order
+		return err
+	})
+
+	return result, err
+}
+
+// recordStandingOrderRun records the outcome of a run and schedules the next one unless the order is no longer active.
+// The order is locked before the run, in the same order as RunStandingOrderTx, so concurrent schedulers cannot deadlock.
+func recordStandingOrderRun(ctx context.Context, q *Queries, arg FinishStandingOrderRunTxParams) (FinishStandingOrderRunTxResult, error) {
+	var result FinishStandingOrderRunTxResult
+	var err error
+
+	result.Order, err = q.GetStandingOrderForUpdate(ctx, arg.OrderID)
+	if err != nil {
+		return result, err
+	}
+
+	runArg := FinishStandingOrderRunParams{
+		ID:         arg.RunID,
+		Status:     StandingOrderRunSucceeded,
+		TransferID: arg.TransferID,
+	}
+	if arg.Error != "" {
+		runArg.Status = StandingOrderRunFailed
+		runArg.Error = sql.NullString{String: arg.Error, Valid: true}
+	}
+
+	// only a pending run can be finished, the transaction is rolled back when another scheduler got there first
+	result.Run, err = q.FinishStandingOrderRun(ctx, runArg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, fmt.Errorf("%w: run [%d]", ErrStandingOrderRunFinished, arg.RunID)
+		}
+		return result, err
+	}
+
+	if result.Order.Status != StandingOrderActive {
+		return result, nil
+	}
+
+	result.Order, err = q.ScheduleStandingOrder(ctx, ScheduleStandingOrderParams{
+		ID:           arg.OrderID,
+		Status:       arg.Status,
+		NextRunAt:    arg.NextRunAt,
+		FailureCount: arg.FailureCount,
+	})
+	return result, err
+}
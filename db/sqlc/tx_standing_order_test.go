@@ -0,0 +1,351 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createDueStandingOrder(t *testing.T) StandingOrder {
+	account1 := createRandomAccountWithBalance(t, 1000, utils.USD)
+	account2 := createRandomAccountWithBalance(t, 0, utils.USD)
+	startAt := time.Now().Add(-time.Minute)
+
+	order, err := testQueries.CreateStandingOrder(context.Background(), CreateStandingOrderParams{
+		Owner:         account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Currency:      utils.USD,
+		Frequency:     StandingOrderDaily,
+		StartAt:       startAt,
+		NextRunAt:     sql.NullTime{Time: startAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.Zero(t, order.FailureCount)
+
+	return order
+}
+
+func TestClaimStandingOrdersTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+	now := time.Now()
+
+	claimed, err := store.ClaimStandingOrdersTx(context.Background(), ClaimStandingOrdersTxParams{
+		Now:       now,
+		BatchSize: 1000,
+		Lease:     time.Minute,
+	})
+	require.NoError(t, err)
+
+	var found *ClaimedStandingOrder
+	for i := range claimed {
+		if claimed[i].Order.ID == order.ID {
+			found = &claimed[i]
+		}
+	}
+	require.NotNil(t, found)
+	require.Equal(t, StandingOrderRunPending, found.Run.Status)
+	require.WithinDuration(t, order.NextRunAt.Time, found.Run.ScheduledAt, time.Second)
+	require.WithinDuration(t, now.Add(time.Minute), found.Order.NextRunAt.Time, time.Second)
+
+	// the lease keeps the order from being claimed again
+	claimed, err = store.ClaimStandingOrdersTx(context.Background(), ClaimStandingOrdersTxParams{
+		Now:       now,
+		BatchSize: 1000,
+		Lease:     time.Minute,
+	})
+	require.NoError(t, err)
+	for _, c := range claimed {
+		require.NotEqual(t, order.ID, c.Order.ID)
+	}
+}
+
+func TestClaimStandingOrdersTxExpiredLease(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+	now := time.Now()
+
+	claim := func(now time.Time) ClaimedStandingOrder {
+		claimed, err := store.ClaimStandingOrdersTx(context.Background(), ClaimStandingOrdersTxParams{
+			Now:       now,
+			BatchSize: 1000,
+			Lease:     time.Minute,
+		})
+		require.NoError(t, err)
+
+		for _, c := range claimed {
+			if c.Order.ID == order.ID {
+				return c
+			}
+		}
+		require.FailNow(t, "standing order was not claimed")
+		return ClaimedStandingOrder{}
+	}
+
+	first := claim(now)
+
+	// the order claimed again once the lease expired gets back the pending run of the period
+	second := claim(now.Add(2 * time.Minute))
+	require.Equal(t, first.Run.ID, second.Run.ID)
+	require.WithinDuration(t, order.NextRunAt.Time, second.Run.ScheduledAt, time.Second)
+}
+
+func TestClaimStandingOrdersTxConcurrent(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	n := 5
+	results := make(chan []ClaimedStandingOrder)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			claimed, err := store.ClaimStandingOrdersTx(context.Background(), ClaimStandingOrdersTxParams{
+				Now:       time.Now(),
+				BatchSize: 1000,
+				Lease:     time.Minute,
+			})
+			errs <- err
+			results <- claimed
+		}()
+	}
+
+	claims := 0
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		for _, c := range <-results {
+			if c.Order.ID == order.ID {
+				claims++
+			}
+		}
+	}
+	require.Equal(t, 1, claims)
+}
+
+func TestFinishStandingOrderRunTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	run, err := testQueries.CreateStandingOrderRun(context.Background(), CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+	})
+	require.NoError(t, err)
+
+	nextRunAt := time.Now().Add(time.Hour)
+	result, err := store.FinishStandingOrderRunTx(context.Background(), FinishStandingOrderRunTxParams{
+		RunID:        run.ID,
+		Error:        ErrInsufficientFunds.Error(),
+		OrderID:      order.ID,
+		Status:       StandingOrderActive,
+		NextRunAt:    sql.NullTime{Time: nextRunAt, Valid: true},
+		FailureCount: 1,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, StandingOrderRunFailed, result.Run.Status)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error.String)
+	require.True(t, result.Run.FinishedAt.Valid)
+	require.Equal(t, int32(1), result.Order.FailureCount)
+	require.WithinDuration(t, nextRunAt, result.Order.NextRunAt.Time, time.Second)
+}
+
+func TestFinishStandingOrderRunTxKeepsCancelledOrder(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	run, err := testQueries.CreateStandingOrderRun(context.Background(), CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpdateStandingOrder(context.Background(), UpdateStandingOrderParams{
+		ID:        order.ID,
+		Amount:    order.Amount,
+		Status:    StandingOrderCancelled,
+		UpdatedAt: order.UpdatedAt,
+	})
+	require.NoError(t, err)
+
+	result, err := store.FinishStandingOrderRunTx(context.Background(), FinishStandingOrderRunTxParams{
+		RunID:     run.ID,
+		OrderID:   order.ID,
+		Status:    StandingOrderActive,
+		NextRunAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, StandingOrderRunSucceeded, result.Run.Status)
+	require.Equal(t, StandingOrderCancelled, result.Order.Status)
+	require.False(t, result.Order.NextRunAt.Valid)
+}
+
+func TestUpdateStandingOrderChangedByScheduler(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	_, err := store.ClaimStandingOrdersTx(context.Background(), ClaimStandingOrdersTxParams{
+		Now:       time.Now(),
+		BatchSize: 1000,
+		Lease:     time.Minute,
+	})
+	require.NoError(t, err)
+
+	// the order read before the claim is stale, updating it would overwrite the lease
+	_, err = testQueries.UpdateStandingOrder(context.Background(), UpdateStandingOrderParams{
+		ID:        order.ID,
+		Amount:    order.Amount,
+		Status:    StandingOrderCancelled,
+		UpdatedAt: order.UpdatedAt,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRunStandingOrderTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	run, err := testQueries.CreateStandingOrderRun(context.Background(), CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+	})
+	require.NoError(t, err)
+
+	nextRunAt := time.Now().Add(24 * time.Hour)
+	result, err := store.RunStandingOrderTx(context.Background(), RunStandingOrderTxParams{
+		Transfer: TransferTxParams{
+			FromAccountID: order.FromAccountID,
+			ToAccountID:   order.ToAccountID,
+			Amount:        order.Amount,
+		},
+		Finish: FinishStandingOrderRunTxParams{
+			RunID:     run.ID,
+			OrderID:   order.ID,
+			Status:    StandingOrderActive,
+			NextRunAt: sql.NullTime{Time: nextRunAt, Valid: true},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1000)-order.Amount, result.Transfer.FromAccount.Balance)
+	require.Equal(t, order.Amount, result.Transfer.ToAccount.Balance)
+	require.Equal(t, StandingOrderRunSucceeded, result.Run.Status)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}, result.Run.TransferID)
+	require.WithinDuration(t, nextRunAt, result.Order.NextRunAt.Time, time.Second)
+}
+
+func TestRunStandingOrderTxFailedTransfer(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	run, err := testQueries.CreateStandingOrderRun(context.Background(), CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+	})
+	require.NoError(t, err)
+
+	_, err = store.RunStandingOrderTx(context.Background(), RunStandingOrderTxParams{
+		Transfer: TransferTxParams{
+			FromAccountID: order.FromAccountID,
+			ToAccountID:   order.ToAccountID,
+			Amount:        2000,
+		},
+		Finish: FinishStandingOrderRunTxParams{
+			RunID:     run.ID,
+			OrderID:   order.ID,
+			Status:    StandingOrderActive,
+			NextRunAt: sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true},
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// nothing is recorded, the run is still pending
+	runs, err := testQueries.ListStandingOrderRuns(context.Background(), ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, StandingOrderRunPending, runs[0].Status)
+	require.False(t, runs[0].TransferID.Valid)
+}
+
+func TestRunStandingOrderTxCancelledOrder(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	run, err := testQueries.CreateStandingOrderRun(context.Background(), CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.ScheduleStandingOrder(context.Background(), ScheduleStandingOrderParams{
+		ID:     order.ID,
+		Status: StandingOrderCancelled,
+	})
+	require.NoError(t, err)
+
+	_, err = store.RunStandingOrderTx(context.Background(), RunStandingOrderTxParams{
+		Transfer: TransferTxParams{
+			FromAccountID: order.FromAccountID,
+			ToAccountID:   order.ToAccountID,
+			Amount:        order.Amount,
+		},
+		Finish: FinishStandingOrderRunTxParams{
+			RunID:   run.ID,
+			OrderID: order.ID,
+			Status:  StandingOrderActive,
+		},
+	})
+	require.ErrorIs(t, err, ErrStandingOrderNotActive)
+
+	account, err := testQueries.GetAccount(context.Background(), order.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), account.Balance)
+}
+
+func TestRunStandingOrderTxRunFinished(t *testing.T) {
+	store := NewStore(testDBConn)
+	order := createDueStandingOrder(t)
+
+	run, err := testQueries.CreateStandingOrderRun(context.Background(), CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt.Time,
+	})
+	require.NoError(t, err)
+
+	arg := RunStandingOrderTxParams{
+		Transfer: TransferTxParams{
+			FromAccountID: order.FromAccountID,
+			ToAccountID:   order.ToAccountID,
+			Amount:        order.Amount,
+		},
+		Finish: FinishStandingOrderRunTxParams{
+			RunID:     run.ID,
+			OrderID:   order.ID,
+			Status:    StandingOrderActive,
+			NextRunAt: sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true},
+		},
+	}
+	_, err = store.RunStandingOrderTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// a second scheduler executing the same run is rolled back
+	_, err = store.RunStandingOrderTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrStandingOrderRunFinished)
+
+	account, err := testQueries.GetAccount(context.Background(), order.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000)-order.Amount, account.Balance)
+
+	_, err = store.FinishStandingOrderRunTx(context.Background(), arg.Finish)
+	require.ErrorIs(t, err, ErrStandingOrderRunFinished)
+}
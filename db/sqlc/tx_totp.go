@@ -0,0 +1,56 @@
+package db
+
+import "context"
+
+// EnableTOTPTxParams contains the input parameter of the enable TOTP transaction
+type EnableTOTPTxParams struct {
+	Username string `json:"username"`
+	// Step is the period of the code confirming the enrollment, it cannot be used again
+	Step               int64    `json:"step"`
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// EnableTOTPTxResult is the result of the enable TOTP transaction
+type EnableTOTPTxResult struct {
+	User          User           `json:"user"`
+	RecoveryCodes []RecoveryCode `json:"recovery_codes"`
+}
+
+// EnableTOTPTx turns on the two-factor authentication of a user once the first code is confirmed.
+// The recovery codes of a previous enrollment are replaced by the new ones.
+func (s *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error) {
+	var result EnableTOTPTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.EnableUserTOTP(ctx, EnableUserTOTPParams{
+			Username:         arg.Username,
+			TotpLastUsedStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.RecoveryCodes = make([]RecoveryCode, 0, len(arg.RecoveryCodeHashes))
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			code, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+
+			result.RecoveryCodes = append(result.RecoveryCodes, code)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableTOTPTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	user := createRandomUser(t)
+
+	user, err := store.UpdateUserTOTPSecret(context.Background(), UpdateUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: "JBSWY3DPEHPK3PXP",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", user.TotpSecret)
+	require.False(t, user.IsTotpEnabled)
+
+	enable := func(hashes ...string) EnableTOTPTxResult {
+		result, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+			Username:           user.Username,
+			Step:               100,
+			RecoveryCodeHashes: hashes,
+		})
+		require.NoError(t, err)
+		require.True(t, result.User.IsTotpEnabled)
+		require.EqualValues(t, 100, result.User.TotpLastUsedStep)
+		require.Len(t, result.RecoveryCodes, len(hashes))
+		return result
+	}
+
+	oldHash := utils.RandomString(64)
+	enable(oldHash, utils.RandomString(64))
+
+	// enrolling again replaces the recovery codes
+	newHash := utils.RandomString(64)
+	enable(newHash)
+
+	count, err := store.CountUnusedRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+
+	_, err = store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{Username: user.Username, CodeHash: oldHash})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	code, err := store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{Username: user.Username, CodeHash: newHash})
+	require.NoError(t, err)
+	require.True(t, code.UsedAt.Valid)
+
+	_, err = store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{Username: user.Username, CodeHash: newHash})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseUserTOTPStep(t *testing.T) {
+	user := createRandomUser(t)
+
+	user, err := testQueries.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{Username: user.Username, TotpLastUsedStep: 10})
+	require.NoError(t, err)
+	require.EqualValues(t, 10, user.TotpLastUsedStep)
+
+	// a code of the same period or an earlier one is a replay
+	_, err = testQueries.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{Username: user.Username, TotpLastUsedStep: 10})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{Username: user.Username, TotpLastUsedStep: 9})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
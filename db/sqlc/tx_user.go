@@ -0,0 +1,43 @@
+package db
+
+import "context"
+
+// ChangeUserRoleTxParams contains the input parameter of the change user role transaction
+type ChangeUserRoleTxParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// ChangeUserRoleTxResult is the result of the change user role transaction
+type ChangeUserRoleTxResult struct {
+	User          User           `json:"user"`
+	Sessions      []Session      `json:"sessions"`
+	RevokedTokens []RevokedToken `json:"revoked_tokens"`
+}
+
+// ChangeUserRoleTx updates the role of a user, blocks every active session and revokes their refresh
+// and access tokens, so that no token carrying the previous role can be used or renewed anymore.
+func (s *SQLStore) ChangeUserRoleTx(ctx context.Context, arg ChangeUserRoleTxParams) (ChangeUserRoleTxResult, error) {
+	var result ChangeUserRoleTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: arg.Username,
+			Role:     arg.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Sessions, err = q.BlockUserSessions(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.RevokedTokens, err = revokeSessionTokens(ctx, q, result.Sessions)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeUserRoleTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	session := createRandomSession(t)
+	accessToken := createRandomSessionAccessToken(t, session)
+
+	result, err := store.ChangeUserRoleTx(context.Background(), ChangeUserRoleTxParams{
+		Username: session.Username,
+		Role:     "admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "admin", result.User.Role)
+	require.Len(t, result.Sessions, 1)
+	require.Equal(t, session.ID, result.Sessions[0].ID)
+	require.Len(t, result.RevokedTokens, 2)
+	require.Equal(t, session.ID, result.RevokedTokens[0].ID)
+	require.Equal(t, accessToken.ID, result.RevokedTokens[1].ID)
+
+	blocked, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+}
+
+func TestChangeUserRoleTxUserNotFound(t *testing.T) {
+	store := NewStore(testDBConn)
+
+	_, err := store.ChangeUserRoleTx(context.Background(), ChangeUserRoleTxParams{
+		Username: "unknown",
+		Role:     "banker",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"time"
+)
+
+// CreateUserTxParams contains the input parameter of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	// ExpiresAt ends the verification of the email address
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateUserTxResult is the result of the create user transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user along with the verification of its email address.
+// The verification email is not sent here, it is due right away and picked up by ClaimVerifyEmailsTx.
+// The secret code is chosen when the email is sent, only its hash is stored.
+func (s *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:  result.User.Username,
+			Email:     result.User.Email,
+			ExpiresAt: arg.ExpiresAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// UpdateUserTxParams contains the input parameter of the update user transaction
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	// ExpiresAt ends the verification of the new email address, it is only used when the email changes
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UpdateUserTxResult is the result of the update user transaction
+type UpdateUserTxResult struct {
+	User         User `json:"user"`
+	EmailChanged bool `json:"email_changed"`
+	// PreviousEmail is the address of the user before the update
+	PreviousEmail string      `json:"previous_email"`
+	VerifyEmail   VerifyEmail `json:"verify_email"`
+}
+
+// UpdateUserTx updates the profile of a user.
+// A new email address must be verified again, the pending verifications of the previous one are expired.
+func (s *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		params := arg.UpdateUserParams
+		result.PreviousEmail = user.Email
+		result.EmailChanged = params.Email.Valid && params.Email.String != user.Email
+		if result.EmailChanged {
+			params.IsEmailVerified = sql.NullBool{Bool: false, Valid: true}
+		} else {
+			params.Email = sql.NullString{}
+		}
+
+		result.User, err = q.UpdateUser(ctx, params)
+		if err != nil || !result.EmailChanged {
+			return err
+		}
+
+		err = q.ExpireUserVerifyEmails(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:  result.User.Username,
+			Email:     result.User.Email,
+			ExpiresAt: arg.ExpiresAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// ClaimVerifyEmailsTxParams contains the input parameter of the claim verify emails transaction
+type ClaimVerifyEmailsTxParams struct {
+	Now       time.Time `json:"now"`
+	BatchSize int32     `json:"batch_size"`
+	// Lease postpones the next send of the claimed emails so they are not claimed again while being sent
+	Lease time.Duration `json:"lease"`
+}
+
+// ClaimVerifyEmailsTx claims up to BatchSize verification emails that are due and counts a send attempt for each.
+// Emails locked by another dispatcher are skipped, so several instances can send emails concurrently.
+func (s *SQLStore) ClaimVerifyEmailsTx(ctx context.Context, arg ClaimVerifyEmailsTxParams) ([]VerifyEmail, error) {
+	var result []VerifyEmail
+	err := s.execTx(ctx, func(q *Queries) error {
+		emails, err := q.ClaimVerifyEmailsToSend(ctx, ClaimVerifyEmailsToSendParams{
+			Now:       arg.Now,
+			BatchSize: arg.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		result = make([]VerifyEmail, 0, len(emails))
+		for _, email := range emails {
+			email, err = q.ScheduleVerifyEmailSend(ctx, ScheduleVerifyEmailSendParams{
+				ID:           email.ID,
+				NextSendAt:   sql.NullTime{Time: arg.Now.Add(arg.Lease), Valid: true},
+				SendAttempts: email.SendAttempts + 1,
+			})
+			if err != nil {
+				return err
+			}
+
+			result = append(result, email)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// VerifyEmailTxParams contains the input parameter of the verify email transaction
+type VerifyEmailTxParams struct {
+	ID int64 `json:"id"`
+	// SecretCodeHash is the hash of the code given by the user
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// VerifyEmailTxResult is the result of the verify email transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx marks the email address of a user as verified once the secret code matches.
+// The verification must be unused and unexpired, it is used up by a successful call.
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		verifyEmail, err := q.GetVerifyEmailForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		// the hash is empty until a code is mailed
+		if len(verifyEmail.SecretCodeHash) == 0 ||
+			subtle.ConstantTimeCompare([]byte(verifyEmail.SecretCodeHash), []byte(arg.SecretCodeHash)) != 1 {
+			return ErrVerifyEmailInvalid
+		}
+
+		if verifyEmail.IsUsed {
+			return ErrVerifyEmailUsed
+		}
+
+		if time.Now().After(verifyEmail.ExpiresAt) {
+			return ErrVerifyEmailExpired
+		}
+
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, verifyEmail.Username)
+		return err
+	})
+
+	return result, err
+}
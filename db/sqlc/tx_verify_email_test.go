@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTx(t *testing.T, expiresAt time.Time) CreateUserTxResult {
+	store := NewStore(testDBConn)
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       utils.RandomOwner(),
+			HashedPassword: utils.RandomString(60),
+			FullName:       utils.RandomOwner(),
+			Email:          utils.RandomEmail(),
+		},
+		ExpiresAt: expiresAt,
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.NotZero(t, result.VerifyEmail.ID)
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Empty(t, result.VerifyEmail.SecretCodeHash)
+	require.False(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.VerifyEmail.NextSendAt.Valid)
+	require.False(t, result.VerifyEmail.SentAt.Valid)
+	require.WithinDuration(t, arg.ExpiresAt, result.VerifyEmail.ExpiresAt, time.Second)
+
+	result.VerifyEmail = setRandomVerifyEmailSecretCode(t, result.VerifyEmail.ID)
+	return result
+}
+
+// setRandomVerifyEmailSecretCode stores the hash of a code as the dispatcher
+// does when it mails the email.
+func setRandomVerifyEmailSecretCode(t *testing.T, id int64) VerifyEmail {
+	arg := UpdateVerifyEmailSecretCodeParams{
+		ID:             id,
+		SecretCodeHash: utils.RandomString(64),
+	}
+
+	verifyEmail, err := testQueries.UpdateVerifyEmailSecretCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, id, verifyEmail.ID)
+	require.Equal(t, arg.SecretCodeHash, verifyEmail.SecretCodeHash)
+
+	return verifyEmail
+}
+
+func TestCreateUserTx(t *testing.T) {
+	createRandomUserTx(t, time.Now().Add(time.Hour))
+}
+
+func TestClaimVerifyEmailsTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	created := createRandomUserTx(t, time.Now().Add(time.Hour))
+	now := created.VerifyEmail.NextSendAt.Time.Add(time.Second)
+
+	claimed, err := store.ClaimVerifyEmailsTx(context.Background(), ClaimVerifyEmailsTxParams{
+		Now:       now,
+		BatchSize: 1000,
+		Lease:     time.Minute,
+	})
+	require.NoError(t, err)
+
+	var email *VerifyEmail
+	for i := range claimed {
+		if claimed[i].ID == created.VerifyEmail.ID {
+			email = &claimed[i]
+		}
+	}
+	require.NotNil(t, email)
+	require.EqualValues(t, 1, email.SendAttempts)
+	require.WithinDuration(t, now.Add(time.Minute), email.NextSendAt.Time, time.Second)
+
+	// the lease keeps the email from being claimed again
+	claimed, err = store.ClaimVerifyEmailsTx(context.Background(), ClaimVerifyEmailsTxParams{
+		Now:       now,
+		BatchSize: 1000,
+		Lease:     time.Minute,
+	})
+	require.NoError(t, err)
+	for _, email := range claimed {
+		require.NotEqual(t, created.VerifyEmail.ID, email.ID)
+	}
+
+	sent, err := store.MarkVerifyEmailSent(context.Background(), created.VerifyEmail.ID)
+	require.NoError(t, err)
+	require.True(t, sent.SentAt.Valid)
+	require.False(t, sent.NextSendAt.Valid)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDBConn)
+	created := createRandomUserTx(t, time.Now().Add(time.Hour))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: "wrong",
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: created.VerifyEmail.SecretCodeHash,
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: created.VerifyEmail.SecretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailUsed)
+}
+
+func TestVerifyEmailTxNotSent(t *testing.T) {
+	store := NewStore(testDBConn)
+	created, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       utils.RandomOwner(),
+			HashedPassword: utils.RandomString(60),
+			FullName:       utils.RandomOwner(),
+			Email:          utils.RandomEmail(),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// no code has been mailed yet, so not even an empty one matches
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: "",
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDBConn)
+	created := createRandomUserTx(t, time.Now().Add(-time.Minute))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: created.VerifyEmail.SecretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailExpired)
+
+	user, err := store.GetUser(context.Background(), created.User.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+}
+
+func TestUpdateUserTxKeepsEmail(t *testing.T) {
+	store := NewStore(testDBConn)
+	created := createRandomUserTx(t, time.Now().Add(time.Hour))
+
+	result, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: created.User.Username,
+			FullName: sql.NullString{String: "New Name", Valid: true},
+			Email:    sql.NullString{String: created.User.Email, Valid: true},
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.False(t, result.EmailChanged)
+	require.Zero(t, result.VerifyEmail.ID)
+	require.Equal(t, "New Name", result.User.FullName)
+	require.Equal(t, created.User.Email, result.User.Email)
+
+	// the pending verification of the unchanged address can still be used
+	verified, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: created.VerifyEmail.SecretCodeHash,
+	})
+	require.NoError(t, err)
+	require.True(t, verified.User.IsEmailVerified)
+}
+
+func TestUpdateUserTxChangesEmail(t *testing.T) {
+	store := NewStore(testDBConn)
+	created := createRandomUserTx(t, time.Now().Add(time.Hour))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             created.VerifyEmail.ID,
+		SecretCodeHash: created.VerifyEmail.SecretCodeHash,
+	})
+	require.NoError(t, err)
+
+	pending, err := store.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:  created.User.Username,
+		Email:     created.User.Email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	pending = setRandomVerifyEmailSecretCode(t, pending.ID)
+
+	arg := UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: created.User.Username,
+			Email:    sql.NullString{String: utils.RandomEmail(), Valid: true},
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	result, err := store.UpdateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.EmailChanged)
+	require.Equal(t, created.User.Email, result.PreviousEmail)
+	require.Equal(t, arg.Email.String, result.User.Email)
+	require.Equal(t, created.User.FullName, result.User.FullName)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.NotZero(t, result.VerifyEmail.ID)
+	require.Equal(t, arg.Email.String, result.VerifyEmail.Email)
+	require.Empty(t, result.VerifyEmail.SecretCodeHash)
+
+	// the code mailed to the previous address cannot verify the new one
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             pending.ID,
+		SecretCodeHash: pending.SecretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailExpired)
+}
+
+func TestUpdateUserTxDuplicateEmail(t *testing.T) {
+	store := NewStore(testDBConn)
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	_, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user1.Username,
+			Email:    sql.NullString{String: user2.Email, Valid: true},
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.Error(t, err)
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+}
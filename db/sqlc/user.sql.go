@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -18,7 +20,7 @@ INSERT INTO users (
 ) VALUES (
   $1, $2, $3, $4
 )
-RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
 `
 
 type CreateUserParams struct {
@@ -43,12 +45,57 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :one
+UPDATE users
+SET is_totp_enabled = true,
+    totp_last_used_step = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type EnableUserTOTPParams struct {
+	Username         string `json:"username"`
+	TotpLastUsedStep int64  `json:"totp_last_used_step"`
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUserTOTP, arg.Username, arg.TotpLastUsedStep)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -62,6 +109,411 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const resetUnlockedUserLoginAttempts = `-- name: ResetUnlockedUserLoginAttempts :one
+UPDATE users
+SET failed_login_attempts = 0,
+    lockouts = 0
+WHERE username = $1
+AND locked_until <= $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type ResetUnlockedUserLoginAttemptsParams struct {
+	Username string    `json:"username"`
+	Now      time.Time `json:"now"`
+}
+
+// No row is returned when the user is locked, a lock set by a concurrent failed login is kept.
+func (q *Queries) ResetUnlockedUserLoginAttempts(ctx context.Context, arg ResetUnlockedUserLoginAttemptsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, resetUnlockedUserLoginAttempts, arg.Username, arg.Now)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const resetUserLoginAttempts = `-- name: ResetUserLoginAttempts :one
+UPDATE users
+SET failed_login_attempts = 0,
+    lockouts = 0,
+    locked_until = '0001-01-01 00:00:00Z'
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+func (q *Queries) ResetUserLoginAttempts(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, resetUserLoginAttempts, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE($1, full_name),
+  email = COALESCE($2, email),
+  is_email_verified = COALESCE($3, is_email_verified)
+WHERE username = $4
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type UpdateUserParams struct {
+	FullName        sql.NullString `json:"full_name"`
+	Email           sql.NullString `json:"email"`
+	IsEmailVerified sql.NullBool   `json:"is_email_verified"`
+	Username        string         `json:"username"`
+}
+
+// updates the profile of a user, the fields given as NULL are left unchanged
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.FullName,
+		arg.Email,
+		arg.IsEmailVerified,
+		arg.Username,
+	)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const updateUserLoginAttempts = `-- name: UpdateUserLoginAttempts :one
+UPDATE users
+SET failed_login_attempts = $2,
+    last_failed_login_at = $3,
+    lockouts = $4,
+    locked_until = $5
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type UpdateUserLoginAttemptsParams struct {
+	Username            string    `json:"username"`
+	FailedLoginAttempts int32     `json:"failed_login_attempts"`
+	LastFailedLoginAt   time.Time `json:"last_failed_login_at"`
+	Lockouts            int32     `json:"lockouts"`
+	LockedUntil         time.Time `json:"locked_until"`
+}
+
+func (q *Queries) UpdateUserLoginAttempts(ctx context.Context, arg UpdateUserLoginAttemptsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserLoginAttempts,
+		arg.Username,
+		arg.FailedLoginAttempts,
+		arg.LastFailedLoginAt,
+		arg.Lockouts,
+		arg.LockedUntil,
+	)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2,
+    password_changed_at = now()
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type UpdateUserPasswordParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.Username, arg.HashedPassword)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type UpdateUserRoleParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Username, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const updateUserTOTPSecret = `-- name: UpdateUserTOTPSecret :one
+UPDATE users
+SET totp_secret = $2,
+    is_totp_enabled = false,
+    totp_last_used_step = 0
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type UpdateUserTOTPSecretParams struct {
+	Username   string `json:"username"`
+	TotpSecret string `json:"totp_secret"`
+}
+
+func (q *Queries) UpdateUserTOTPSecret(ctx context.Context, arg UpdateUserTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserTOTPSecret, arg.Username, arg.TotpSecret)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :one
+UPDATE users
+SET totp_last_used_step = $2
+WHERE username = $1
+AND totp_last_used_step < $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+type UseUserTOTPStepParams struct {
+	Username         string `json:"username"`
+	TotpLastUsedStep int64  `json:"totp_last_used_step"`
+}
+
+// records the period of an accepted TOTP code.
+// No row is returned when a code of this period or a later one was already used.
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, useUserTOTPStep, arg.Username, arg.TotpLastUsedStep)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, last_failed_login_at, lockouts, locked_until, is_email_verified, totp_secret, is_totp_enabled, totp_last_used_step
+`
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LastFailedLoginAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastUsedStep,
 	)
 	return i, err
 }
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -29,7 +30,12 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, args.FullName, user.FullName)
 	require.Equal(t, args.Email, user.Email)
 
+	require.Equal(t, "depositor", user.Role)
 	require.True(t, user.PasswordChangedAt.IsZero())
+	require.Zero(t, user.FailedLoginAttempts)
+	require.True(t, user.LockedUntil.IsZero())
+	require.False(t, user.IsEmailVerified)
+	require.False(t, user.IsTotpEnabled)
 	require.NotZero(t, user.CreatedAt)
 
 	return user
@@ -49,6 +55,55 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, user1.HashedPassword, user2.HashedPassword)
 	require.Equal(t, user1.FullName, user2.FullName)
 	require.Equal(t, user1.Email, user2.Email)
+	require.Equal(t, user1.Role, user2.Role)
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func TestUpdateUserOnlyFullName(t *testing.T) {
+	oldUser := createRandomUser(t)
+
+	newFullName := utils.RandomOwner()
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: oldUser.Username,
+		FullName: sql.NullString{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, oldUser.Email, updatedUser.Email)
+	require.Equal(t, oldUser.IsEmailVerified, updatedUser.IsEmailVerified)
+	require.Equal(t, oldUser.HashedPassword, updatedUser.HashedPassword)
+}
+
+func TestUpdateUserOnlyEmail(t *testing.T) {
+	oldUser := createRandomUser(t)
+
+	newEmail := utils.RandomEmail()
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:        oldUser.Username,
+		Email:           sql.NullString{String: newEmail, Valid: true},
+		IsEmailVerified: sql.NullBool{Bool: false, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, updatedUser.Email)
+	require.Equal(t, oldUser.FullName, updatedUser.FullName)
+	require.False(t, updatedUser.IsEmailVerified)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	user2, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     "banker",
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, "banker", user2.Role)
+
+	_, err = testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     "teller",
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSender writes each email to an .eml file of a directory instead of delivering it,
+// so the messages of a local deployment can be opened with a mail client.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir string, from string) (Sender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mail directory: %w", err)
+	}
+
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+// Send delivers a plain text message
+func (sender *FileSender) Send(_ context.Context, msg Message) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000Z"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(sender.dir, name), format(sender.from, msg, now), 0o600)
+}
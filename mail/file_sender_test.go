@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+
+	sender, err := NewFileSender(dir, "no-reply@simplebank.local")
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = sender.Send(context.Background(), Message{To: "alice@mail.com", Subject: "Hello", Body: "Hello Alice"})
+		require.NoError(t, err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: alice@mail.com\r\n")
+	require.Contains(t, string(data), "\r\n\r\nHello Alice")
+}
@@ -0,0 +1,26 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogSender writes the emails to the log instead of delivering them.
+// The messages may carry secrets such as reset tokens, it is meant for local development only.
+type LogSender struct {
+	logger *slog.Logger
+}
+
+func NewLogSender(logger *slog.Logger) Sender {
+	return &LogSender{logger: logger}
+}
+
+// Send delivers a plain text message
+func (sender *LogSender) Send(ctx context.Context, msg Message) error {
+	sender.logger.InfoContext(ctx, "email sent",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
@@ -0,0 +1,26 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	sender := NewLogSender(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	err := sender.Send(context.Background(), Message{To: "alice@mail.com", Subject: "Hello", Body: "Hello Alice"})
+	require.NoError(t, err)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "email sent", entry["msg"])
+	require.Equal(t, "alice@mail.com", entry["to"])
+	require.Equal(t, "Hello", entry["subject"])
+	require.Equal(t, "Hello Alice", entry["body"])
+}
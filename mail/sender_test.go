@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 10, 30, 0, 0, time.UTC)
+	msg := Message{To: "alice@mail.com", Subject: "Réinitialisation", Body: "Hello Alice"}
+
+	data := format("Simple Bank <no-reply@simplebank.local>", msg, date)
+	require.Equal(t, "From: Simple Bank <no-reply@simplebank.local>\r\n"+
+		"To: alice@mail.com\r\n"+
+		"Subject: =?utf-8?q?R=C3=A9initialisation?=\r\n"+
+		"Date: Fri, 01 Mar 2024 10:30:00 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"Hello Alice", string(data))
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPSender delivers the emails through an SMTP relay.
+// It authenticates with PLAIN when a username is set, which net/smtp only allows over TLS or to localhost.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(addr string, username string, password string, from string) Sender {
+	sender := &SMTPSender{addr: addr, from: from}
+	if len(username) > 0 {
+		host, _, _ := net.SplitHostPort(addr)
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender
+}
+
+// Send delivers a plain text message
+func (sender *SMTPSender) Send(_ context.Context, msg Message) error {
+	from, err := mail.ParseAddress(sender.from)
+	if err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(sender.addr, sender.auth, from.Address, []string{to.Address}, format(sender.from, msg, time.Now()))
+}
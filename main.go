@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	_ "github.com/lib/pq"
 	"github.com/mrohadi/simplebank/cmd/api"
@@ -15,6 +22,15 @@ func main() {
 	if err != nil {
 		log.Fatal("cannot read config")
 	}
+
+	logLevel := slog.LevelInfo
+	if config.LogLevel != "" {
+		if err := logLevel.UnmarshalText([]byte(config.LogLevel)); err != nil {
+			log.Fatal("cannot parse log level: ", err)
+		}
+	}
+	slog.SetDefault(utils.NewLogger(os.Stdout, logLevel))
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
 		log.Fatal("cannot connect to database", err)
@@ -26,8 +42,39 @@ func main() {
 		log.Fatal("Cannot started the server")
 	}
 
-	err = server.Start(config.ServerAddress)
-	if err != nil {
-		log.Fatal("Cannot started the server")
+	if err := server.RegisterDBStats(conn); err != nil {
+		log.Fatal("cannot register database metrics: ", err)
+	}
+
+	// the context is canceled with the reason of the shutdown: a signal or a listener that stopped
+	ctx, shutdown := context.WithCancelCause(context.Background())
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		shutdown(fmt.Errorf("received signal %s", <-quit))
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := server.StartGRPC(ctx, config.GRPCServerAddress); err != nil {
+			slog.Error("gRPC server stopped", slog.Any("error", err))
+			shutdown(fmt.Errorf("gRPC server stopped: %w", err))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := server.Start(ctx, config.ServerAddress); err != nil {
+			slog.Error("HTTP server stopped", slog.Any("error", err))
+			shutdown(fmt.Errorf("HTTP server stopped: %w", err))
+		}
+	}()
+	wg.Wait()
+
+	slog.Info("server shut down", slog.Any("reason", context.Cause(ctx)))
+
+	if err := conn.Close(); err != nil {
+		log.Fatal("cannot close database connection: ", err)
 	}
 }
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limiter is an interface for token bucket rate limiters
+type Limiter interface {
+	// Allow takes a token from the bucket of the key, it reports whether the request is allowed
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+
+	// Prune drops the buckets that are full again, they are recreated on the next request
+	Prune(ctx context.Context) error
+}
+
+// Limit lets Requests requests through per Period.
+// The bucket holds Requests tokens and is refilled continuously over Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Disabled reports whether the limit lets every request through
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// capacity is the number of tokens of a full bucket
+func (l Limit) capacity() float64 {
+	return float64(l.Requests)
+}
+
+// rate is the number of tokens added to the bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// refill returns the tokens of a bucket holding tokens after elapsed
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(l.capacity(), tokens+elapsed.Seconds()*l.rate())
+}
+
+// wait returns how long a bucket holding tokens takes to hold want tokens
+func (l Limit) wait(tokens float64, want float64) time.Duration {
+	if tokens >= want {
+		return 0
+	}
+
+	return time.Duration((want - tokens) / l.rate() * float64(time.Second))
+}
+
+// Result is the state of the bucket after a request
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long the client has to wait for the next token when the request is denied
+	RetryAfter time.Duration
+}
+
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	result := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	if !allowed {
+		result.RetryAfter = limit.wait(tokens, 1)
+	}
+
+	return result
+}
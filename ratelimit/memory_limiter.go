@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps the buckets in memory, each instance of the server limits the requests on its own
+type MemoryLimiter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens     float64
+	refilledAt time.Time
+	expiresAt  time.Time
+}
+
+func NewMemoryLimiter() Limiter {
+	return &MemoryLimiter{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of the key, it reports whether the request is allowed
+func (limiter *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	if limit.Disabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	now := limiter.now()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.capacity(), refilledAt: now}
+		limiter.buckets[key] = b
+	}
+
+	b.tokens = limit.refill(b.tokens, now.Sub(b.refilledAt))
+	b.refilledAt = now
+	if b.tokens < 1 {
+		return newResult(limit, false, b.tokens), nil
+	}
+
+	b.tokens--
+	b.expiresAt = now.Add(limit.wait(b.tokens, limit.capacity()))
+
+	return newResult(limit, true, b.tokens), nil
+}
+
+// Prune drops the buckets that are full again, they are recreated on the next request
+func (limiter *MemoryLimiter) Prune(_ context.Context) error {
+	now := limiter.now()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	for key, b := range limiter.buckets {
+		if !now.Before(b.expiresAt) {
+			delete(limiter.buckets, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryLimiter(now *time.Time) *MemoryLimiter {
+	limiter := NewMemoryLimiter().(*MemoryLimiter)
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newTestMemoryLimiter(&now)
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	result, err := limiter.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// the other keys have their own bucket
+	result, err = limiter.Allow(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+}
+
+func TestMemoryLimiterDisabled(t *testing.T) {
+	now := time.Now()
+	limiter := newTestMemoryLimiter(&now)
+
+	for _, limit := range []Limit{{}, {Requests: 1}, {Period: time.Minute}} {
+		for i := 0; i < 3; i++ {
+			result, err := limiter.Allow(context.Background(), "key", limit)
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+		}
+	}
+	require.Empty(t, limiter.buckets)
+}
+
+func TestMemoryLimiterPrune(t *testing.T) {
+	now := time.Now()
+	limiter := newTestMemoryLimiter(&now)
+	limit := Limit{Requests: 2, Period: 2 * time.Second}
+
+	_, err := limiter.Allow(context.Background(), "full", limit)
+	require.NoError(t, err)
+
+	now = now.Add(500 * time.Millisecond)
+	_, err = limiter.Allow(context.Background(), "empty", limit)
+	require.NoError(t, err)
+	_, err = limiter.Allow(context.Background(), "empty", limit)
+	require.NoError(t, err)
+
+	// "full" is refilled a second after its request, "empty" needs two seconds
+	now = now.Add(time.Second)
+	require.NoError(t, limiter.Prune(context.Background()))
+	require.Len(t, limiter.buckets, 1)
+	require.Contains(t, limiter.buckets, "empty")
+}
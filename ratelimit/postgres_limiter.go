@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	db "github.com/mrohadi/simplebank/db/sqlc"
+)
+
+// Store holds the queries PostgresLimiter runs against the rate_limit_buckets table
+type Store interface {
+	TakeRateLimitToken(ctx context.Context, arg db.TakeRateLimitTokenParams) (db.RateLimitBucket, error)
+	GetRateLimitBucket(ctx context.Context, key string) (db.RateLimitBucket, error)
+	DeleteExpiredRateLimitBuckets(ctx context.Context) error
+}
+
+// PostgresLimiter keeps the buckets in the database so the instances of the server share the limits
+type PostgresLimiter struct {
+	store Store
+}
+
+func NewPostgresLimiter(store Store) Limiter {
+	return &PostgresLimiter{store: store}
+}
+
+// Allow takes a token from the bucket of the key, it reports whether the request is allowed
+func (limiter *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Disabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	bucket, err := limiter.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:      key,
+		Capacity: limit.capacity(),
+		Rate:     limit.rate(),
+	})
+	if err == nil {
+		return newResult(limit, true, bucket.Tokens), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	// the bucket holds less than a token, it is read again to tell the client when to retry
+	bucket, err = limiter.store.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens := limit.refill(bucket.Tokens, time.Since(bucket.RefilledAt))
+	return newResult(limit, false, tokens), nil
+}
+
+// Prune drops the buckets that are full again, they are recreated on the next request
+func (limiter *PostgresLimiter) Prune(ctx context.Context) error {
+	return limiter.store.DeleteExpiredRateLimitBuckets(ctx)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/mrohadi/simplebank/db/mock"
+	db "github.com/mrohadi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPostgresLimiterAllowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	limit := Limit{Requests: 10, Period: time.Minute}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Eq(db.TakeRateLimitTokenParams{
+			Key:      "key",
+			Capacity: 10,
+			Rate:     10.0 / 60,
+		})).
+		Times(1).
+		Return(db.RateLimitBucket{Key: "key", Tokens: 8.5, RefilledAt: time.Now()}, nil)
+	store.EXPECT().
+		GetRateLimitBucket(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	result, err := NewPostgresLimiter(store).Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 8, result.Remaining)
+	require.Zero(t, result.RetryAfter)
+}
+
+func TestPostgresLimiterDenied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.RateLimitBucket{}, sql.ErrNoRows)
+	store.EXPECT().
+		GetRateLimitBucket(gomock.Any(), gomock.Eq("key")).
+		Times(1).
+		Return(db.RateLimitBucket{Key: "key", Tokens: 0, RefilledAt: time.Now().Add(-20 * time.Second)}, nil)
+
+	result, err := NewPostgresLimiter(store).Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.InDelta(t, 40*time.Second, result.RetryAfter, float64(time.Second))
+}
+
+func TestPostgresLimiterError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.RateLimitBucket{}, sql.ErrConnDone)
+
+	_, err := NewPostgresLimiter(store).Allow(context.Background(), "key", Limit{Requests: 1, Period: time.Minute})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestPostgresLimiterDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	result, err := NewPostgresLimiter(store).Allow(context.Background(), "key", Limit{})
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
+
+func TestPostgresLimiterPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredRateLimitBuckets(gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	require.NoError(t, NewPostgresLimiter(store).Prune(context.Background()))
+}
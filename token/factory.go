@@ -0,0 +1,39 @@
+package token
+
+import "fmt"
+
+// Types of the token makers
+const (
+	TypePaseto       = "paseto"
+	TypeJWT          = "jwt"
+	TypePasetoPublic = "paseto_public"
+	TypeJWTEdDSA     = "jwt_eddsa"
+)
+
+// NewMaker creates the maker of a token type, PASETO is the default.
+// The symmetric types use symmetricKey while the public types sign with signingKeys.
+func NewMaker(tokenType string, symmetricKey string, signingKeys []Ed25519Key) (Maker, error) {
+	var (
+		maker Maker
+		err   error
+	)
+
+	switch tokenType {
+	case "", TypePaseto:
+		maker, err = NewPasetoMaker(symmetricKey)
+	case TypeJWT:
+		maker, err = NewJWTMaker(symmetricKey)
+	case TypePasetoPublic:
+		maker, err = NewPasetoPublicMaker(signingKeys)
+	case TypeJWTEdDSA:
+		maker, err = NewJWTEdDSAMaker(signingKeys)
+	default:
+		return nil, fmt.Errorf("unknown token type %q", tokenType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid keys of %q tokens: %w", tokenType, err)
+	}
+
+	return maker, nil
+}
@@ -0,0 +1,52 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMaker(t *testing.T) {
+	symmetricKey := utils.RandomString(32)
+	signingKeys := []Ed25519Key{randomEd25519Key(t, "kid")}
+
+	testCases := []struct {
+		tokenType string
+		expected  Maker
+	}{
+		{"", &PasetoMaker{}},
+		{TypePaseto, &PasetoMaker{}},
+		{TypeJWT, &JWTMaker{}},
+		{TypePasetoPublic, &PasetoPublicMaker{}},
+		{TypeJWTEdDSA, &JWTEdDSAMaker{}},
+	}
+
+	for _, tc := range testCases {
+		maker, err := NewMaker(tc.tokenType, symmetricKey, signingKeys)
+		require.NoError(t, err)
+		require.IsType(t, tc.expected, maker)
+	}
+
+	_, err := NewMaker("unknown", symmetricKey, signingKeys)
+	require.Error(t, err)
+}
+
+func TestNewMakerKeySize(t *testing.T) {
+	// PASETO needs a key of exactly 32 characters, JWT at least 32
+	_, err := NewMaker(TypePaseto, utils.RandomString(33), nil)
+	require.Error(t, err)
+
+	_, err = NewMaker(TypeJWT, utils.RandomString(33), nil)
+	require.NoError(t, err)
+
+	_, err = NewMaker(TypeJWT, utils.RandomString(31), nil)
+	require.Error(t, err)
+
+	// the public types need a signing key
+	_, err = NewMaker(TypePasetoPublic, utils.RandomString(32), nil)
+	require.Error(t, err)
+
+	_, err = NewMaker(TypeJWTEdDSA, utils.RandomString(32), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,23 @@
+package token
+
+// JWK is a public key in the JSON Web Key format (RFC 7517), the Ed25519 keys use the OKP key type (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyPublisher is implemented by the makers whose tokens are verified with public keys.
+// The keys are published so other services can verify the tokens without being able to create them.
+type KeyPublisher interface {
+	// JWKS returns the public keys of the active signing keys
+	JWKS() JWKS
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtEdDSAAlgorithm is the JWS algorithm of the Ed25519 signatures (RFC 8037)
+const jwtEdDSAAlgorithm = "EdDSA"
+
+// JWTEdDSAMaker is a JSON Web Token maker signing with Ed25519 keys.
+// The kid header tells which key signed a token, so the keys can be rotated without invalidating the issued tokens.
+type JWTEdDSAMaker struct {
+	keys *keyRing
+}
+
+// NewJWTEdDSAMaker creates a new JWTEdDSAMaker, the first key signs the new tokens
+func NewJWTEdDSAMaker(keys []Ed25519Key) (Maker, error) {
+	ring, err := newKeyRing(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTEdDSAMaker{keys: ring}, nil
+}
+
+// CreateToken creates a new token for specific username, role and duration
+func (maker *JWTEdDSAMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	jwtToken.Header["kid"] = maker.keys.signingKey.ID
+
+	token, err := jwtToken.SignedString(maker.keys.signingKey.PrivateKey)
+	return token, payload, err
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *JWTEdDSAMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return maker.keys.publicKey(kid)
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc, jwt.WithValidMethods([]string{jwtEdDSAAlgorithm}))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// JWKS returns the public keys verifying the tokens
+func (maker *JWTEdDSAMaker) JWKS() JWKS {
+	return maker.keys.jwks(jwtEdDSAAlgorithm)
+}
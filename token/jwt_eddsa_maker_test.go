@@ -0,0 +1,118 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTEdDSAMaker(t *testing.T) {
+	maker, err := NewJWTEdDSAMaker([]Ed25519Key{randomEd25519Key(t, "kid")})
+	require.NoError(t, err)
+
+	username := utils.RandomOwner()
+	role := utils.BankerRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredJWTEdDSAToken(t *testing.T) {
+	maker, err := NewJWTEdDSAMaker([]Ed25519Key{randomEd25519Key(t, "kid")})
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestJWTEdDSAKeyRotation(t *testing.T) {
+	oldKey := randomEd25519Key(t, "old")
+	newKey := randomEd25519Key(t, "new")
+
+	oldMaker, err := NewJWTEdDSAMaker([]Ed25519Key{oldKey})
+	require.NoError(t, err)
+
+	token, _, err := oldMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// the old key still verifies the tokens it signed while the new key signs the new ones
+	rotatedMaker, err := NewJWTEdDSAMaker([]Ed25519Key{newKey, oldKey})
+	require.NoError(t, err)
+
+	_, err = rotatedMaker.VerifyToken(token)
+	require.NoError(t, err)
+
+	newToken, _, err := rotatedMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = oldMaker.VerifyToken(newToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	// the tokens of a retired key are rejected
+	retiredMaker, err := NewJWTEdDSAMaker([]Ed25519Key{newKey})
+	require.NoError(t, err)
+
+	_, err = retiredMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestInvalidJWTEdDSAToken(t *testing.T) {
+	key := randomEd25519Key(t, "kid")
+	maker, err := NewJWTEdDSAMaker([]Ed25519Key{key})
+	require.NoError(t, err)
+
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// a token signed with HMAC and the public key as secret must not be accepted
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	hmacToken.Header["kid"] = key.ID
+	token, err := hmacToken.SignedString([]byte(key.PrivateKey.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	// a token without kid cannot be verified
+	noKidToken, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload).SignedString(key.PrivateKey)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(noKidToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestJWTEdDSAMakerJWKS(t *testing.T) {
+	maker, err := NewJWTEdDSAMaker([]Ed25519Key{randomEd25519Key(t, "kid")})
+	require.NoError(t, err)
+
+	publisher, ok := maker.(KeyPublisher)
+	require.True(t, ok)
+
+	set := publisher.JWKS()
+	require.Len(t, set.Keys, 1)
+	require.Equal(t, "kid", set.Keys[0].KeyID)
+	require.Equal(t, jwtEdDSAAlgorithm, set.Keys[0].Algorithm)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/mrohadi/simplebank/utils"
 	"github.com/stretchr/testify/require"
 )
@@ -14,53 +15,57 @@ func TestJWTMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := utils.RandomOwner()
+	role := utils.BankerRole
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 	fmt.Println(token)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
 
-// func TestExpiredJWTToken(t *testing.T) {
-// 	maker, err := NewJWTMaker(utils.RandomString(32))
-// 	require.NoError(t, err)
-//
-// 	token, err := maker.CreateToken(utils.RandomOwner(), -time.Minute)
-// 	require.NoError(t, err)
-// 	require.NotEmpty(t, token)
-//
-// 	payload, err := maker.VerifyToken(token)
-// 	require.Error(t, err)
-// 	require.EqualError(t, err, ErrExpiredToken.Error())
-// 	require.Nil(t, payload)
-// }
-//
-// func TestInvalidJWTTokenAlgNone(t *testing.T) {
-// 	payload, err := NewPayload(utils.RandomOwner(), time.Minute)
-// 	require.NoError(t, err)
-//
-// 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
-// 	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
-// 	require.NoError(t, err)
-//
-// 	maker, err := NewJWTMaker(utils.RandomString(32))
-// 	require.NoError(t, err)
-//
-// 	payload, err = maker.VerifyToken(token)
-// 	require.NoError(t, err)
-// 	require.EqualError(t, err, ErrInvalidToken.Error())
-// 	require.Nil(t, payload)
-// }
+func TestExpiredJWTToken(t *testing.T) {
+	maker, err := NewJWTMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidJWTTokenAlgNone(t *testing.T) {
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
+	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	maker, err := NewJWTMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
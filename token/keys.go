@@ -0,0 +1,121 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ed25519Key is an Ed25519 signing key identified by its key ID
+type Ed25519Key struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+}
+
+// GenerateEd25519Key creates a new random Ed25519 key with the given key ID
+func GenerateEd25519Key(id string) (Ed25519Key, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Ed25519Key{}, err
+	}
+
+	return Ed25519Key{ID: id, PrivateKey: privateKey}, nil
+}
+
+// String encodes the key in the kid:seed format read by ParseEd25519Keys
+func (key Ed25519Key) String() string {
+	return key.ID + ":" + base64.RawURLEncoding.EncodeToString(key.PrivateKey.Seed())
+}
+
+// ParseEd25519Keys reads a comma separated list of keys in the kid:seed format,
+// the seed being the base64url encoded 32 bytes seed of the private key.
+func ParseEd25519Keys(value string) ([]Ed25519Key, error) {
+	var keys []Ed25519Key
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		id, encodedSeed, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid key %q: must be formatted as kid:seed", entry)
+		}
+
+		seed, err := base64.RawURLEncoding.DecodeString(encodedSeed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed of key %q: %w", id, err)
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid seed of key %q: must be exactly %d bytes", id, ed25519.SeedSize)
+		}
+
+		keys = append(keys, Ed25519Key{ID: id, PrivateKey: ed25519.NewKeyFromSeed(seed)})
+	}
+
+	return keys, nil
+}
+
+// keyRing holds the active keys of an asymmetric maker.
+// The first key signs the new tokens, the others only verify the tokens they signed before a rotation.
+type keyRing struct {
+	signingKey Ed25519Key
+	publicKeys map[string]ed25519.PublicKey
+	ids        []string
+}
+
+func newKeyRing(keys []Ed25519Key) (*keyRing, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+
+	ring := &keyRing{
+		signingKey: keys[0],
+		publicKeys: make(map[string]ed25519.PublicKey, len(keys)),
+	}
+	for _, key := range keys {
+		if len(key.ID) == 0 {
+			return nil, errors.New("the key ID cannot be empty")
+		}
+		if len(key.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid private key %q: must be exactly %d bytes", key.ID, ed25519.PrivateKeySize)
+		}
+		if _, ok := ring.publicKeys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+
+		ring.publicKeys[key.ID] = key.PrivateKey.Public().(ed25519.PublicKey)
+		ring.ids = append(ring.ids, key.ID)
+	}
+
+	return ring, nil
+}
+
+// publicKey returns the public key of a key ID, it fails for the unknown or retired keys
+func (ring *keyRing) publicKey(id string) (ed25519.PublicKey, error) {
+	publicKey, ok := ring.publicKeys[id]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return publicKey, nil
+}
+
+func (ring *keyRing) jwks(alg string) JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ring.ids))}
+	for _, id := range ring.ids {
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(ring.publicKeys[id]),
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: alg,
+		})
+	}
+
+	return set
+}
@@ -0,0 +1,80 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomEd25519Key(t *testing.T, id string) Ed25519Key {
+	key, err := GenerateEd25519Key(id)
+	require.NoError(t, err)
+	return key
+}
+
+func TestParseEd25519Keys(t *testing.T) {
+	key1 := randomEd25519Key(t, "2025-01")
+	key2 := randomEd25519Key(t, "2025-02")
+
+	keys, err := ParseEd25519Keys(key2.String() + ", " + key1.String())
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, key2.ID, keys[0].ID)
+	require.Equal(t, key2.PrivateKey, keys[0].PrivateKey)
+	require.Equal(t, key1.PrivateKey, keys[1].PrivateKey)
+
+	keys, err = ParseEd25519Keys("")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = ParseEd25519Keys("no-seed")
+	require.Error(t, err)
+
+	_, err = ParseEd25519Keys("kid:" + base64.RawURLEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
+
+func TestNewKeyRing(t *testing.T) {
+	key := randomEd25519Key(t, "kid")
+
+	_, err := newKeyRing(nil)
+	require.Error(t, err)
+
+	_, err = newKeyRing([]Ed25519Key{key, randomEd25519Key(t, "kid")})
+	require.Error(t, err)
+
+	_, err = newKeyRing([]Ed25519Key{randomEd25519Key(t, "")})
+	require.Error(t, err)
+
+	_, err = newKeyRing([]Ed25519Key{{ID: "kid", PrivateKey: ed25519.PrivateKey("short")}})
+	require.Error(t, err)
+}
+
+func TestKeyRingJWKS(t *testing.T) {
+	key1 := randomEd25519Key(t, "new")
+	key2 := randomEd25519Key(t, "old")
+
+	ring, err := newKeyRing([]Ed25519Key{key1, key2})
+	require.NoError(t, err)
+
+	set := ring.jwks(jwtEdDSAAlgorithm)
+	require.Len(t, set.Keys, 2)
+	for i, key := range []Ed25519Key{key1, key2} {
+		jwk := set.Keys[i]
+		require.Equal(t, key.ID, jwk.KeyID)
+		require.Equal(t, "OKP", jwk.KeyType)
+		require.Equal(t, "Ed25519", jwk.Curve)
+		require.Equal(t, "sig", jwk.Use)
+		require.Equal(t, jwtEdDSAAlgorithm, jwk.Algorithm)
+
+		publicKey, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		require.NoError(t, err)
+		require.Equal(t, []byte(key.PrivateKey.Public().(ed25519.PublicKey)), publicKey)
+
+		// only the public keys are published
+		require.False(t, strings.Contains(jwk.X, base64.RawURLEncoding.EncodeToString(key.PrivateKey.Seed())))
+	}
+}
@@ -4,8 +4,8 @@ import "time"
 
 // Maker is an interface for managing token
 type Maker interface {
-	// CreateToken creates a new token for specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new token for specific username, role and duration
+	CreateToken(username string, role string, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"errors"
+	"time"
+)
+
+// MigrationMaker moves the tokens from a maker to another without logging the users out.
+// Only the current maker creates tokens, the tokens of the previous one are still verified until they expire.
+type MigrationMaker struct {
+	current  Maker
+	previous Maker
+}
+
+// NewMigrationMaker creates a new MigrationMaker
+func NewMigrationMaker(current Maker, previous Maker) Maker {
+	return &MigrationMaker{current: current, previous: previous}
+}
+
+// CreateToken creates a new token for specific username, role and duration
+func (maker *MigrationMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	return maker.current.CreateToken(username, role, duration)
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *MigrationMaker) VerifyToken(token string) (*Payload, error) {
+	payload, err := maker.current.VerifyToken(token)
+	if errors.Is(err, ErrInvalidToken) {
+		return maker.previous.VerifyToken(token)
+	}
+
+	return payload, err
+}
+
+// JWKS returns the public keys of both makers, the tokens of the previous one must still be verifiable
+func (maker *MigrationMaker) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, m := range []Maker{maker.current, maker.previous} {
+		if publisher, ok := m.(KeyPublisher); ok {
+			set.Keys = append(set.Keys, publisher.JWKS().Keys...)
+		}
+	}
+
+	return set
+}
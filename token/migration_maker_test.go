@@ -0,0 +1,56 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationMaker(t *testing.T) {
+	previous, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	current, err := NewJWTEdDSAMaker([]Ed25519Key{randomEd25519Key(t, "kid")})
+	require.NoError(t, err)
+
+	maker := NewMigrationMaker(current, previous)
+
+	// the new tokens are only issued by the current maker
+	token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	verified, err := current.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+
+	_, err = previous.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	verified, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+
+	// the tokens issued before the migration are still accepted
+	oldToken, oldPayload, err := previous.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	verified, err = maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, oldPayload.ID, verified.ID)
+
+	expiredToken, _, err := previous.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(expiredToken)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+
+	_, err = maker.VerifyToken("invalid")
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	// the keys of the current maker are published
+	set := maker.(KeyPublisher).JWKS()
+	require.Len(t, set.Keys, 1)
+	require.Equal(t, "kid", set.Keys[0].KeyID)
+}
@@ -27,14 +27,15 @@ func NewPasetoMaker(symmectricKey string) (Maker, error) {
 	return maker, nil
 }
 
-// CreateToken creates a new token for specific username and duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token for specific username, role and duration
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
-		return "", nil
+		return "", payload, err
 	}
 
-	return maker.paseto.Encrypt(maker.symmectricKey, payload, nil)
+	token, err := maker.paseto.Encrypt(maker.symmectricKey, payload, nil)
+	return token, payload, err
 }
 
 // VerifyToken checks if the token is valid or not
@@ -0,0 +1,132 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const pasetoV4PublicHeader = "v4.public."
+
+// pasetoFooter is the footer of the PASETO public tokens, it tells which key signed a token
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// PasetoPublicMaker is a PASETO v4.public token maker signing with Ed25519 keys.
+// The footer tells which key signed a token, so the keys can be rotated without invalidating the issued tokens.
+type PasetoPublicMaker struct {
+	keys *keyRing
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker, the first key signs the new tokens
+func NewPasetoPublicMaker(keys []Ed25519Key) (Maker, error) {
+	ring, err := newKeyRing(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasetoPublicMaker{keys: ring}, nil
+}
+
+// CreateToken creates a new token for specific username, role and duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", payload, err
+	}
+
+	footer, err := json.Marshal(pasetoFooter{KeyID: maker.keys.signingKey.ID})
+	if err != nil {
+		return "", payload, err
+	}
+
+	signature := ed25519.Sign(maker.keys.signingKey.PrivateKey, pasetoPAE([]byte(pasetoV4PublicHeader), message, footer, nil))
+
+	token := pasetoV4PublicHeader +
+		base64.RawURLEncoding.EncodeToString(append(message, signature...)) + "." +
+		base64.RawURLEncoding.EncodeToString(footer)
+	return token, payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	body, found := strings.CutPrefix(token, pasetoV4PublicHeader)
+	if !found {
+		return nil, ErrInvalidToken
+	}
+
+	encodedMessage, encodedFooter, found := strings.Cut(body, ".")
+	if !found {
+		return nil, ErrInvalidToken
+	}
+
+	signed, err := base64.RawURLEncoding.DecodeString(encodedMessage)
+	if err != nil || len(signed) < ed25519.SignatureSize {
+		return nil, ErrInvalidToken
+	}
+
+	footer, err := base64.RawURLEncoding.DecodeString(encodedFooter)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var keyID pasetoFooter
+	if err := json.Unmarshal(footer, &keyID); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	publicKey, err := maker.keys.publicKey(keyID.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := signed[:len(signed)-ed25519.SignatureSize]
+	signature := signed[len(signed)-ed25519.SignatureSize:]
+	if !ed25519.Verify(publicKey, pasetoPAE([]byte(pasetoV4PublicHeader), message, footer, nil), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// JWKS returns the public keys verifying the tokens
+func (maker *PasetoPublicMaker) JWKS() JWKS {
+	return maker.keys.jwks("")
+}
+
+// pasetoPAE is the pre-authentication encoding of the pieces covered by a PASETO signature.
+// Each piece is prefixed by its length so the boundaries between them cannot be moved.
+func pasetoPAE(pieces ...[]byte) []byte {
+	size := 8
+	for _, piece := range pieces {
+		size += 8 + len(piece)
+	}
+
+	output := make([]byte, 0, size)
+	output = binary.LittleEndian.AppendUint64(output, uint64(len(pieces)))
+	for _, piece := range pieces {
+		output = binary.LittleEndian.AppendUint64(output, uint64(len(piece)))
+		output = append(output, piece...)
+	}
+
+	return output
+}
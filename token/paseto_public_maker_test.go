@@ -0,0 +1,113 @@
+package token
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrohadi/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker([]Ed25519Key{randomEd25519Key(t, "kid")})
+	require.NoError(t, err)
+
+	username := utils.RandomOwner()
+	role := utils.BankerRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+	require.True(t, strings.HasPrefix(token, "v4.public."))
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker([]Ed25519Key{randomEd25519Key(t, "kid")})
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldKey := randomEd25519Key(t, "old")
+	newKey := randomEd25519Key(t, "new")
+
+	oldMaker, err := NewPasetoPublicMaker([]Ed25519Key{oldKey})
+	require.NoError(t, err)
+
+	token, _, err := oldMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	rotatedMaker, err := NewPasetoPublicMaker([]Ed25519Key{newKey, oldKey})
+	require.NoError(t, err)
+
+	_, err = rotatedMaker.VerifyToken(token)
+	require.NoError(t, err)
+
+	retiredMaker, err := NewPasetoPublicMaker([]Ed25519Key{newKey})
+	require.NoError(t, err)
+
+	_, err = retiredMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestInvalidPasetoPublicToken(t *testing.T) {
+	key := randomEd25519Key(t, "kid")
+	maker, err := NewPasetoPublicMaker([]Ed25519Key{key})
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 4)
+
+	signed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	signed[0] ^= 1
+	tampered := strings.Join([]string{parts[0], parts[1], base64.RawURLEncoding.EncodeToString(signed), parts[3]}, ".")
+
+	// the footer is covered by the signature too, it cannot point to another key
+	otherFooter := base64.RawURLEncoding.EncodeToString([]byte(`{"kid":"other"}`))
+	otherKey := strings.Join([]string{parts[0], parts[1], parts[2], otherFooter}, ".")
+
+	symmetricMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+	localToken, _, err := symmetricMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	for _, invalid := range []string{tampered, otherKey, localToken, "v4.public.", strings.Join(parts[:3], ".")} {
+		payload, err := maker.VerifyToken(invalid)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestPasetoPAE(t *testing.T) {
+	// test vectors of the PASETO specification
+	require.Equal(t, []byte("\x00\x00\x00\x00\x00\x00\x00\x00"), pasetoPAE())
+	require.Equal(t, []byte("\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), pasetoPAE([]byte{}))
+	require.Equal(t, []byte("\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00test"), pasetoPAE([]byte("test")))
+}
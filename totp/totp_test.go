@@ -0,0 +1,89 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 key of the test vectors of RFC 6238
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// the RFC lists 8 digits codes, the 6 digits codes are their last digits
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(rfcSecret, time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code, "at %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+
+	step, err := Validate(secret, code, now, 0)
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	// the code of the previous period is still accepted
+	step, err = Validate(secret, code, now.Add(Period), 0)
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	_, err = Validate(secret, code, now.Add(3*Period), 0)
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	// a used step cannot be replayed
+	_, err = Validate(secret, code, now, step)
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate(secret, "12345", now, 0)
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate("not base32!", code, now, 0)
+	require.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret1, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, secret1, 32)
+
+	secret2, err := GenerateSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Simple Bank", "alice", rfcSecret))
+	require.NoError(t, err)
+
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Simple Bank:alice", uri.Path)
+	require.Equal(t, rfcSecret, uri.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+	require.True(t, strings.HasPrefix(uri.String(), "otpauth://totp/Simple%20Bank:alice?"))
+}
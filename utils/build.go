@@ -0,0 +1,27 @@
+package utils
+
+import "runtime"
+
+// The build information is injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/mrohadi/simplebank/utils.GitCommit=$(git rev-parse HEAD)"
+var (
+	GitCommit = "unknown"
+	BuildTime = "unknown"
+)
+
+// BuildInfo describes the binary currently running
+type BuildInfo struct {
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// GetBuildInfo returns the build information injected at link time
+func GetBuildInfo() BuildInfo {
+	return BuildInfo{
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
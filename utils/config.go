@@ -10,11 +10,50 @@ import (
 // The values are read by viper package from a config file
 // or environment variable
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmectricKey  string        `mapstructure:"TOKEN_SYMMECTRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                  string        `mapstructure:"DB_DRIVER"`
+	DBSource                  string        `mapstructure:"DB_SOURCE"`
+	LogLevel                  string        `mapstructure:"LOG_LEVEL"`
+	ServerAddress             string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress         string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TrustedProxies            string        `mapstructure:"TRUSTED_PROXIES"`
+	HTTPReadTimeout           time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout          time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout           time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	ShutdownDelay             time.Duration `mapstructure:"SHUTDOWN_DELAY"`
+	ShutdownTimeout           time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	TokenType                 string        `mapstructure:"TOKEN_TYPE"`
+	TokenPreviousType         string        `mapstructure:"TOKEN_PREVIOUS_TYPE"`
+	TokenSymmectricKey        string        `mapstructure:"TOKEN_SYMMECTRIC_KEY"`
+	TokenSigningKeys          string        `mapstructure:"TOKEN_SIGNING_KEYS"`
+	AccessTokenDuration       time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration      time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	RevocationSyncInterval    time.Duration `mapstructure:"REVOCATION_SYNC_INTERVAL"`
+	RateQuoteDuration         time.Duration `mapstructure:"RATE_QUOTE_DURATION"`
+	IdempotencyKeyDuration    time.Duration `mapstructure:"IDEMPOTENCY_KEY_DURATION"`
+	SchedulerInterval         time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	LoginMaxAttempts          int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginAttemptWindow        time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	LoginLockoutDuration      time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginMaxLockoutDuration   time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT_DURATION"`
+	MFAChallengeDuration      time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	MFAStepUpAmount           int64         `mapstructure:"MFA_STEP_UP_AMOUNT"`
+	PasswordResetDuration     time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	VerifyEmailDuration       time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	AppURL                    string        `mapstructure:"APP_URL"`
+	MailSender                string        `mapstructure:"MAIL_SENDER"`
+	MailFrom                  string        `mapstructure:"MAIL_FROM"`
+	MailDir                   string        `mapstructure:"MAIL_DIR"`
+	MailDispatchInterval      time.Duration `mapstructure:"MAIL_DISPATCH_INTERVAL"`
+	SMTPAddress               string        `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername              string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword              string        `mapstructure:"SMTP_PASSWORD"`
+	RateLimitBackend          string        `mapstructure:"RATE_LIMIT_BACKEND"`
+	RateLimitPublicRequests   int           `mapstructure:"RATE_LIMIT_PUBLIC_REQUESTS"`
+	RateLimitPublicPeriod     time.Duration `mapstructure:"RATE_LIMIT_PUBLIC_PERIOD"`
+	RateLimitTransferRequests int           `mapstructure:"RATE_LIMIT_TRANSFER_REQUESTS"`
+	RateLimitTransferPeriod   time.Duration `mapstructure:"RATE_LIMIT_TRANSFER_PERIOD"`
+	RateLimitAPIRequests      int           `mapstructure:"RATE_LIMIT_API_REQUESTS"`
+	RateLimitAPIPeriod        time.Duration `mapstructure:"RATE_LIMIT_API_PERIOD"`
 }
 
 // LoadConfig reads configuration from file or environment variable.
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactJSON(t *testing.T) {
+	body := []byte(`{
+		"username": "alice",
+		"password": "secret123",
+		"session": {"refresh_token": "v2.local.abc", "ids": [1, 2]},
+		"items": [{"new_password": "p4ss"}, {"amount": 10}]
+	}`)
+
+	redacted, ok := RedactJSON(body)
+	require.True(t, ok)
+	require.JSONEq(t, `{
+		"username": "alice",
+		"password": "[REDACTED]",
+		"session": {"refresh_token": "[REDACTED]", "ids": [1, 2]},
+		"items": [{"new_password": "[REDACTED]"}, {"amount": 10}]
+	}`, string(redacted))
+
+	_, ok = RedactJSON([]byte("password=secret123"))
+	require.False(t, ok)
+}
+
+func TestLoggerRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, slog.LevelInfo).With(slog.String("component", "test"))
+
+	logger.InfoContext(WithRequestID(context.Background(), "req-1"), "with request ID")
+	logger.DebugContext(WithRequestID(context.Background(), "req-2"), "below the level")
+	logger.Info("without request ID")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &record))
+	require.Equal(t, "req-1", record["request_id"])
+	require.Equal(t, "test", record["component"])
+
+	record = nil
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	require.NotContains(t, record, "request_id")
+}